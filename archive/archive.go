@@ -226,3 +226,37 @@ func ApplyEncryptedFile(hashchainFile, patchDir, filename string, head, key *[32
 	}
 	return Apply(hashchainFile, patchDir, bytes.NewBuffer(msg), head)
 }
+
+// ReadHashChain reads and verifies the hash chain contained in the archive
+// read from r, without applying anything.
+func ReadHashChain(r io.Reader) (*hashchain.HashChain, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil, ErrNoHashchain
+			}
+			return nil, err
+		}
+		log.Printf("archive: read %s", hdr.Name)
+		if hdr.Name == globalHashchainFile {
+			return hashchain.Read(tr)
+		}
+	}
+}
+
+// ReadHashChainFile reads and verifies the hash chain contained in the
+// archive in filename, without applying anything.
+func ReadHashChainFile(filename string) (*hashchain.HashChain, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadHashChain(f)
+}
@@ -9,3 +9,6 @@ var ErrUnknownFile = errors.New("archive: contains unknown file, not a codechain
 
 // ErrCannotDecrypt is returned if an encrypted archive cannot be decrypted.
 var ErrCannotDecrypt = errors.New("archive: cannot decrypt")
+
+// ErrNoHashchain is returned if an archive doesn't contain a hash chain.
+var ErrNoHashchain = errors.New("archive: contains no hash chain")
@@ -13,9 +13,9 @@ func usage() {
 	cmd := os.Args[0]
 	fmt.Fprintf(os.Stderr, "Usage: %s keygen [-s seckey.bin]\n", cmd)
 	fmt.Fprintf(os.Stderr, "       %s keyfile -s seckey.bin [-c]\n", cmd)
-	fmt.Fprintf(os.Stderr, "       %s createpkg -name name -dns FQDN -url URL -s seckey.bin [-dyn]\n", cmd)
-	fmt.Fprintf(os.Stderr, "       %s signhead\n", cmd)
-	fmt.Fprintf(os.Stderr, "       %s refresh .secpkg [...]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s createpkg -name name -dns FQDN -url URL -s seckey.bin|-agent [-dyn]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s signhead [-agent | -request | -import signedhead]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s refresh [-agent] .secpkg [...]\n", cmd)
 	fmt.Fprintf(os.Stderr, "       %s status\n", cmd)
 	fmt.Fprintf(os.Stderr, "       %s testbuild\n", cmd)
 	os.Exit(2)
@@ -12,18 +12,33 @@ import (
 
 func usage() {
 	cmd := os.Args[0]
-	fmt.Fprintf(os.Stderr, "Usage: %s treehash [-l]\n", cmd)
+	fmt.Fprintf(os.Stderr, "Usage: %s treehash [-l] [treehash|tag]\n", cmd)
 	fmt.Fprintf(os.Stderr, "       %s keygen [-s seckey.bin]\n", cmd)
 	fmt.Fprintf(os.Stderr, "       %s keyfile [-l] -s seckey.bin [-c]\n", cmd)
-	fmt.Fprintf(os.Stderr, "       %s start -s seckey.bin\n", cmd)
-	fmt.Fprintf(os.Stderr, "       %s publish [-s seckey.bin]\n", cmd)
-	fmt.Fprintf(os.Stderr, "       %s review [-a] [-d] [-s seckey.bin] [treehash]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s start -s seckey.bin | -agent\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s publish [-s seckey.bin | -agent] [-rev rev|range]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s review [-a] [-d] [-r pubkey] [-s seckey.bin | -agent] [treehash|tag]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s sign-request [-s seckey.bin | -agent] request\n", cmd)
 	fmt.Fprintf(os.Stderr, "       %s addkey [-w] pubkey signature [comment]\n", cmd)
 	fmt.Fprintf(os.Stderr, "       %s remkey pubkey\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s expkey pubkey expiry\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s rotkey [-d] -s new-seckey.bin | -agent old-pubkey\n", cmd)
 	fmt.Fprintf(os.Stderr, "       %s sigctl -m\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s revoke [-s seckey.bin | -agent] treehash reason\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s note [-s seckey.bin | -agent] [-nack | -ack] treehash|tag|linkhash text\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s tag name [treehash]\n", cmd)
 	fmt.Fprintf(os.Stderr, "       %s createdist -f dist.tar.gz\n", cmd)
-	fmt.Fprintf(os.Stderr, "       %s apply\n", cmd)
-	fmt.Fprintf(os.Stderr, "       %s status\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s apply [-block-nacks] [-f dist.tar.gz] [-head head|tag]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s checkout [-dir path] treehash|tag|line\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s status [-block-nacks] [-deep-verify] [-json | -p]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s blame file\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s history path\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s export-git dir\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s bisect [-dir path] -run command [good [bad]]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s log [-block-nacks] [-json] [-signer pubkey] [-since date] [-until date] [-t treehash|tag]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s prove [-head head] [-o proof.txt] [treehash|tag]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s verify [-block-nacks] [-reviewers n]\n", cmd)
+	fmt.Fprintf(os.Stderr, "       %s verify-proof [-t treehash] head proof.txt\n", cmd)
 	fmt.Fprintf(os.Stderr, "       %s cleanslate\n", cmd)
 	os.Exit(2)
 }
@@ -48,18 +63,48 @@ func main() {
 		err = command.Publish(argv0, args...)
 	case "review":
 		err = command.Review(argv0, args...)
+	case "sign-request":
+		err = command.SignRequest(argv0, args...)
 	case "addkey":
 		err = command.AddKey(argv0, args...)
 	case "remkey":
 		err = command.RemKey(argv0, args...)
+	case "expkey":
+		err = command.ExpKey(argv0, args...)
+	case "rotkey":
+		err = command.RotKey(argv0, args...)
 	case "sigctl":
 		err = command.SigCtl(argv0, args...)
+	case "revoke":
+		err = command.Revoke(argv0, args...)
+	case "note":
+		err = command.Note(argv0, args...)
+	case "tag":
+		err = command.Tag(argv0, args...)
 	case "createdist":
 		err = command.CreateDist(argv0, args...)
 	case "apply":
 		err = command.Apply(argv0, args...)
+	case "checkout":
+		err = command.Checkout(argv0, args...)
 	case "status":
 		err = command.Status(argv0, args...)
+	case "blame":
+		err = command.Blame(argv0, args...)
+	case "export-git":
+		err = command.ExportGit(argv0, args...)
+	case "bisect":
+		err = command.Bisect(argv0, args...)
+	case "history":
+		err = command.History(argv0, args...)
+	case "log":
+		err = command.Log(argv0, args...)
+	case "prove":
+		err = command.Prove(argv0, args...)
+	case "verify":
+		err = command.Verify(argv0, args...)
+	case "verify-proof":
+		err = command.VerifyProof(argv0, args...)
 	case "cleanslate":
 		err = command.CleanSlate(argv0, args...)
 	default:
@@ -68,6 +113,9 @@ func main() {
 	if err != nil {
 		if err != flag.ErrHelp {
 			fmt.Fprintf(os.Stderr, "%s: error: %s\n", os.Args[0], err)
+			if exitErr, ok := err.(*command.ExitError); ok {
+				os.Exit(exitErr.Code)
+			}
 			os.Exit(1)
 		}
 		os.Exit(2)
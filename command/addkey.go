@@ -5,10 +5,8 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/frankbraun/codechain/hashchain"
 	"github.com/frankbraun/codechain/secpkg"
 	"github.com/frankbraun/codechain/util/base64"
-	"github.com/frankbraun/codechain/util/def"
 	"github.com/frankbraun/codechain/util/log"
 )
 
@@ -53,7 +51,7 @@ func AddKey(argv0 string, args ...string) error {
 	if nArg == 3 {
 		comment = []byte(fs.Arg(2))
 	}
-	c, err := hashchain.ReadFile(def.HashchainFile)
+	c, err := readHashChain()
 	if err != nil {
 		return err
 	}
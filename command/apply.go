@@ -17,12 +17,14 @@ import (
 func Apply(argv0 string, args ...string) error {
 	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s\n", argv0)
+		fmt.Fprintf(os.Stderr, "Usage: %s [-block-nacks] [-f dist.tar.gz] [-head head|tag]\n", argv0)
 		fmt.Fprintf(os.Stderr, "Apply all patches with enough signatures to code tree.\n")
+		fmt.Fprintf(os.Stderr, "With -head tag the code tree is synced to the tree hash of the signed tag.\n")
 		fs.PrintDefaults()
 	}
+	blockNacks := fs.Bool("block-nacks", false, "Skip releases with unresolved objections of signers")
 	filename := fs.String("f", "", "Distribution file")
-	headStr := fs.String("head", "", "Check that the hash chain contains the given head")
+	headStr := fs.String("head", "", "Check that the hash chain contains the given head (or sync to signed tag)")
 	verbose := fs.Bool("v", false, "Be verbose")
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -37,17 +39,32 @@ func Apply(argv0 string, args ...string) error {
 	if err := secpkg.UpToDate("codechain"); err != nil {
 		return err
 	}
-	var head *[32]byte
+	var (
+		head *[32]byte
+		tag  string
+	)
 	if *headStr != "" {
 		h, err := hex.Decode(*headStr, 32)
 		if err != nil {
-			return err
+			// not a head, interpret as tag
+			tag = *headStr
+		} else {
+			var ha [32]byte
+			copy(ha[:], h)
+			head = &ha
 		}
-		var ha [32]byte
-		copy(ha[:], h)
-		head = &ha
 	}
 	if *filename != "" {
+		if tag != "" {
+			// check the tag before anything from the distribution is written
+			src, err := archive.ReadHashChainFile(*filename)
+			if err != nil {
+				return err
+			}
+			if _, ok := src.TagTreeHash(tag); !ok {
+				return fmt.Errorf("-head is neither a valid head nor a signed tag: %s", tag)
+			}
+		}
 		err := archive.ApplyFile(def.HashchainFile, def.PatchDir, *filename, head)
 		if err != nil {
 			return err
@@ -60,5 +77,15 @@ func Apply(argv0 string, args ...string) error {
 	if err := c.Close(); err != nil {
 		return err
 	}
+	c.SetObjectionPolicy(*blockNacks)
+	if tag != "" {
+		// the hash chain must contain the signed tag (which also rejects
+		// mistyped heads, tag names cannot look like heads)
+		treeHash, ok := c.TagTreeHash(tag)
+		if !ok {
+			return fmt.Errorf("-head is neither a valid head nor a signed tag: %s", tag)
+		}
+		return c.ApplyTreeHash(treeHash, def.PatchDir)
+	}
 	return c.Apply(head, def.PatchDir)
 }
@@ -0,0 +1,114 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frankbraun/codechain/archive"
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/tree"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/file"
+)
+
+func TestApplyTag(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "command_test")
+	if err != nil {
+		t.Fatalf("TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd() failed: %v", err)
+	}
+	defer os.Chdir(wd)
+	projectDir := filepath.Join(tmpdir, "project")
+	if err := os.MkdirAll(filepath.Join(projectDir, def.CodechainDir), 0755); err != nil {
+		t.Fatalf("os.MkdirAll() failed: %v", err)
+	}
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatalf("os.Chdir() failed: %v", err)
+	}
+
+	// publish and tag release 1, publish release 2, sign everything
+	s := testSigner(t)
+	c, _, err := hashchain.Start(def.HashchainFile, s, nil)
+	if err != nil {
+		t.Fatalf("hashchain.Start() failed: %v", err)
+	}
+	h1 := testPublish(t, c, s, tmpdir, 1)
+	if _, err := c.Tag("v1", h1); err != nil {
+		t.Fatalf("c.Tag() failed: %v", err)
+	}
+	h2 := testPublish(t, c, s, tmpdir, 2)
+	if _, err := c.Signature(c.Head(), s, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	dist := filepath.Join(tmpdir, "dist.tar.gz")
+	if err := archive.CreateDist(c, dist); err != nil {
+		t.Fatalf("archive.CreateDist() failed: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("c.Close() failed: %v", err)
+	}
+
+	// unknown tags and mistyped heads are rejected
+	for _, head := range []string{"v2", "5998c63aca42e471297c0fa353538a93d4d4cfafe9a672df6989e694188b4a9"} {
+		if err := Apply("apply", "-head", head); err == nil {
+			t.Errorf("Apply() should fail for -head %s", head)
+		}
+	}
+	exists, err := file.Exists("a.txt")
+	if err != nil {
+		t.Fatalf("file.Exists() failed: %v", err)
+	}
+	if exists {
+		t.Error("Apply() changed tree after failed -head check")
+	}
+
+	// the tree is synced to the tagged tree hash
+	if err := Apply("apply", "-head", "v1"); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	testTreeHash(t, h1)
+	if err := Apply("apply"); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	testTreeHash(t, h2)
+
+	// with a distribution file unknown tags are rejected before anything
+	// is written
+	distDir := filepath.Join(tmpdir, "dist")
+	if err := os.Mkdir(distDir, 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	if err := os.Chdir(distDir); err != nil {
+		t.Fatalf("os.Chdir() failed: %v", err)
+	}
+	if err := Apply("apply", "-f", dist, "-head", "v2"); err == nil {
+		t.Error("Apply() should fail for -f with unknown -head tag")
+	}
+	exists, err = file.Exists(def.CodechainDir)
+	if err != nil {
+		t.Fatalf("file.Exists() failed: %v", err)
+	}
+	if exists {
+		t.Error("Apply() wrote distribution after failed -head check")
+	}
+	if err := Apply("apply", "-f", dist, "-head", "v1"); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	testTreeHash(t, h1)
+}
+
+func testTreeHash(t *testing.T, treeHash [32]byte) {
+	h, err := tree.Hash(".", def.ExcludePaths)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+	if *h != treeHash {
+		t.Errorf("tree hash is %x, want %x", *h, treeHash)
+	}
+}
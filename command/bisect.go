@@ -0,0 +1,173 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/sync"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/file"
+	"github.com/frankbraun/codechain/util/log"
+)
+
+// bisect finds the first bad index between the good index good and the bad
+// index bad with a binary search. The function isGood reports whether the
+// given index is good.
+func bisect(good, bad int, isGood func(i int) (bool, error)) (int, error) {
+	for bad-good > 1 {
+		mid := good + (bad-good)/2
+		ok, err := isGood(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			good = mid
+		} else {
+			bad = mid
+		}
+	}
+	return bad, nil
+}
+
+// runTest runs the shell command run in directory dir and reports whether it
+// succeeded.
+func runTest(dir, run string) (bool, error) {
+	cmd := exec.Command("sh", "-c", run)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err == nil {
+		return true, nil
+	}
+	if _, ok := err.(*exec.ExitError); ok {
+		return false, nil
+	}
+	return false, err
+}
+
+// canRemoveScratch returns true, if the contents of the scratch directory dir
+// can be removed while syncing. This is only the case for the default scratch
+// directory and for directories which are empty before bisect starts, because
+// a directory given with -dir could contain files of the user.
+func canRemoveScratch(dir, defaultDir string) (bool, error) {
+	if filepath.Clean(dir) == filepath.Clean(defaultDir) {
+		return true, nil
+	}
+	exists, err := file.Exists(dir)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return true, nil
+	}
+	entries, err := file.List(dir)
+	if err != nil {
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
+// Bisect implements the 'bisect' command.
+func Bisect(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-dir path] -run command [good [bad]]\n", argv0)
+		fmt.Fprintf(os.Stderr, "Find first bad tree hash between good (default: empty tree) and bad (default: last).\n")
+		fmt.Fprintf(os.Stderr, "A tree hash is good, if command exits with 0 in the synced directory.\n")
+		fs.PrintDefaults()
+	}
+	defaultDir := filepath.Join(treeDirRoot, "bisect")
+	dir := fs.String("dir", defaultDir, "Scratch directory to sync trees to (only removed if empty or default)")
+	run := fs.String("run", "", "Shell command to test tree hashes with")
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if *run == "" || fs.NArg() > 2 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	if err := c.Close(); err != nil {
+		return err
+	}
+	treeHashes := c.TreeHashes()
+	index := func(s string) (int, error) {
+		treeHash, err := c.ResolveTreeHash(s)
+		if err != nil {
+			return 0, err
+		}
+		for i, h := range treeHashes {
+			if h == treeHash {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("unknown tree hash: %s", treeHash) // cannot happen
+	}
+	good := 0
+	bad := len(treeHashes) - 1
+	if fs.NArg() > 0 {
+		if good, err = index(fs.Arg(0)); err != nil {
+			return err
+		}
+	}
+	if fs.NArg() > 1 {
+		if bad, err = index(fs.Arg(1)); err != nil {
+			return err
+		}
+	}
+	if good >= bad {
+		return errors.New("good tree hash must come before bad tree hash")
+	}
+	canRemoveDir, err := canRemoveScratch(*dir, defaultDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+	idx, err := bisect(good, bad, func(i int) (bool, error) {
+		fmt.Printf("testing %s (%d/%d)\n", treeHashes[i], i, len(treeHashes)-1)
+		err := sync.Dir(*dir, treeHashes[i], def.PatchDir, reverseDir, treeHashes,
+			nil, def.ExcludePaths, canRemoveDir)
+		if err != nil {
+			return false, err
+		}
+		ok, err := runTest(*dir, *run)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			fmt.Println("good")
+		} else {
+			fmt.Println("bad")
+		}
+		return ok, nil
+	})
+	if err != nil {
+		return err
+	}
+	// releases are in the same order as treeHashes, without the empty tree
+	r := c.Releases()[idx-1]
+	fmt.Printf("first bad tree hash: %s (line %d)\n", r.TreeHash, r.Line)
+	if r.Comment != "" {
+		fmt.Printf("comment:   %s\n", r.Comment)
+	}
+	fmt.Printf("publisher: %s %s\n", r.PubKey, c.SignerComment(r.PubKey))
+	return nil
+}
@@ -0,0 +1,89 @@
+package command
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBisect(t *testing.T) {
+	for first := 1; first <= 10; first++ {
+		tested := 0
+		idx, err := bisect(0, 10, func(i int) (bool, error) {
+			if i <= 0 || i >= 10 {
+				t.Errorf("bisect() tested known index %d", i)
+			}
+			tested++
+			return i < first, nil
+		})
+		if err != nil {
+			t.Fatalf("bisect() failed: %v", err)
+		}
+		if idx != first {
+			t.Errorf("bisect() found %d instead of %d", idx, first)
+		}
+		if tested > 4 {
+			t.Errorf("bisect() tested %d indices", tested)
+		}
+	}
+	errTest := errors.New("test failed")
+	_, err := bisect(0, 10, func(i int) (bool, error) {
+		return false, errTest
+	})
+	if err != errTest {
+		t.Errorf("bisect() should fail with errTest (has %v)", err)
+	}
+}
+
+func TestRunTest(t *testing.T) {
+	ok, err := runTest(".", "true")
+	if err != nil || !ok {
+		t.Errorf("runTest(true) = %v, %v", ok, err)
+	}
+	ok, err = runTest(".", "exit 1")
+	if err != nil || ok {
+		t.Errorf("runTest(exit 1) = %v, %v", ok, err)
+	}
+}
+
+func TestCanRemoveScratch(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "command_test")
+	if err != nil {
+		t.Fatalf("TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	defaultDir := filepath.Join(tmpdir, "default")
+	userDir := filepath.Join(tmpdir, "user")
+	for _, dir := range []string{defaultDir, userDir} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("os.Mkdir() failed: %v", err)
+		}
+		fn := filepath.Join(dir, "a.txt")
+		if err := ioutil.WriteFile(fn, []byte("a\n"), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile() failed: %v", err)
+		}
+	}
+	tests := []struct {
+		dir       string
+		canRemove bool
+	}{
+		{defaultDir, true},
+		{userDir, false},
+		{filepath.Join(tmpdir, "missing"), true},
+		{filepath.Join(tmpdir, "empty"), true},
+	}
+	if err := os.Mkdir(filepath.Join(tmpdir, "empty"), 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	for _, test := range tests {
+		canRemove, err := canRemoveScratch(test.dir, defaultDir)
+		if err != nil {
+			t.Fatalf("canRemoveScratch() failed: %v", err)
+		}
+		if canRemove != test.canRemove {
+			t.Errorf("canRemoveScratch(%s) = %v", test.dir, canRemove)
+		}
+	}
+}
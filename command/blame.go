@@ -0,0 +1,194 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/sync"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/file"
+	"github.com/frankbraun/codechain/util/log"
+	"github.com/frankbraun/go-diff/diffmatchpatch"
+)
+
+// uncommitted is the release index of lines which are not contained in any
+// release.
+const uncommitted = -1
+
+// splitLines splits text into lines (including the newlines).
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// blameLines attributes the lines of text b to releases, given the
+// attribution attrA of the lines of text a. Unchanged lines keep their
+// attribution, new and changed lines are attributed to release.
+func blameLines(a, b string, attrA []int, release int) []int {
+	dmp := diffmatchpatch.New()
+	runesA, runesB, _ := dmp.DiffLinesToRunes(a, b)
+	var attrB []int
+	i := 0
+	for _, d := range dmp.DiffMainRunes(runesA, runesB, false) {
+		n := len([]rune(d.Text))
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			attrB = append(attrB, attrA[i:i+n]...)
+			i += n
+		case diffmatchpatch.DiffDelete:
+			i += n
+		case diffmatchpatch.DiffInsert:
+			for k := 0; k < n; k++ {
+				attrB = append(attrB, release)
+			}
+		}
+	}
+	return attrB
+}
+
+// readText reads the UTF-8 file with filename. If the file does not exist
+// or is a symlink, an empty string is returned.
+func readText(filename string) (string, error) {
+	fi, err := os.Lstat(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !fi.Mode().IsRegular() {
+		return "", nil
+	}
+	isBinary, err := file.IsBinary(filename)
+	if err != nil {
+		return "", err
+	}
+	if isBinary {
+		return "", fmt.Errorf("cannot blame binary file: %s", filename)
+	}
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// blame attributes the lines of the file with canonical filename name in the
+// current directory to the releases of hash chain c (as indices into
+// c.TreeHashes()), by applying all patches one after another in a temporary
+// directory.
+func blame(c *hashchain.HashChain, name string) ([]string, []int, error) {
+	tmpdir, err := ioutil.TempDir("", "codechain_blame")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+	var (
+		text string
+		attr []int
+	)
+	treeHashes := c.TreeHashes()
+	for i := 1; i < len(treeHashes); i++ {
+		err := sync.Dir(tmpdir, treeHashes[i], def.PatchDir, "", treeHashes, nil,
+			def.ExcludePaths, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		t, err := readText(filepath.Join(tmpdir, name))
+		if err != nil {
+			return nil, nil, err
+		}
+		if t != text {
+			attr = blameLines(text, t, attr, i)
+			text = t
+		}
+	}
+	// compare with current file
+	t, err := readText(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t != text {
+		attr = blameLines(text, t, attr, uncommitted)
+	}
+	return splitLines(t), attr, nil
+}
+
+func showBlame(c *hashchain.HashChain, lines []string, attr []int) {
+	treeHashes := c.TreeHashes()
+	releases := c.Releases()
+	var shown []int
+	seen := make(map[int]bool)
+	for i, line := range lines {
+		prefix := "uncommitted"
+		if attr[i] != uncommitted {
+			prefix = treeHashes[attr[i]][:8] + "   "
+			if !seen[attr[i]] {
+				seen[attr[i]] = true
+				shown = append(shown, attr[i])
+			}
+		}
+		fmt.Printf("%s %4d %s", prefix, i+1, line)
+		if !strings.HasSuffix(line, "\n") {
+			fmt.Println()
+		}
+	}
+	for _, idx := range shown {
+		// releases are in the same order as treeHashes, without the empty tree
+		r := releases[idx-1]
+		fmt.Println()
+		fmt.Printf("%s (line %d) %s\n", r.TreeHash, r.Line, r.Comment)
+		fmt.Printf("publisher: %s %s\n", r.PubKey, c.SignerComment(r.PubKey))
+		for _, pubKey := range c.Reviewers(r.TreeHash) {
+			fmt.Printf("reviewed:  %s %s\n", pubKey, c.SignerComment(pubKey))
+		}
+	}
+}
+
+// Blame implements the 'blame' command.
+func Blame(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s file\n", argv0)
+		fmt.Fprintf(os.Stderr, "Show which release last changed each line of file.\n")
+		fs.PrintDefaults()
+	}
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	name := filepath.Clean(fs.Arg(0))
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "..") {
+		return fmt.Errorf("file must be relative to current directory: %s", fs.Arg(0))
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	lines, attr, err := blame(c, name)
+	if err != nil {
+		return err
+	}
+	showBlame(c, lines, attr)
+	return nil
+}
@@ -0,0 +1,30 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlameLines(t *testing.T) {
+	v1 := "a\nb\nc\n"
+	v2 := "a\nB\nc\nd\n"
+	v3 := "x\na\nB\nd"
+	attr := blameLines("", v1, nil, 1)
+	if !reflect.DeepEqual(attr, []int{1, 1, 1}) {
+		t.Errorf("wrong attribution for v1: %v", attr)
+	}
+	attr = blameLines(v1, v2, attr, 2)
+	if !reflect.DeepEqual(attr, []int{1, 2, 1, 2}) {
+		t.Errorf("wrong attribution for v2: %v", attr)
+	}
+	attr = blameLines(v2, v3, attr, uncommitted)
+	if !reflect.DeepEqual(attr, []int{uncommitted, 1, 2, uncommitted}) {
+		t.Errorf("wrong attribution for v3: %v", attr)
+	}
+	if lines := splitLines(v3); len(lines) != len(attr) {
+		t.Errorf("wrong number of lines: %d != %d", len(lines), len(attr))
+	}
+	if lines := splitLines(""); len(lines) != 0 {
+		t.Errorf("empty text should not have lines: %v", lines)
+	}
+}
@@ -0,0 +1,123 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/sync"
+	"github.com/frankbraun/codechain/tree"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/log"
+)
+
+// resolveRelease resolves s, which is either a tree hash, a signed tag name,
+// or the line number of a source entry, to a release of hash chain c.
+func resolveRelease(c *hashchain.HashChain, s string) (*hashchain.Release, error) {
+	releases := c.Releases()
+	if line, err := strconv.Atoi(s); err == nil {
+		for _, r := range releases {
+			if r.Line == line {
+				return &r, nil
+			}
+		}
+		return nil, fmt.Errorf("hash chain line %d is not a source entry", line)
+	}
+	treeHash, err := c.ResolveTreeHash(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range releases {
+		if r.TreeHash == treeHash {
+			return &r, nil
+		}
+	}
+	// the empty tree is not published with a source entry
+	if treeHash != tree.EmptyHash {
+		return nil, fmt.Errorf("tree hash %s is not a published release", treeHash)
+	}
+	return &hashchain.Release{TreeHash: treeHash, Signed: true}, nil
+}
+
+func showRelease(c *hashchain.HashChain, r *hashchain.Release) {
+	fmt.Printf("treehash: %s\n", r.TreeHash)
+	if r.TreeHash == tree.EmptyHash {
+		fmt.Println("status: empty tree")
+		return
+	}
+	lastSigned, _ := c.LastSignedTreeHash()
+	switch {
+	case !r.Signed:
+		fmt.Println("status: unsigned")
+	case r.TreeHash == lastSigned:
+		fmt.Println("status: signed")
+	default:
+		fmt.Println("status: superseded")
+	}
+	if r.Revoked != "" {
+		fmt.Printf("REVOKED: %s\n", r.Revoked)
+	}
+	for _, n := range r.Objections {
+		fmt.Printf("OBJECTION: %s (%s %s)\n", n.Text, n.PubKey, c.SignerComment(n.PubKey))
+	}
+	if r.Comment != "" {
+		fmt.Printf("comment: %s\n", r.Comment)
+	}
+	fmt.Printf("published (line %d): %s %s\n", r.Line, r.PubKey, c.SignerComment(r.PubKey))
+	for _, pubKey := range c.Reviewers(r.TreeHash) {
+		fmt.Printf("reviewed: %s %s\n", pubKey, c.SignerComment(pubKey))
+	}
+}
+
+// Checkout implements the 'checkout' command.
+func Checkout(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-dir path] treehash|tag|line\n", argv0)
+		fmt.Fprintf(os.Stderr, "Write tree with treehash (or source entry at line) to directory.\n")
+		fs.PrintDefaults()
+	}
+	dir := fs.String("dir", filepath.Join(treeDirRoot, "checkout"), "Directory to write tree to")
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	if err := c.Close(); err != nil {
+		return err
+	}
+	r, err := resolveRelease(c, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	// only write to existing directories which contain a tree of the hash
+	// chain (or nothing at all), never remove anything
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+	err = sync.Dir(*dir, r.TreeHash, def.PatchDir, reverseDir, c.TreeHashes(),
+		nil, def.ExcludePaths, false)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("checked out to %s\n", *dir)
+	showRelease(c, r)
+	return nil
+}
@@ -2,13 +2,101 @@
 package command
 
 import (
+	"fmt"
 	"path/filepath"
 
+	"github.com/frankbraun/codechain/hashchain"
 	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/diffview"
+	"github.com/frankbraun/codechain/util/git"
+	"github.com/frankbraun/codechain/util/homedir"
+	"github.com/frankbraun/codechain/util/terminal"
 )
 
 var (
 	treeDirRoot = filepath.Join(def.CodechainDir, "tree")
 	treeDirA    = filepath.Join(treeDirRoot, "a")
 	treeDirB    = filepath.Join(treeDirRoot, "b")
+	reverseDir  = filepath.Join(treeDirRoot, "reverse")
+	reviewDir   = filepath.Join(def.CodechainDir, "review")
 )
+
+// ExitError is an error returned by commands which require a specific exit
+// code of the program.
+type ExitError struct {
+	Code int   // exit code
+	Err  error // error which caused the exit
+}
+
+// Error returns the error message of the underlying error.
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// exitErrorf returns an ExitError with the given code and a formatted error
+// message.
+func exitErrorf(code int, format string, a ...interface{}) error {
+	return &ExitError{Code: code, Err: fmt.Errorf(format, a...)}
+}
+
+// readHashChain reads and verifies the hash chain in def.HashchainFile. The
+// already verified prefix is cached in a checkpoint file in the home
+// directory, which is not shared with the hash chain.
+func readHashChain() (*hashchain.HashChain, error) {
+	dir := filepath.Join(homedir.Codechain(), def.CheckpointsSubDir)
+	checkpointFile, err := hashchain.CheckpointFile(dir, def.HashchainFile)
+	if err != nil {
+		return nil, err
+	}
+	return hashchain.ReadFileCheckpoint(def.HashchainFile, checkpointFile)
+}
+
+// showDiff shows the diff between treeDirA and treeDirB, with git-diff if
+// useGit is set and Git is installed, and with the built-in viewer otherwise.
+// If the review progress p is not nil, files viewed with the built-in viewer
+// are marked as reviewed (with git-diff the user is asked to mark all files).
+// It returns the names of the changed files which are not marked as reviewed.
+func showDiff(useGit bool, p *reviewProgress) ([]string, error) {
+	var (
+		marked []string
+		onMark func(name string) error
+	)
+	if p != nil {
+		marked = p.names()
+		onMark = p.mark
+	}
+	if !useGit || !git.Installed() {
+		return diffview.Pager(treeDirA, treeDirB, def.ExcludePaths, marked, onMark)
+	}
+	if err := git.DiffPager(treeDirA, treeDirB); err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, nil
+	}
+	files, err := diffview.Files(treeDirA, treeDirB, def.ExcludePaths)
+	if err != nil {
+		return nil, err
+	}
+	var unmarked []string
+	for _, f := range files {
+		if !p.marked[f.Name] {
+			unmarked = append(unmarked, f.Name)
+		}
+	}
+	if len(unmarked) == 0 {
+		return nil, nil
+	}
+	err = terminal.Confirm(fmt.Sprintf("mark all %d changed files as reviewed?", len(files)))
+	if err == terminal.ErrAbort {
+		return unmarked, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, name := range unmarked {
+		if err := p.mark(name); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
@@ -1,17 +1,24 @@
 package command
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"flag"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/patchfile"
+	"github.com/frankbraun/codechain/tree"
 	"github.com/frankbraun/codechain/util/def"
 	"github.com/frankbraun/codechain/util/file"
+	"github.com/frankbraun/codechain/util/hex"
 	"github.com/frankbraun/codechain/util/homedir"
 	"github.com/frankbraun/codechain/util/seckey"
+	"github.com/frankbraun/codechain/util/signer"
 )
 
 const (
@@ -19,7 +26,70 @@ const (
 	testSig    = "H8TsdqsqPV7ogkjqkfQq_m7sn2Xb8LzyWCOT0ZURKN4uGDlk_cmktt5bxzfIbJ-PTFj_q1kA1erTdKnZy0i_Aw"
 )
 
+// testSigner returns a signer with a new random key.
+func testSigner(t *testing.T) signer.Signer {
+	_, sec, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() failed: %v", err)
+	}
+	var secKey [64]byte
+	copy(secKey[:], sec)
+	return signer.New(secKey)
+}
+
+// testRelease writes release i to tmpdir/release/i and returns its tree
+// hash. Release 0 is empty, release i > 0 contains file a.txt with content i.
+func testRelease(t *testing.T, tmpdir string, i int) [32]byte {
+	dir := filepath.Join(tmpdir, "release", strconv.Itoa(i))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("os.MkdirAll() failed: %v", err)
+	}
+	if i > 0 {
+		fn := filepath.Join(dir, "a.txt")
+		if err := ioutil.WriteFile(fn, []byte(strconv.Itoa(i)+"\n"), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile() failed: %v", err)
+		}
+	}
+	h, err := tree.Hash(dir, nil)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+	return *h
+}
+
+// testPublish publishes release i (see testRelease) in hash chain c with
+// signer s and writes the patch from release i-1 to def.PatchDir in the
+// current working directory.
+func testPublish(t *testing.T, c *hashchain.HashChain, s signer.Signer, tmpdir string, i int) [32]byte {
+	prevHash := testRelease(t, tmpdir, i-1)
+	h := testRelease(t, tmpdir, i)
+	if err := os.MkdirAll(def.PatchDir, 0755); err != nil {
+		t.Fatalf("os.MkdirAll() failed: %v", err)
+	}
+	f, err := os.Create(filepath.Join(def.PatchDir, hex.Encode(prevHash[:])))
+	if err != nil {
+		t.Fatalf("os.Create() failed: %v", err)
+	}
+	prev := filepath.Join(tmpdir, "release", strconv.Itoa(i-1))
+	dir := filepath.Join(tmpdir, "release", strconv.Itoa(i))
+	if err := patchfile.Diff(patchfile.Version, f, prev, dir, nil); err != nil {
+		t.Fatalf("patchfile.Diff() failed: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("f.Close() failed: %v", err)
+	}
+	if _, err := c.Source(h, s, []byte("release "+strconv.Itoa(i))); err != nil {
+		t.Fatalf("c.Source() failed: %v", err)
+	}
+	return h
+}
+
 func TestKey(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd() failed: %v", err)
+	}
+	defer os.Chdir(wd)
 	tmpdirDist, err := ioutil.TempDir("", "command_test")
 	if err != nil {
 		t.Fatalf("TempDir() failed: %v", err)
@@ -189,6 +259,31 @@ func TestHelp(t *testing.T) {
 	if err != flag.ErrHelp {
 		t.Errorf("codechain remkey -h should fail with flag.ErrHelp: %v", err)
 	}
+	// codechain expkey -h
+	err = ExpKey("codechain expkey", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain expkey -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain rotkey -h
+	err = RotKey("codechain rotkey", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain rotkey -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain revoke -h
+	err = Revoke("codechain revoke", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain revoke -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain note -h
+	err = Note("codechain note", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain note -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain tag -h
+	err = Tag("codechain tag", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain tag -h should fail with flag.ErrHelp: %v", err)
+	}
 	// codechain sigctl -h
 	err = SigCtl("codechain sigctl", "-h")
 	if err != flag.ErrHelp {
@@ -204,11 +299,61 @@ func TestHelp(t *testing.T) {
 	if err != flag.ErrHelp {
 		t.Errorf("codechain apply -h should fail with flag.ErrHelp: %v", err)
 	}
+	// codechain sign-request -h
+	err = SignRequest("codechain sign-request", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain sign-request -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain prove -h
+	err = Prove("codechain prove", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain prove -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain verify -h
+	err = Verify("codechain verify", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain verify -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain verify-proof -h
+	err = VerifyProof("codechain verify-proof", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain verify-proof -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain checkout -h
+	err = Checkout("codechain checkout", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain checkout -h should fail with flag.ErrHelp: %v", err)
+	}
 	// codechain status -h
 	err = Status("codechain status", "-h")
 	if err != flag.ErrHelp {
 		t.Errorf("codechain status -h should fail with flag.ErrHelp: %v", err)
 	}
+	// codechain log -h
+	err = Log("codechain log", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain log -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain blame -h
+	err = Blame("codechain blame", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain blame -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain history -h
+	err = History("codechain history", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain history -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain bisect -h
+	err = Bisect("codechain bisect", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain bisect -h should fail with flag.ErrHelp: %v", err)
+	}
+	// codechain export-git -h
+	err = ExportGit("codechain export-git", "-h")
+	if err != flag.ErrHelp {
+		t.Errorf("codechain export-git -h should fail with flag.ErrHelp: %v", err)
+	}
 	// codechain cleanslate -h
 	err = CleanSlate("codechain cleanslate", "-h")
 	if err != flag.ErrHelp {
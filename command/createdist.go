@@ -6,9 +6,7 @@ import (
 	"os"
 
 	"github.com/frankbraun/codechain/archive"
-	"github.com/frankbraun/codechain/hashchain"
 	"github.com/frankbraun/codechain/secpkg"
-	"github.com/frankbraun/codechain/util/def"
 	"github.com/frankbraun/codechain/util/log"
 )
 
@@ -35,7 +33,7 @@ func CreateDist(argv0 string, args ...string) error {
 	if err := secpkg.UpToDate("codechain"); err != nil {
 		return err
 	}
-	c, err := hashchain.ReadFile(def.HashchainFile)
+	c, err := readHashChain()
 	if err != nil {
 		return err
 	}
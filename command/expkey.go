@@ -0,0 +1,59 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/log"
+	"github.com/frankbraun/codechain/util/time"
+)
+
+// ExpKey implements the 'expkey' command.
+func ExpKey(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s pubkey expiry\n", argv0)
+		fmt.Fprintf(os.Stderr, "Set expiry of existing signer in hashchain.\n")
+		fmt.Fprintf(os.Stderr, "The expiry must be given in RFC3339 format (e.g., 2006-01-02T15:04:05Z).\n")
+		fs.PrintDefaults()
+	}
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	pubkey := fs.Arg(0)
+	pub, err := base64.Decode(pubkey, 32)
+	if err != nil {
+		return fmt.Errorf("cannot decode pubkey: %s", err)
+	}
+	expiry, err := time.Parse(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("cannot parse expiry: %s", err)
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	var pubKey [32]byte
+	copy(pubKey[:], pub)
+	line, err := c.ExpireKey(pubKey, expiry)
+	if err != nil {
+		return err
+	}
+	fmt.Println(line)
+	return nil
+}
@@ -0,0 +1,216 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/sync"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/git"
+	"github.com/frankbraun/codechain/util/log"
+)
+
+// Git trailers and notes written by export-git.
+const (
+	trailerTreeHash  = "Codechain-Treehash"
+	trailerLine      = "Codechain-Line"
+	trailerLinkHash  = "Codechain-Link-Hash"
+	trailerPublisher = "Codechain-Publisher"
+	noteReviewed     = "Codechain-Reviewed-By"
+	noteSigned       = "Codechain-Signed"
+	noteRevoked      = "Codechain-Revoked"
+	notesRef         = "codechain"
+)
+
+// gitIdentity returns the name and email address used in Git for the signer
+// with pubKey and comment. If the comment has the form "name <email>" it is
+// used, otherwise the pubKey is used as email address.
+func gitIdentity(pubKey, comment string) (string, string) {
+	i := strings.LastIndex(comment, "<")
+	j := strings.LastIndex(comment, ">")
+	if i >= 0 && j > i {
+		name := strings.TrimSpace(comment[:i])
+		if name == "" {
+			name = pubKey
+		}
+		return name, comment[i+1 : j]
+	}
+	name := strings.TrimSpace(comment)
+	if name == "" {
+		name = pubKey
+	}
+	return name, pubKey
+}
+
+// exportedTreeHash returns the tree hash of the last source exported to the
+// Git repository in dir or an empty string, if the repository has no commits.
+func exportedTreeHash(dir string) (string, error) {
+	if _, err := git.Run(dir, nil, "rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		return "", nil // no commits yet
+	}
+	msg, err := git.Run(dir, nil, "log", "-1", "--format=%B")
+	if err != nil {
+		return "", err
+	}
+	treeHash := git.Trailer(msg, trailerTreeHash)
+	if treeHash == "" {
+		return "", fmt.Errorf("last commit in %s has no %s trailer", dir, trailerTreeHash)
+	}
+	return treeHash, nil
+}
+
+// exportSource commits the tree in dir as the source published in release r
+// of hash chain c. Author and committer are set to the publisher and the
+// time of the source entry, which makes the export reproducible.
+func exportSource(c *hashchain.HashChain, dir string, r *hashchain.Release, e *hashchain.Entry) error {
+	name, email := gitIdentity(r.PubKey, c.SignerComment(r.PubKey))
+	date := fmt.Sprintf("@%d +0000", e.Time)
+	env := []string{
+		"GIT_AUTHOR_NAME=" + name,
+		"GIT_AUTHOR_EMAIL=" + email,
+		"GIT_AUTHOR_DATE=" + date,
+		"GIT_COMMITTER_NAME=" + name,
+		"GIT_COMMITTER_EMAIL=" + email,
+		"GIT_COMMITTER_DATE=" + date,
+	}
+	subject := r.Comment
+	if subject == "" {
+		subject = "codechain release " + r.TreeHash
+	}
+	msg := fmt.Sprintf("%s\n\n%s: %s\n%s: %d\n%s: %s\n%s: %s %s\n", subject,
+		trailerTreeHash, r.TreeHash,
+		trailerLine, r.Line,
+		trailerLinkHash, e.Hash,
+		trailerPublisher, r.PubKey, c.SignerComment(r.PubKey))
+	if _, err := git.Run(dir, nil, "add", "-A", "--force", "."); err != nil {
+		return err
+	}
+	_, err := git.Run(dir, env, "-c", "commit.gpgsign=false", "commit", "-q",
+		"--allow-empty", "--no-verify", "-m", msg)
+	return err
+}
+
+// exportNotes (re)writes the Git notes containing the reviewers and signature
+// status of all exported sources in the Git repository in dir. Notes are used
+// for this, because sources can be reviewed after they have been exported.
+func exportNotes(c *hashchain.HashChain, dir string) error {
+	out, err := git.Run(dir, nil, "log", "-z", "--format=%H%n%B")
+	if err != nil {
+		return err
+	}
+	releases := make(map[string]hashchain.Release)
+	for _, r := range c.Releases() {
+		releases[r.TreeHash] = r
+	}
+	env := []string{
+		"GIT_AUTHOR_NAME=codechain",
+		"GIT_AUTHOR_EMAIL=codechain",
+		"GIT_COMMITTER_NAME=codechain",
+		"GIT_COMMITTER_EMAIL=codechain",
+	}
+	for _, commit := range strings.Split(out, "\x00") {
+		lines := strings.SplitN(commit, "\n", 2)
+		if len(lines) != 2 {
+			continue
+		}
+		r, ok := releases[git.Trailer(lines[1], trailerTreeHash)]
+		if !ok {
+			continue
+		}
+		var note strings.Builder
+		fmt.Fprintf(&note, "%s: %s\n", noteSigned, strconv.FormatBool(r.Signed))
+		if r.Revoked != "" {
+			fmt.Fprintf(&note, "%s: %s\n", noteRevoked, r.Revoked)
+		}
+		for _, pubKey := range c.Reviewers(r.TreeHash) {
+			fmt.Fprintf(&note, "%s: %s %s\n", noteReviewed, pubKey, c.SignerComment(pubKey))
+		}
+		_, err := git.Run(dir, env, "notes", "--ref="+notesRef, "add", "-f",
+			"-m", note.String(), lines[0])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportGit(c *hashchain.HashChain, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// always initialize, dir could be contained in another Git repository
+	// (reinitializing an existing repository is safe)
+	if _, err := git.Run(dir, nil, "init", "-q"); err != nil {
+		return err
+	}
+	exported, err := exportedTreeHash(dir)
+	if err != nil {
+		return err
+	}
+	treeHashes := c.TreeHashes()
+	start := 1
+	if exported != "" {
+		start = 0
+		for i, h := range treeHashes {
+			if h == exported {
+				start = i + 1
+				break
+			}
+		}
+		if start == 0 {
+			return fmt.Errorf("exported tree hash not in hash chain: %s", exported)
+		}
+	}
+	releases := c.Releases()
+	entries := c.Entries()
+	for i := start; i < len(treeHashes); i++ {
+		err := sync.Dir(dir, treeHashes[i], def.PatchDir, "", treeHashes, nil,
+			def.ExcludePaths, false)
+		if err != nil {
+			return err
+		}
+		// releases are in the same order as treeHashes, without the empty tree
+		r := &releases[i-1]
+		if err := exportSource(c, dir, r, &entries[r.Line]); err != nil {
+			return err
+		}
+		fmt.Printf("exported %s %s\n", r.TreeHash, r.Comment)
+	}
+	return exportNotes(c, dir)
+}
+
+// ExportGit implements the 'export-git' command.
+func ExportGit(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s dir\n", argv0)
+		fmt.Fprintf(os.Stderr, "Export hash chain to Git repository in dir (one commit per release).\n")
+		fmt.Fprintf(os.Stderr, "Existing exports are continued with new releases.\n")
+		fs.PrintDefaults()
+	}
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return exportGit(c, fs.Arg(0))
+}
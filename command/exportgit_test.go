@@ -0,0 +1,110 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/git"
+	"github.com/frankbraun/codechain/util/hex"
+)
+
+func TestGitIdentity(t *testing.T) {
+	tests := []struct {
+		comment string
+		name    string
+		email   string
+	}{
+		{"John Doe <john.doe@mailinator.com>", "John Doe", "john.doe@mailinator.com"},
+		{"John Doe", "John Doe", "pubkey"},
+		{"<john.doe@mailinator.com>", "pubkey", "john.doe@mailinator.com"},
+		{"", "pubkey", "pubkey"},
+	}
+	for _, test := range tests {
+		name, email := gitIdentity("pubkey", test.comment)
+		if name != test.name || email != test.email {
+			t.Errorf("gitIdentity(%q) = %q, %q", test.comment, name, email)
+		}
+	}
+}
+
+func TestTrailer(t *testing.T) {
+	msg := "comment\n\n" + trailerTreeHash + ": hash\n" + trailerLine + ": 1\n"
+	if h := git.Trailer(msg, trailerTreeHash); h != "hash" {
+		t.Errorf("wrong trailer: %s", h)
+	}
+	if h := git.Trailer(msg, trailerLinkHash); h != "" {
+		t.Errorf("trailer should be empty: %s", h)
+	}
+}
+
+func TestExportGit(t *testing.T) {
+	if !git.Installed() {
+		t.Skip("git not installed")
+	}
+	tmpdir, err := ioutil.TempDir("", "command_test")
+	if err != nil {
+		t.Fatalf("TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd() failed: %v", err)
+	}
+	defer os.Chdir(wd)
+	// the export directory is contained in another Git repository
+	if _, err := git.Run(tmpdir, nil, "init", "-q"); err != nil {
+		t.Fatalf("git init failed: %v", err)
+	}
+	exportDir := filepath.Join(tmpdir, "export")
+	projectDir := filepath.Join(tmpdir, "project")
+	if err := os.Mkdir(projectDir, 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatalf("os.Chdir() failed: %v", err)
+	}
+	if err := os.MkdirAll(def.CodechainDir, 0755); err != nil {
+		t.Fatalf("os.MkdirAll() failed: %v", err)
+	}
+	s := testSigner(t)
+	c, _, err := hashchain.Start(def.HashchainFile, s, nil)
+	if err != nil {
+		t.Fatalf("hashchain.Start() failed: %v", err)
+	}
+	defer c.Close()
+
+	// export first release, then second release incrementally
+	var treeHashes [][32]byte
+	for i := 1; i <= 2; i++ {
+		treeHashes = append(treeHashes, testPublish(t, c, s, tmpdir, i))
+		if _, err := c.Signature(c.Head(), s, false); err != nil {
+			t.Fatalf("c.Signature() failed: %v", err)
+		}
+		if err := exportGit(c, exportDir); err != nil {
+			t.Fatalf("exportGit() failed: %v", err)
+		}
+		out, err := git.Run(exportDir, nil, "rev-list", "--count", "HEAD")
+		if err != nil {
+			t.Fatalf("git rev-list failed: %v", err)
+		}
+		if n := strings.TrimSpace(out); n != strconv.Itoa(i) {
+			t.Errorf("exportGit() created %s commits instead of %d", n, i)
+		}
+		exported, err := exportedTreeHash(exportDir)
+		if err != nil {
+			t.Fatalf("exportedTreeHash() failed: %v", err)
+		}
+		if exported != hex.Encode(treeHashes[i-1][:]) {
+			t.Errorf("exportGit() exported wrong tree hash: %s", exported)
+		}
+	}
+	if _, err := git.Run(tmpdir, nil, "rev-parse", "--verify", "-q", "HEAD"); err == nil {
+		t.Error("exportGit() committed to parent repository")
+	}
+}
@@ -0,0 +1,124 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/patchfile"
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/log"
+)
+
+// affects returns true, if change c affects the canonical path p (a file or
+// directory). The source of a move is affected, the source of a copy is not.
+func affects(c *patchfile.Change, p string) bool {
+	names := []string{c.Filename}
+	if c.Kind == patchfile.ChangeMovedFrom {
+		names = append(names, c.Other)
+	}
+	for _, name := range names {
+		if name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func showChange(c *patchfile.Change) {
+	switch c.Kind {
+	case patchfile.ChangeDeleted:
+		fmt.Printf("%-11s %s\n", c.Kind, c.Filename)
+	case patchfile.ChangeMovedFrom, patchfile.ChangeCopiedFrom:
+		fmt.Printf("%-11s %c %x %s <- %s\n", c.Kind, c.Mode, c.Hash, c.Filename, c.Other)
+	default:
+		fmt.Printf("%-11s %c %x %s\n", c.Kind, c.Mode, c.Hash, c.Filename)
+	}
+}
+
+func history(c *hashchain.HashChain, p string) error {
+	treeHashes := c.TreeHashes()
+	releases := c.Releases()
+	found := false
+	for i := 0; i < len(treeHashes)-1; i++ {
+		patch, err := os.Open(filepath.Join(def.PatchDir, treeHashes[i]))
+		if err != nil {
+			return err
+		}
+		changes, err := patchfile.Changes(patch)
+		patch.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", treeHashes[i], err)
+		}
+		var affected []patchfile.Change
+		for _, change := range changes {
+			// moves are shown once for the target
+			if change.Kind == patchfile.ChangeMovedTo {
+				continue
+			}
+			if affects(&change, p) {
+				affected = append(affected, change)
+			}
+		}
+		if len(affected) == 0 {
+			continue
+		}
+		if found {
+			fmt.Println()
+		}
+		found = true
+		// patch i leads to treeHashes[i+1], which has been published in releases[i]
+		r := releases[i]
+		fmt.Printf("release %s (line %d) %s\n", r.TreeHash, r.Line, r.Comment)
+		fmt.Printf("publisher: %s %s\n", r.PubKey, c.SignerComment(r.PubKey))
+		for _, pubKey := range c.Reviewers(r.TreeHash) {
+			fmt.Printf("reviewed:  %s %s\n", pubKey, c.SignerComment(pubKey))
+		}
+		for i := range affected {
+			showChange(&affected[i])
+		}
+	}
+	if !found {
+		fmt.Printf("no changes of %s found\n", p)
+	}
+	return nil
+}
+
+// History implements the 'history' command.
+func History(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s path\n", argv0)
+		fmt.Fprintf(os.Stderr, "Show all releases which changed path (a file or directory).\n")
+		fs.PrintDefaults()
+	}
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	p := path.Clean(filepath.ToSlash(fs.Arg(0)))
+	if path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+		return fmt.Errorf("path must be relative to current directory: %s", fs.Arg(0))
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return history(c, p)
+}
@@ -0,0 +1,29 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/frankbraun/codechain/patchfile"
+)
+
+func TestAffects(t *testing.T) {
+	tests := []struct {
+		change  patchfile.Change
+		path    string
+		affects bool
+	}{
+		{patchfile.Change{Kind: patchfile.ChangeModified, Filename: "a.txt"}, "a.txt", true},
+		{patchfile.Change{Kind: patchfile.ChangeModified, Filename: "dir/a.txt"}, "dir", true},
+		{patchfile.Change{Kind: patchfile.ChangeModified, Filename: "dirx/a.txt"}, "dir", false},
+		{patchfile.Change{Kind: patchfile.ChangeMovedFrom, Filename: "b.txt", Other: "a.txt"}, "a.txt", true},
+		{patchfile.Change{Kind: patchfile.ChangeMovedFrom, Filename: "b.txt", Other: "a.txt"}, "b.txt", true},
+		{patchfile.Change{Kind: patchfile.ChangeCopiedFrom, Filename: "b.txt", Other: "a.txt"}, "a.txt", false},
+		{patchfile.Change{Kind: patchfile.ChangeCopiedFrom, Filename: "b.txt", Other: "a.txt"}, "b.txt", true},
+	}
+	for _, test := range tests {
+		if affects(&test.change, test.path) != test.affects {
+			t.Errorf("affects(%s %s <- %s, %s) should be %v", test.change.Kind,
+				test.change.Filename, test.change.Other, test.path, test.affects)
+		}
+	}
+}
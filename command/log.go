@@ -0,0 +1,170 @@
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/hashchain/linktype"
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/util/log"
+	"github.com/frankbraun/codechain/util/time"
+)
+
+// logFilter describes which hash chain log entries are shown.
+type logFilter struct {
+	signer   string // pubkey which published, reviewed, or is changed by entry
+	since    int64  // earliest entry time (Unix time), 0 if undefined
+	until    int64  // latest entry time (Unix time), 0 if undefined
+	treeHash string // tree hash of source entries
+}
+
+// match returns true, if the log entry e matches filter f.
+func (f *logFilter) match(e *hashchain.LogEntry) bool {
+	if f.since != 0 && e.Time < f.since {
+		return false
+	}
+	if f.until != 0 && e.Time > f.until {
+		return false
+	}
+	if f.treeHash != "" && (e.Type != linktype.Source || e.TreeHash != f.treeHash) {
+		return false
+	}
+	if f.signer != "" && e.PubKey != f.signer && e.NewPubKey != f.signer {
+		for _, r := range e.Reviews {
+			if r.PubKey == f.signer {
+				return true
+			}
+		}
+		for _, n := range e.Notes {
+			if n.PubKey == f.signer {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func showLogEntry(c *hashchain.HashChain, e *hashchain.LogEntry) {
+	signed := "signed"
+	if !e.Signed {
+		signed = "unsigned"
+	}
+	switch e.Type {
+	case linktype.Source:
+		fmt.Printf("release %s (line %d, %s)\n", e.TreeHash, e.Line, signed)
+		fmt.Printf("date:      %s\n", time.Format(e.Time))
+		fmt.Printf("publisher: %s %s\n", e.PubKey, c.SignerComment(e.PubKey))
+		if e.Comment != "" {
+			fmt.Printf("comment:   %s\n", e.Comment)
+		}
+		if e.Revoked != "" {
+			fmt.Printf("REVOKED:   %s\n", e.Revoked)
+		}
+		for _, r := range e.Reviews {
+			fmt.Printf("reviewed:  %s %s (line %d, %s)\n", r.PubKey,
+				c.SignerComment(r.PubKey), r.Line, time.Format(r.Time))
+		}
+		showNotes(c, e.Notes)
+		return
+	}
+	fmt.Printf("%s (line %d, %s, %s): ", e.Type, e.Line, time.Format(e.Time), signed)
+	switch e.Type {
+	case linktype.AddKey:
+		fmt.Printf("add key %s with weight %d %s\n", e.PubKey, e.Weight, e.Comment)
+	case linktype.RemoveKey:
+		fmt.Printf("remove key %s\n", e.PubKey)
+	case linktype.ExpireKey:
+		fmt.Printf("key %s expires on %s\n", e.PubKey, time.Format(e.Expiry))
+	case linktype.RotateKey:
+		fmt.Printf("rotate key %s to %s\n", e.PubKey, e.NewPubKey)
+	case linktype.SignatureControl:
+		fmt.Printf("set signature threshold to %d\n", e.M)
+	case linktype.Note:
+		fmt.Printf("%s note for entry %s by %s %s: %s\n", e.Flag, e.LinkHash,
+			e.PubKey, c.SignerComment(e.PubKey), e.Comment)
+	}
+	showNotes(c, e.Notes)
+}
+
+func showNotes(c *hashchain.HashChain, notes []hashchain.Note) {
+	for i := range notes {
+		fmt.Println(formatNote(c, &notes[i]))
+	}
+}
+
+// Log implements the 'log' command.
+func Log(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-block-nacks] [-json] [-signer pubkey] [-since date] [-until date] [-t treehash|tag]\n", argv0)
+		fmt.Fprintf(os.Stderr, "Show releases with their reviews, notes, and key changes of hash chain.\n")
+		fs.PrintDefaults()
+	}
+	blockNacks := fs.Bool("block-nacks", false, "Releases with unresolved objections do not count as signed")
+	jsonOutput := fs.Bool("json", false, "Print log in JSON format to stdout")
+	signer := fs.String("signer", "", "Only show entries published, reviewed, annotated, or changing key of signer")
+	since := fs.String("since", "", "Only show entries since date (RFC3339)")
+	until := fs.String("until", "", "Only show entries until date (RFC3339)")
+	treeHash := fs.String("t", "", "Only show release of given treehash or tag")
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	c.SetObjectionPolicy(*blockNacks)
+	f := logFilter{signer: *signer}
+	if *since != "" {
+		f.since, err = time.Parse(*since)
+		if err != nil {
+			return err
+		}
+	}
+	if *until != "" {
+		f.until, err = time.Parse(*until)
+		if err != nil {
+			return err
+		}
+	}
+	if *treeHash != "" {
+		f.treeHash, err = c.ResolveTreeHash(*treeHash)
+		if err != nil {
+			return err
+		}
+	}
+	entries := []hashchain.LogEntry{}
+	for _, e := range c.Log() {
+		if f.match(&e) {
+			entries = append(entries, e)
+		}
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	for i := range entries {
+		if i > 0 {
+			fmt.Println()
+		}
+		showLogEntry(c, &entries[i])
+	}
+	return nil
+}
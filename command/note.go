@@ -0,0 +1,110 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/homedir"
+	"github.com/frankbraun/codechain/util/log"
+	"github.com/frankbraun/codechain/util/seckey"
+	"github.com/frankbraun/codechain/util/time"
+)
+
+// formatNote formats note n (without the annotated entry) for display.
+func formatNote(c *hashchain.HashChain, n *hashchain.Note) string {
+	label := "note"
+	switch n.Flag {
+	case hashchain.NoteObjection:
+		label = "OBJECTION"
+		if n.Resolved {
+			label = "objection (resolved)"
+		}
+	case hashchain.NoteResolve:
+		label = "resolved"
+	}
+	return fmt.Sprintf("%s: %s (%s %s, line %d, %s)", label, n.Text, n.PubKey,
+		c.SignerComment(n.PubKey), n.Line, time.Format(n.Time))
+}
+
+// noteTarget resolves target, which is either a tree hash, a signed tag name,
+// or the link hash of a hash chain entry, to a link hash.
+func noteTarget(c *hashchain.HashChain, target string) ([32]byte, error) {
+	var linkHash [32]byte
+	if treeHash, err := c.ResolveTreeHash(target); err == nil {
+		return c.LinkHash(treeHash), nil
+	}
+	h, err := hex.Decode(target, 32)
+	if err != nil {
+		return linkHash, fmt.Errorf("cannot resolve treehash, tag, or linkhash: %s", target)
+	}
+	copy(linkHash[:], h)
+	return linkHash, nil
+}
+
+// Note implements the 'note' command.
+func Note(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-s seckey.bin | -agent] [-nack | -ack] treehash|tag|linkhash text\n", argv0)
+		fmt.Fprintf(os.Stderr, "Add signed note with text to release or hash chain entry.\n")
+		fs.PrintDefaults()
+	}
+	secKey := fs.String("s", "", "Secret key file")
+	agent := fs.Bool("agent", false, "Use Ed25519 key from ssh-agent")
+	nack := fs.Bool("nack", false, "Note is an objection against the entry")
+	ack := fs.Bool("ack", false, "Note resolves own objections against the entry")
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if *nack && *ack {
+		return fmt.Errorf("%s: options -nack and -ack exclude each other", argv0)
+	}
+	if *agent {
+		if *secKey != "" {
+			return fmt.Errorf("%s: options -s and -agent exclude each other", argv0)
+		}
+	} else if err := seckey.Check(homedir.Codechain(), *secKey); err != nil {
+		return err
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	noteFlag := hashchain.NoteInfo
+	if *nack {
+		noteFlag = hashchain.NoteObjection
+	} else if *ack {
+		noteFlag = hashchain.NoteResolve
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	linkHash, err := noteTarget(c, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	s, err := seckey.LoadSigner(c, homedir.Codechain(), *secKey, *agent)
+	if err != nil {
+		return err
+	}
+	defer s.Zero()
+	line, err := c.Note(linkHash, s, noteFlag, []byte(fs.Arg(1)))
+	if err != nil {
+		return err
+	}
+	fmt.Println(line)
+	return nil
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// reviewProgress records which changed files of the patch between two tree
+// hashes have been marked as reviewed by a reviewer. It is stored in
+// reviewDir, which allows to resume reviews of large patches later.
+type reviewProgress struct {
+	filename string          // file the progress is stored in
+	marked   map[string]bool // changed files marked as reviewed
+}
+
+// progressFile returns the file storing the review progress of the signer
+// with pubKey for the patch from treeHashA to treeHashB.
+func progressFile(pubKey, treeHashA, treeHashB string) string {
+	return filepath.Join(reviewDir, pubKey, treeHashA+"_"+treeHashB)
+}
+
+// loadProgress loads the review progress of the signer with pubKey for the
+// patch from treeHashA to treeHashB.
+func loadProgress(pubKey, treeHashA, treeHashB string) (*reviewProgress, error) {
+	p := &reviewProgress{
+		filename: progressFile(pubKey, treeHashA, treeHashB),
+		marked:   make(map[string]bool),
+	}
+	f, err := os.Open(p.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if s.Text() != "" {
+			p.marked[s.Text()] = true
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// names returns the names of the files marked as reviewed in lexical order.
+func (p *reviewProgress) names() []string {
+	var names []string
+	for name := range p.marked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mark marks the file with the given name as reviewed and saves the progress
+// immediately.
+func (p *reviewProgress) mark(name string) error {
+	if p.marked[name] {
+		return nil
+	}
+	if strings.Contains(name, "\n") {
+		return fmt.Errorf("cannot record review progress of file: %q", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p.filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, name); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	p.marked[name] = true
+	return nil
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestReviewProgress(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "progress_test")
+	if err != nil {
+		t.Fatalf("TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	defer func(dir string) { reviewDir = dir }(reviewDir)
+	reviewDir = tmpdir
+	p, err := loadProgress("pubkey", "a", "b")
+	if err != nil {
+		t.Fatalf("loadProgress() failed: %v", err)
+	}
+	if len(p.marked) != 0 {
+		t.Errorf("new review progress should be empty")
+	}
+	for _, name := range []string{"z.txt", "dir/a.txt", "z.txt"} {
+		if err := p.mark(name); err != nil {
+			t.Fatalf("mark() failed: %v", err)
+		}
+	}
+	if err := p.mark("new\nline"); err == nil {
+		t.Error("mark() should fail for filenames with newlines")
+	}
+	p, err = loadProgress("pubkey", "a", "b")
+	if err != nil {
+		t.Fatalf("loadProgress() failed: %v", err)
+	}
+	if !reflect.DeepEqual(p.names(), []string{"dir/a.txt", "z.txt"}) {
+		t.Errorf("wrong review progress loaded: %v", p.names())
+	}
+	p, err = loadProgress("other", "a", "b")
+	if err != nil {
+		t.Fatalf("loadProgress() failed: %v", err)
+	}
+	if len(p.marked) != 0 {
+		t.Errorf("review progress of other signer should be empty")
+	}
+}
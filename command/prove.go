@@ -0,0 +1,75 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/log"
+)
+
+// Prove implements the 'prove' command.
+func Prove(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-head head] [-o proof.txt] [treehash|tag]\n", argv0)
+		fmt.Fprintf(os.Stderr, "Prove that treehash (default: last signed) is signed as of head (default: current head).\n")
+		fmt.Fprintf(os.Stderr, "Only entries which affect the signers or treehash are replayed by the verifier,\n")
+		fmt.Fprintf(os.Stderr, "all other entries up to head are included in reduced form to link them to head.\n")
+		fs.PrintDefaults()
+	}
+	headStr := fs.String("head", "", "Prove relative to the given head")
+	output := fs.String("o", "", "Write proof to file (default: stdout)")
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	treeHash, _ := c.LastSignedTreeHash()
+	if fs.NArg() == 1 {
+		treeHash, err = c.ResolveTreeHash(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+	}
+	head := c.Head()
+	if *headStr != "" {
+		h, err := hex.Decode(*headStr, 32)
+		if err != nil {
+			return fmt.Errorf("cannot decode head: %s", err)
+		}
+		copy(head[:], h)
+	}
+	p, err := c.Prove(treeHash, head)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		return p.Write(os.Stdout)
+	}
+	fp, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := p.Write(fp); err != nil {
+		fp.Close()
+		return err
+	}
+	return fp.Close()
+}
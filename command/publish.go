@@ -2,10 +2,13 @@ package command
 
 import (
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/frankbraun/codechain/hashchain"
 	"github.com/frankbraun/codechain/patchfile"
@@ -20,17 +23,52 @@ import (
 	"github.com/frankbraun/codechain/util/interrupt"
 	"github.com/frankbraun/codechain/util/log"
 	"github.com/frankbraun/codechain/util/seckey"
+	"github.com/frankbraun/codechain/util/signer"
 	"github.com/frankbraun/codechain/util/terminal"
 )
 
+// errTreeNotDirty is returned by publish, if there is nothing to publish.
+var errTreeNotDirty = errors.New("tree not dirty, nothing to publish")
+
+// lazySigner loads the signer for publishing on first use. This makes sure
+// the passphrase is only asked if something is published and at most once,
+// even if many sources are published.
+type lazySigner struct {
+	c          *hashchain.HashChain
+	secKeyFile string
+	agent      bool
+	s          signer.Signer
+}
+
+// get returns the signer and loads it, if necessary.
+func (l *lazySigner) get() (signer.Signer, error) {
+	if l.s == nil {
+		s, err := seckey.LoadSigner(l.c, homedir.Codechain(), l.secKeyFile, l.agent)
+		if err != nil {
+			return nil, err
+		}
+		l.s = s
+	}
+	return l.s, nil
+}
+
+// zero wipes the secret key of the signer, if it has been loaded.
+func (l *lazySigner) zero() {
+	if l.s != nil {
+		l.s.Zero()
+	}
+}
+
+// publish publishes the tree in directory dir (usually ".") as a new source
+// with the signer from ls.
 func publish(
-	c *hashchain.HashChain, secKeyFile, message string,
+	c *hashchain.HashChain, dir string, ls *lazySigner, message string,
 	dryRun, useGit, yesPrompt bool,
 	version int,
 ) error {
 	var (
-		secKey *[64]byte
-		err    error
+		s   signer.Signer
+		err error
 	)
 
 	// get last published treehash
@@ -47,7 +85,7 @@ func publish(
 	}
 
 	// calculate current treehash
-	curHash, err := tree.Hash(".", def.ExcludePaths)
+	curHash, err := tree.Hash(dir, def.ExcludePaths)
 	if err != nil {
 		return err
 	}
@@ -56,12 +94,12 @@ func publish(
 
 	// make sure the tree is dirty
 	if curHashStr == treeHash {
-		return fmt.Errorf("tree not dirty, nothing to publish")
+		return errTreeNotDirty
 	}
 
 	// load secret key
 	if !dryRun {
-		secKey, _, _, err = seckey.Load(c, homedir.Codechain(), secKeyFile)
+		s, err = ls.get()
 		if err != nil {
 			return err
 		}
@@ -70,7 +108,7 @@ func publish(
 	// bring .codechain/tree/a in sync with last published treehash
 	log.Println("sync tree/a")
 	treeHashes := c.TreeHashes()
-	err = sync.Dir(treeDirA, treeHash, def.PatchDir, treeHashes, def.ExcludePaths, true)
+	err = sync.Dir(treeDirA, treeHash, def.PatchDir, reverseDir, treeHashes, nil, def.ExcludePaths, true)
 	if err != nil {
 		return err
 	}
@@ -85,14 +123,14 @@ func publish(
 		if err := os.RemoveAll(treeDirB); err != nil {
 			return err
 		}
-		if err := file.CopyDirExclude(".", treeDirB, def.ExcludePaths); err != nil {
+		if err := file.CopyDirExclude(dir, treeDirB, def.ExcludePaths); err != nil {
 			return err
 		}
 	}
 
-	if useGit && !yesPrompt {
+	if !yesPrompt {
 		// display diff pager
-		if err := git.DiffPager(treeDirA, treeDirB); err != nil {
+		if _, err := showDiff(useGit, nil); err != nil {
 			return err
 		}
 	} else {
@@ -143,7 +181,7 @@ func publish(
 
 	// apply patch file to .codechain/tree/a to make sure it works
 	treeHashes = append(treeHashes, curHashStr)
-	err = sync.Dir(treeDirA, curHashStr, def.PatchDir, treeHashes, def.ExcludePaths, false)
+	err = sync.Dir(treeDirA, curHashStr, def.PatchDir, reverseDir, treeHashes, nil, def.ExcludePaths, false)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s: is faulty (this is a bug, please report it)\n",
 			patchFile)
@@ -151,7 +189,7 @@ func publish(
 	}
 
 	// sign patch and add to hash chain
-	entry, err := c.Source(*curHash, *secKey, comment)
+	entry, err := c.Source(*curHash, s, comment)
 	if err != nil {
 		return err
 	}
@@ -160,18 +198,90 @@ func publish(
 	return nil
 }
 
+// publishGit publishes the tree of Git revision rev with the signer from ls
+// (with the subject of the commit message as comment, if message is empty,
+// or the commit hash, if the subject is empty). If rev is a revision range
+// (containing ".."), every commit in the range is published as a separate
+// source, oldest first. Commits which do not change the tree are skipped.
+func publishGit(
+	c *hashchain.HashChain, rev string, ls *lazySigner, message string,
+	dryRun, useGit, yesPrompt bool,
+	version int,
+) error {
+	revs := []string{rev}
+	if strings.Contains(rev, "..") {
+		if message != "" {
+			return errors.New("option -m cannot be used with a Git revision range")
+		}
+		out, err := git.Run(".", nil, "rev-list", "--reverse", rev, "--")
+		if err != nil {
+			return err
+		}
+		revs = strings.Fields(out)
+		if len(revs) == 0 {
+			return fmt.Errorf("no commits in Git revision range %s", rev)
+		}
+	}
+	for _, r := range revs {
+		out, err := git.Run(".", nil, "log", "-1", "--format=%H%n%s", r, "--")
+		if err != nil {
+			return err
+		}
+		lines := strings.SplitN(strings.TrimSpace(out), "\n", 2)
+		commit := lines[0]
+		comment := message
+		if comment == "" && len(lines) == 2 {
+			comment = strings.TrimSpace(lines[1])
+		}
+		if comment == "" {
+			// never prompt for a comment in the middle of a range
+			comment = "commit " + commit
+		}
+		fmt.Printf("commit %s %s\n", commit, comment)
+		tmpdir, err := ioutil.TempDir("", "codechain_publish")
+		if err != nil {
+			return err
+		}
+		err = git.Export(".", commit, tmpdir)
+		if err == nil {
+			err = publish(c, tmpdir, ls, comment, dryRun, useGit, yesPrompt,
+				version)
+		}
+		os.RemoveAll(tmpdir)
+		if err == errTreeNotDirty && len(revs) > 1 {
+			fmt.Println("tree not changed, commit skipped")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if dryRun && len(revs) > 1 {
+			// trees of later commits are based on unpublished ones
+			fmt.Println("dry run stops after first commit")
+			return nil
+		}
+	}
+	return nil
+}
+
 // Publish implements the 'publish' command.
 func Publish(argv0 string, args ...string) error {
 	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [-s seckey.bin]\n", argv0)
+		fmt.Fprintf(os.Stderr, "Usage: %s [-s seckey.bin | -agent] [-rev rev|range]\n", argv0)
 		fmt.Fprintf(os.Stderr, "Add signed changes in tree to .codechain ready for publication.\n")
+		fmt.Fprintf(os.Stderr, "With -rev the tree of Git revision rev is published instead, with\n")
+		fmt.Fprintf(os.Stderr, "the commit subject as comment. If rev is a range (like v1.0..main),\n")
+		fmt.Fprintf(os.Stderr, "one source is published per commit in the range. Revisions are given\n")
+		fmt.Fprintf(os.Stderr, "with -rev, not -git (the boolean option to show diffs with git-diff).\n")
 		fs.PrintDefaults()
 	}
 	dryRun := fs.Bool("d", false, "Dry run, just show diff without signing anything")
 	message := fs.String("m", "", "Use the given message as the comment describing the code change")
-	useGit := fs.Bool("git", true, "Use git-diff to show diffs")
+	useGit := fs.Bool("git", true, "Use git-diff to show diffs (built-in viewer otherwise)")
+	rev := fs.String("rev", "", "Git revision (or revision range) to publish")
 	secKey := fs.String("s", "", "Secret key file")
+	agent := fs.Bool("agent", false, "Use Ed25519 key from ssh-agent")
 	verbose := fs.Bool("v", false, "Be verbose")
 	version := fs.Int("version", patchfile.Version, "Patchfile version to publish")
 	yesPrompt := fs.Bool("y", false, "Automatic yes to prompts, use with care!")
@@ -184,7 +294,10 @@ func Publish(argv0 string, args ...string) error {
 	if *version < 1 || *version > patchfile.Version {
 		return patchfile.ErrHeaderVersion
 	}
-	if !*dryRun {
+	if *agent && *secKey != "" {
+		return fmt.Errorf("%s: options -s and -agent exclude each other", argv0)
+	}
+	if !*dryRun && !*agent {
 		if err := seckey.Check(homedir.Codechain(), *secKey); err != nil {
 			return err
 		}
@@ -205,7 +318,7 @@ func Publish(argv0 string, args ...string) error {
 	if err := os.MkdirAll(def.PatchDir, 0755); err != nil {
 		return err
 	}
-	c, err := hashchain.ReadFile(def.HashchainFile)
+	c, err := readHashChain()
 	if err != nil {
 		return err
 	}
@@ -216,7 +329,16 @@ func Publish(argv0 string, args ...string) error {
 	})
 	// run publish
 	go func() {
-		err := publish(c, *secKey, *message, *dryRun, *useGit, *yesPrompt, *version)
+		var err error
+		ls := &lazySigner{c: c, secKeyFile: *secKey, agent: *agent}
+		if *rev != "" {
+			err = publishGit(c, *rev, ls, *message, *dryRun, *useGit,
+				*yesPrompt, *version)
+		} else {
+			err = publish(c, ".", ls, *message, *dryRun, *useGit, *yesPrompt,
+				*version)
+		}
+		ls.zero()
 		if err != nil {
 			interrupt.ShutdownChannel <- err
 			return
@@ -0,0 +1,145 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/patchfile"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/git"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/homedir"
+	"github.com/frankbraun/codechain/util/seckey"
+)
+
+func TestPublishGit(t *testing.T) {
+	if !git.Installed() {
+		t.Skip("git not installed")
+	}
+	tmpdir, err := ioutil.TempDir("", "command_test")
+	if err != nil {
+		t.Fatalf("TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd() failed: %v", err)
+	}
+	defer os.Chdir(wd)
+	projectDir := filepath.Join(tmpdir, "project")
+	if err := os.Mkdir(projectDir, 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatalf("os.Chdir() failed: %v", err)
+	}
+
+	// create Git repository with three commits, the second one is empty
+	env := []string{
+		"GIT_AUTHOR_NAME=John Doe",
+		"GIT_AUTHOR_EMAIL=john.doe@mailinator.com",
+		"GIT_COMMITTER_NAME=John Doe",
+		"GIT_COMMITTER_EMAIL=john.doe@mailinator.com",
+	}
+	if _, err := git.Run(".", nil, "init", "-q"); err != nil {
+		t.Fatalf("git init failed: %v", err)
+	}
+	commits := []struct {
+		content string
+		subject string
+	}{
+		{"1\n", "first commit"},
+		{"", "empty commit"},
+		{"2\n", ""}, // empty subject
+	}
+	for _, commit := range commits {
+		if commit.content != "" {
+			err := ioutil.WriteFile("a.txt", []byte(commit.content), 0644)
+			if err != nil {
+				t.Fatalf("ioutil.WriteFile() failed: %v", err)
+			}
+			if _, err := git.Run(".", nil, "add", "a.txt"); err != nil {
+				t.Fatalf("git add failed: %v", err)
+			}
+		}
+		_, err := git.Run(".", env, "-c", "commit.gpgsign=false", "commit", "-q",
+			"--allow-empty", "--allow-empty-message", "-m", commit.subject)
+		if err != nil {
+			t.Fatalf("git commit failed: %v", err)
+		}
+	}
+	// uncommitted changes are not published
+	if err := ioutil.WriteFile("a.txt", []byte("3\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile() failed: %v", err)
+	}
+
+	// start hash chain
+	seckey.TestPass = "passphrase"
+	TestComment = "John Doe"
+	secKeyFile := filepath.Join(tmpdir, "seckey.bin")
+	err = KeyGen("codechain", homedir.Codechain(), "keygen", "-s", secKeyFile)
+	if err != nil {
+		t.Fatalf("KeyGen() failed: %v ", err)
+	}
+	if err := Start("start", "-s", secKeyFile); err != nil {
+		t.Fatalf("Start() failed: %v ", err)
+	}
+	for _, dir := range []string{treeDirA, treeDirB, def.PatchDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("os.MkdirAll() failed: %v", err)
+		}
+	}
+	c, err := hashchain.ReadFile(def.HashchainFile)
+	if err != nil {
+		t.Fatalf("hashchain.ReadFile() failed: %v", err)
+	}
+	defer c.Close()
+	ls := &lazySigner{c: c, secKeyFile: secKeyFile}
+	defer ls.zero()
+
+	// -m cannot be used with a range
+	err = publishGit(c, "HEAD~2..HEAD", ls, "message", false,
+		false, true, patchfile.Version)
+	if err == nil {
+		t.Error("publishGit() should fail with -m and a revision range")
+	}
+
+	// publish first commit, then the rest of the commits as a range
+	err = publishGit(c, "HEAD~2", ls, "", false, false, true,
+		patchfile.Version)
+	if err != nil {
+		t.Fatalf("publishGit() failed: %v", err)
+	}
+	// the signer is only loaded once
+	if err := os.Remove(secKeyFile); err != nil {
+		t.Fatalf("os.Remove() failed: %v", err)
+	}
+	err = publishGit(c, "HEAD~2..HEAD", ls, "", false, false, true,
+		patchfile.Version)
+	if err != nil {
+		t.Fatalf("publishGit() failed: %v", err)
+	}
+	releases := c.Releases()
+	if len(releases) != 2 {
+		t.Fatalf("publishGit() published %d releases instead of 2", len(releases))
+	}
+	for i, r := range releases {
+		h := testRelease(t, tmpdir, i+1)
+		if r.TreeHash != hex.Encode(h[:]) {
+			t.Errorf("publishGit() published wrong tree: %s", r.TreeHash)
+		}
+	}
+	head, err := git.Run(".", nil, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatalf("git rev-parse failed: %v", err)
+	}
+	if releases[0].Comment != "first commit" ||
+		releases[1].Comment != "commit "+strings.TrimSpace(head) {
+		t.Errorf("publishGit() used wrong comments: %q, %q", releases[0].Comment,
+			releases[1].Comment)
+	}
+}
@@ -5,10 +5,8 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/frankbraun/codechain/hashchain"
 	"github.com/frankbraun/codechain/secpkg"
 	"github.com/frankbraun/codechain/util/base64"
-	"github.com/frankbraun/codechain/util/def"
 	"github.com/frankbraun/codechain/util/log"
 )
 
@@ -39,7 +37,7 @@ func RemKey(argv0 string, args ...string) error {
 	if err != nil {
 		return fmt.Errorf("cannot decode pubkey: %s", err)
 	}
-	c, err := hashchain.ReadFile(def.HashchainFile)
+	c, err := readHashChain()
 	if err != nil {
 		return err
 	}
@@ -8,14 +8,15 @@ import (
 
 	"github.com/frankbraun/codechain/hashchain"
 	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/signreq"
 	"github.com/frankbraun/codechain/sync"
 	"github.com/frankbraun/codechain/util/base64"
 	"github.com/frankbraun/codechain/util/def"
-	"github.com/frankbraun/codechain/util/git"
 	"github.com/frankbraun/codechain/util/homedir"
 	"github.com/frankbraun/codechain/util/interrupt"
 	"github.com/frankbraun/codechain/util/log"
 	"github.com/frankbraun/codechain/util/seckey"
+	"github.com/frankbraun/codechain/util/signer"
 	"github.com/frankbraun/codechain/util/terminal"
 )
 
@@ -37,47 +38,79 @@ func showPatchInfo(
 	if comment != "" {
 		fmt.Println(comment)
 	}
+	line := c.SourceLine(treeHashes[i])
+	for _, n := range c.Notes() {
+		if n.Target == line {
+			fmt.Println(formatNote(c, &n))
+		}
+	}
 }
 
-func procDiff(i int, treeHashes []string, useGit bool) error {
+func procDiff(i int, treeHashes []string, useGit bool, pubKey string) ([]string, error) {
 	// bring .codechain/tree/a in sync
 	log.Println("bring .codechain/tree/a in sync")
-	err := sync.Dir(treeDirA, treeHashes[i-1], def.PatchDir, treeHashes, def.ExcludePaths, true)
+	err := sync.Dir(treeDirA, treeHashes[i-1], def.PatchDir, reverseDir, treeHashes, nil, def.ExcludePaths, true)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// bring .codechain/tree/b in sync
 	log.Println("bring .codechain/tree/b in sync")
-	err = sync.Dir(treeDirB, treeHashes[i], def.PatchDir, treeHashes, def.ExcludePaths, true)
+	err = sync.Dir(treeDirB, treeHashes[i], def.PatchDir, reverseDir, treeHashes, nil, def.ExcludePaths, true)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if useGit {
-		// display diff pager
-		if err := git.DiffPager(treeDirA, treeDirB); err != nil {
-			return err
-		}
-	} else {
-		fmt.Println("review diff between the following two directries:")
-		fmt.Println(treeDirA)
-		fmt.Println(treeDirB)
+	// load review progress
+	p, err := loadProgress(pubKey, treeHashes[i-1], treeHashes[i])
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	if len(p.marked) > 0 {
+		fmt.Printf("resuming review: %d changed files already marked as reviewed\n",
+			len(p.marked))
+	}
+
+	// display diff pager
+	return showDiff(useGit, p)
 }
 
-func review(c *hashchain.HashChain, secKeyFile, treeHash string, detached, useGit bool) error {
-	// load secret key
-	log.Println("review(): load secret key")
-	secKey, _, _, err := seckey.Load(c, homedir.Codechain(), secKeyFile)
-	if err != nil {
-		return err
+func review(
+	c *hashchain.HashChain,
+	secKeyFile string,
+	agent bool,
+	requestPubKey, treeHash string,
+	detached, useGit bool,
+) error {
+	var (
+		s   signer.Signer
+		pub [32]byte
+	)
+	if requestPubKey != "" {
+		// signing request for offline signing, no secret key required
+		p, err := base64.Decode(requestPubKey, 32)
+		if err != nil {
+			return fmt.Errorf("cannot decode pubkey: %s", err)
+		}
+		if !c.Signer()[requestPubKey] {
+			return fmt.Errorf("pubkey %s is not an active signer", requestPubKey)
+		}
+		copy(pub[:], p)
+	} else {
+		// load secret key
+		log.Println("review(): load secret key")
+		var err error
+		s, err = seckey.LoadSigner(c, homedir.Codechain(), secKeyFile, agent)
+		if err != nil {
+			return err
+		}
+		defer s.Zero()
+		log.Println("review(): loaded")
+		pub = s.PublicKey()
 	}
-	log.Println("review(): loaded")
 
 	// get last tree hashes
-	_, idx := c.LastSignedTreeHash()
+	idx := c.LastSignedIndex()
 	treeHashes := c.TreeHashes()
 	treeComments := c.TreeComments()
 	if len(treeHashes) != len(treeComments) {
@@ -116,7 +149,7 @@ func review(c *hashchain.HashChain, secKeyFile, treeHash string, detached, useGi
 
 	// show changes in signers/sigctl
 	var signed bool
-	pubKey := base64.Encode(secKey[32:])
+	pubKey := base64.Encode(pub[:])
 	infos, err := c.UnsignedInfo(pubKey, treeHash, true)
 	if err != nil {
 		return err
@@ -151,7 +184,7 @@ outer:
 				}
 				return err
 			}
-			if err := procDiff(i, treeHashes, useGit); err != nil {
+			if _, err := procDiff(i, treeHashes, useGit, pubKey); err != nil {
 				return err
 			}
 		}
@@ -163,10 +196,22 @@ outer:
 			if err := terminal.Confirm("review patch (no aborts)?"); err != nil {
 				return err
 			}
-			if err := procDiff(i, treeHashes, useGit); err != nil {
+			unmarked, err := procDiff(i, treeHashes, useGit, pubKey)
+			if err != nil {
 				return err
 			}
-			if err := terminal.Confirm("sign patch?"); err != nil {
+			// only offer signing if all changed files are marked as reviewed
+			if len(unmarked) > 0 {
+				fmt.Printf("%d changed files not marked as reviewed (progress is saved, review again to resume)\n",
+					len(unmarked))
+				err = terminal.Confirm("sign patch anyway?")
+			} else {
+				err = terminal.Confirm("sign patch?")
+			}
+			if err != nil {
+				if err == terminal.ErrAbort {
+					fmt.Printf("record why with: codechain note -nack %s reason\n", treeHashes[i])
+				}
 				return err
 			}
 			signed = true
@@ -190,7 +235,10 @@ outer:
 	} else {
 		linkHash = c.Head()
 	}
-	entry, err := c.Signature(linkHash, *secKey, detached)
+	if requestPubKey != "" {
+		return printSigningRequest(c, linkHash, pub, treeHash)
+	}
+	entry, err := c.Signature(linkHash, s, detached)
 	if err != nil {
 		return err
 	}
@@ -198,6 +246,24 @@ outer:
 	return nil
 }
 
+func printSigningRequest(
+	c *hashchain.HashChain,
+	linkHash, pubKey [32]byte,
+	treeHash string,
+) error {
+	infos, err := c.UnsignedInfo(base64.Encode(pubKey[:]), treeHash, false)
+	if err != nil {
+		return err
+	}
+	if treeHash != "" {
+		infos = append([]string{"treehash: " + treeHash}, infos...)
+	}
+	r := signreq.NewReview(linkHash, pubKey, infos)
+	fmt.Println("sign the following request offline with 'codechain sign-request':")
+	fmt.Println(r.Marshal())
+	return nil
+}
+
 func addDetached(c *hashchain.HashChain, linkHash, pubKey, signature string) error {
 	entry, err := c.DetachedSignature(linkHash, pubKey, signature)
 	if err != nil {
@@ -211,15 +277,19 @@ func addDetached(c *hashchain.HashChain, linkHash, pubKey, signature string) err
 func Review(argv0 string, args ...string) error {
 	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [-s seckey.bin] [treehash]\n", argv0)
+		fmt.Fprintf(os.Stderr, "Usage: %s [-s seckey.bin | -agent] [treehash|tag]\n", argv0)
+		fmt.Fprintf(os.Stderr, "       %s -r pubkey [treehash|tag]\n", argv0)
 		fmt.Fprintf(os.Stderr, "       %s -a linkhash pubkey signature\n", argv0)
 		fmt.Fprintf(os.Stderr, "Review code changes (all or up to treehash) and changes of signers and sigctl.\n")
+		fmt.Fprintf(os.Stderr, "Files marked as reviewed are stored in %s to resume reviews later.\n", reviewDir)
 		fs.PrintDefaults()
 	}
 	add := fs.Bool("a", false, "Add detached signature")
 	detached := fs.Bool("d", false, "Create detached signature")
-	useGit := fs.Bool("git", true, "Use git-diff to show diffs")
+	useGit := fs.Bool("git", true, "Use git-diff to show diffs (built-in viewer otherwise)")
+	request := fs.String("r", "", "Create signing request for offline signing with pubkey")
 	secKey := fs.String("s", "", "Secret key file")
+	agent := fs.Bool("agent", false, "Use Ed25519 key from ssh-agent")
 	verbose := fs.Bool("v", false, "Be verbose")
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -227,7 +297,15 @@ func Review(argv0 string, args ...string) error {
 	if *verbose {
 		log.Std = log.NewStd(os.Stdout)
 	}
-	if err := seckey.Check(homedir.Codechain(), *secKey); err != nil {
+	if *request != "" {
+		if *secKey != "" || *agent || *detached || *add {
+			return fmt.Errorf("%s: option -r excludes options -s, -agent, -d, and -a", argv0)
+		}
+	} else if *agent {
+		if *secKey != "" {
+			return fmt.Errorf("%s: options -s and -agent exclude each other", argv0)
+		}
+	} else if err := seckey.Check(homedir.Codechain(), *secKey); err != nil {
 		return err
 	}
 	if *add {
@@ -250,15 +328,18 @@ func Review(argv0 string, args ...string) error {
 	if err := os.MkdirAll(treeDirB, 0755); err != nil {
 		return err
 	}
-	var treeHash string
-	if fs.NArg() == 1 {
-		treeHash = fs.Arg(0)
-	}
-	c, err := hashchain.ReadFile(def.HashchainFile)
+	c, err := readHashChain()
 	if err != nil {
 		return err
 	}
 	defer c.Close()
+	var treeHash string
+	if !*add && fs.NArg() == 1 {
+		treeHash, err = c.ResolveTreeHash(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+	}
 	// add interrupt handler
 	interrupt.AddInterruptHandler(func() {
 		c.Close()
@@ -269,7 +350,7 @@ func Review(argv0 string, args ...string) error {
 		if *add {
 			err = addDetached(c, fs.Arg(0), fs.Arg(1), fs.Arg(2))
 		} else {
-			err = review(c, *secKey, treeHash, *detached, *useGit)
+			err = review(c, *secKey, *agent, *request, treeHash, *detached, *useGit)
 		}
 		if err != nil {
 			interrupt.ShutdownChannel <- err
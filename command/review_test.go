@@ -0,0 +1,22 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReviewRequestOptions(t *testing.T) {
+	tests := [][]string{
+		{"-r", testPubkey, "-s", "seckey.bin"},
+		{"-r", testPubkey, "-agent"},
+		{"-r", testPubkey, "-agent", "-d"},
+		{"-r", testPubkey, "-d"},
+		{"-r", testPubkey, "-a"},
+	}
+	for _, args := range tests {
+		err := Review("review", args...)
+		if err == nil || !strings.Contains(err.Error(), "option -r excludes") {
+			t.Errorf("Review(%s) should fail (has %v)", strings.Join(args, " "), err)
+		}
+	}
+}
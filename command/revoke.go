@@ -0,0 +1,69 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/homedir"
+	"github.com/frankbraun/codechain/util/log"
+	"github.com/frankbraun/codechain/util/seckey"
+)
+
+// Revoke implements the 'revoke' command.
+func Revoke(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-s seckey.bin | -agent] treehash reason\n", argv0)
+		fmt.Fprintf(os.Stderr, "Revoke published release with treehash for given reason.\n")
+		fs.PrintDefaults()
+	}
+	secKey := fs.String("s", "", "Secret key file")
+	agent := fs.Bool("agent", false, "Use Ed25519 key from ssh-agent")
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if *agent {
+		if *secKey != "" {
+			return fmt.Errorf("%s: options -s and -agent exclude each other", argv0)
+		}
+	} else if err := seckey.Check(homedir.Codechain(), *secKey); err != nil {
+		return err
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	h, err := hex.Decode(fs.Arg(0), 32)
+	if err != nil {
+		return fmt.Errorf("cannot decode treehash: %s", err)
+	}
+	var treeHash [32]byte
+	copy(treeHash[:], h)
+	reason := fs.Arg(1)
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	s, err := seckey.LoadSigner(c, homedir.Codechain(), *secKey, *agent)
+	if err != nil {
+		return err
+	}
+	defer s.Zero()
+	line, err := c.Revoke(treeHash, s, []byte(reason))
+	if err != nil {
+		return err
+	}
+	fmt.Println(line)
+	return nil
+}
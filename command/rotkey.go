@@ -0,0 +1,100 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/log"
+	"github.com/frankbraun/codechain/util/seckey"
+)
+
+// RotKey implements the 'rotkey' command.
+func RotKey(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-d] -s new-seckey.bin | -agent old-pubkey\n", argv0)
+		fmt.Fprintf(os.Stderr, "       %s old-pubkey new-pubkey signature\n", argv0)
+		fmt.Fprintf(os.Stderr, "Replace existing signer in hashchain with new key (keeps weight and comment).\n")
+		fs.PrintDefaults()
+	}
+	detached := fs.Bool("d", false, "Only show old-pubkey, new-pubkey, and signature for later rotation")
+	secKey := fs.String("s", "", "Secret key file of new key")
+	agent := fs.Bool("agent", false, "Use new Ed25519 key from ssh-agent")
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if *secKey != "" && *agent {
+		return fmt.Errorf("%s: options -s and -agent exclude each other", argv0)
+	}
+	if *secKey != "" || *agent {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+	} else {
+		if *detached || fs.NArg() != 3 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	old, err := base64.Decode(fs.Arg(0), 32)
+	if err != nil {
+		return fmt.Errorf("cannot decode old-pubkey: %s", err)
+	}
+	var (
+		oldPubKey [32]byte
+		newPubKey [32]byte
+		signature [64]byte
+	)
+	copy(oldPubKey[:], old)
+	if *secKey != "" || *agent {
+		s, _, err := seckey.ReadSigner(*secKey, *agent)
+		if err != nil {
+			return err
+		}
+		defer s.Zero()
+		newPubKey = s.PublicKey()
+		signature, err = hashchain.RotateKeySignature(oldPubKey, s)
+		if err != nil {
+			return err
+		}
+		if *detached {
+			fmt.Printf("%s %s %s\n", fs.Arg(0), base64.Encode(newPubKey[:]),
+				base64.Encode(signature[:]))
+			return nil
+		}
+	} else {
+		pub, err := base64.Decode(fs.Arg(1), 32)
+		if err != nil {
+			return fmt.Errorf("cannot decode new-pubkey: %s", err)
+		}
+		copy(newPubKey[:], pub)
+		sig, err := base64.Decode(fs.Arg(2), 64)
+		if err != nil {
+			return fmt.Errorf("cannot decode signature: %s", err)
+		}
+		copy(signature[:], sig)
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	line, err := c.RotateKey(oldPubKey, newPubKey, signature)
+	if err != nil {
+		return err
+	}
+	fmt.Println(line)
+	return nil
+}
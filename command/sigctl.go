@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/frankbraun/codechain/hashchain"
 	"github.com/frankbraun/codechain/secpkg"
-	"github.com/frankbraun/codechain/util/def"
 	"github.com/frankbraun/codechain/util/log"
 )
 
@@ -40,7 +38,7 @@ func SigCtl(argv0 string, args ...string) error {
 	if err := secpkg.UpToDate("codechain"); err != nil {
 		return err
 	}
-	c, err := hashchain.ReadFile(def.HashchainFile)
+	c, err := readHashChain()
 	if err != nil {
 		return err
 	}
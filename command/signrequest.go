@@ -0,0 +1,92 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/frankbraun/codechain/signreq"
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/homedir"
+	"github.com/frankbraun/codechain/util/log"
+	"github.com/frankbraun/codechain/util/seckey"
+	"github.com/frankbraun/codechain/util/signer"
+	"github.com/frankbraun/codechain/util/terminal"
+)
+
+// signRequestSigner returns the signer for signing request r. If neither
+// secKeyFile nor agent is given, the keyfile for the requested pubkey is
+// loaded from the secrets subdirectory of codechain (review requests) or
+// ssotpub (signed head requests).
+func signRequestSigner(r *signreq.Request, secKeyFile string, agent bool) (signer.Signer, error) {
+	pubKey := base64.Encode(r.PubKey[:])
+	if agent {
+		s, _, err := seckey.Agent(map[string]bool{pubKey: true})
+		return s, err
+	}
+	if secKeyFile == "" {
+		homeDir := homedir.Codechain()
+		if r.Type == signreq.TypeSignHead {
+			homeDir = homedir.SSOTPub()
+		}
+		secKeyFile = filepath.Join(homeDir, def.SecretsSubDir, pubKey)
+	}
+	s, _, err := seckey.ReadSigner(secKeyFile, false)
+	return s, err
+}
+
+// SignRequest implements the 'sign-request' command.
+func SignRequest(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-s seckey.bin | -agent] request\n", argv0)
+		fmt.Fprintf(os.Stderr, "Sign request created by 'codechain review -r' or 'ssotpub signhead -request' (offline).\n")
+		fs.PrintDefaults()
+	}
+	agent := fs.Bool("agent", false, "Use Ed25519 key from ssh-agent")
+	secKey := fs.String("s", "", "Secret key file")
+	verbose := fs.Bool("v", false, "Be verbose")
+	yesPrompt := fs.Bool("y", false, "Automatic yes to prompts, use with care!")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if *agent && *secKey != "" {
+		return fmt.Errorf("%s: options -s and -agent exclude each other", argv0)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	r, err := signreq.Unmarshal(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Print(r.Summary())
+	if !*yesPrompt {
+		if err := terminal.Confirm("sign request?"); err != nil {
+			return err
+		}
+	}
+	s, err := signRequestSigner(r, *secKey, *agent)
+	if err != nil {
+		return err
+	}
+	defer s.Zero()
+	resp, err := r.Sign(s)
+	if err != nil {
+		return err
+	}
+	switch r.Type {
+	case signreq.TypeReview:
+		fmt.Println("import the following with 'codechain review -a':")
+	case signreq.TypeSignHead:
+		fmt.Println("import the following with 'ssotpub signhead -import':")
+	}
+	fmt.Println(resp)
+	return nil
+}
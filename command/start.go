@@ -17,11 +17,12 @@ import (
 func Start(argv0 string, args ...string) error {
 	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s -s seckey.bin\n", argv0)
+		fmt.Fprintf(os.Stderr, "Usage: %s -s seckey.bin | -agent\n", argv0)
 		fmt.Fprintf(os.Stderr, "Initialized new .codechain/hashchain in current directory.\n")
 		fs.PrintDefaults()
 	}
 	secKey := fs.String("s", "", "Secret key file")
+	agent := fs.Bool("agent", false, "Use Ed25519 key from ssh-agent")
 	verbose := fs.Bool("v", false, "Be verbose")
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -29,8 +30,11 @@ func Start(argv0 string, args ...string) error {
 	if *verbose {
 		log.Std = log.NewStd(os.Stdout)
 	}
-	if *secKey == "" {
-		return fmt.Errorf("%s: option -s is mandatory", argv0)
+	if *secKey == "" && !*agent {
+		return fmt.Errorf("%s: option -s or -agent is mandatory", argv0)
+	}
+	if *secKey != "" && *agent {
+		return fmt.Errorf("%s: options -s and -agent exclude each other", argv0)
 	}
 	if fs.NArg() != 0 {
 		fs.Usage()
@@ -49,11 +53,12 @@ func Start(argv0 string, args ...string) error {
 	if exists {
 		return fmt.Errorf("%s: file '%s' exists already", argv0, def.HashchainFile)
 	}
-	sec, _, comment, err := seckey.Read(*secKey)
+	s, comment, err := seckey.ReadSigner(*secKey, *agent)
 	if err != nil {
 		return err
 	}
-	c, entry, err := hashchain.Start(def.HashchainFile, *sec, comment)
+	defer s.Zero()
+	c, entry, err := hashchain.Start(def.HashchainFile, s, comment)
 	if err != nil {
 		return err
 	}
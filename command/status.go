@@ -1,6 +1,7 @@
 package command
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -13,8 +14,12 @@ import (
 	"github.com/frankbraun/codechain/util/def"
 	"github.com/frankbraun/codechain/util/hex"
 	"github.com/frankbraun/codechain/util/log"
+	"github.com/frankbraun/codechain/util/time"
 )
 
+// warn about keys which expire within 30 days
+const expiryWarning = 30 * 24 * 60 * 60
+
 func showSigner(c *hashchain.HashChain) {
 	fmt.Printf("signers (%d-of-%d required):\n", c.M(), c.N())
 	var signer []string
@@ -25,10 +30,22 @@ func showSigner(c *hashchain.HashChain) {
 	for _, s := range signer {
 		fmt.Printf("%d %s %s\n", c.SignerWeight(s), s, c.SignerComment(s))
 	}
+	now := time.Now()
+	for _, s := range signer {
+		expiry := c.SignerExpiry(s)
+		if expiry == 0 {
+			continue
+		}
+		if now >= expiry {
+			fmt.Printf("WARNING: key %s expired on %s\n", s, time.Format(expiry))
+		} else if expiry-now <= expiryWarning {
+			fmt.Printf("WARNING: key %s expires on %s\n", s, time.Format(expiry))
+		}
+	}
 }
 
 func showSignedReleases(c *hashchain.HashChain) {
-	_, idx := c.LastSignedTreeHash()
+	idx := c.LastSignedIndex()
 	if idx == 0 {
 		fmt.Println("no signed releases yet")
 		return
@@ -38,6 +55,37 @@ func showSignedReleases(c *hashchain.HashChain) {
 	fmt.Println("signed releases:")
 	for i := 1; i <= idx; i++ {
 		fmt.Printf("%s %s\n", treeHashes[i], treeComments[i])
+		if reason, ok := c.Revoked(treeHashes[i]); ok {
+			fmt.Printf("REVOKED: %s\n", reason)
+		}
+		for _, n := range c.Objections(treeHashes[i]) {
+			fmt.Printf("OBJECTION: %s (%s %s)\n", n.Text, n.PubKey, c.SignerComment(n.PubKey))
+		}
+	}
+}
+
+func showObjections(c *hashchain.HashChain) {
+	objections := c.UnresolvedObjections()
+	if len(objections) == 0 {
+		fmt.Println("no unresolved objections")
+		return
+	}
+	fmt.Println("unresolved objections:")
+	for i := range objections {
+		fmt.Println(formatNote(c, &objections[i]))
+	}
+}
+
+func showTags(c *hashchain.HashChain) {
+	tags := c.Tags()
+	if len(tags) == 0 {
+		fmt.Println("no tags yet")
+		return
+	}
+	fmt.Println("tags:")
+	for _, name := range tags {
+		treeHash, _ := c.TagTreeHash(name)
+		fmt.Printf("%s %s\n", name, treeHash)
 	}
 }
 
@@ -71,15 +119,44 @@ func showTreeStatus(c *hashchain.HashChain) error {
 	return nil
 }
 
+type jsonStatus struct {
+	*hashchain.Status
+	TreeHash  string `json:"treehash"`
+	TreeDirty bool   `json:"tree_dirty"`
+}
+
+func statusJSON(c *hashchain.HashChain) error {
+	s, err := c.Status()
+	if err != nil {
+		return err
+	}
+	treeHash, err := tree.Hash(".", def.ExcludePaths)
+	if err != nil {
+		return err
+	}
+	js := jsonStatus{
+		Status:    s,
+		TreeHash:  hex.Encode(treeHash[:]),
+		TreeDirty: !util.ContainsString(c.TreeHashes(), hex.Encode(treeHash[:])),
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(js)
+}
+
 func status(c *hashchain.HashChain) error {
 	showSignedReleases(c)
 	fmt.Println()
+	showTags(c)
+	fmt.Println()
 	showSigner(c)
 	fmt.Println()
 	if err := showUnsigned(c); err != nil {
 		return err
 	}
 	fmt.Println()
+	showObjections(c)
+	fmt.Println()
 	fmt.Println("head:")
 	fmt.Printf("%x\n", c.Head())
 	fmt.Println()
@@ -90,11 +167,13 @@ func status(c *hashchain.HashChain) error {
 func Status(argv0 string, args ...string) error {
 	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s\n", argv0)
+		fmt.Fprintf(os.Stderr, "Usage: %s [-block-nacks] [-deep-verify] [-json | -p]\n", argv0)
 		fmt.Fprintf(os.Stderr, "Show status of hashchain and tree.\n")
 		fs.PrintDefaults()
 	}
+	blockNacks := fs.Bool("block-nacks", false, "Releases with unresolved objections do not count as signed")
 	deepVerify := fs.Bool("deep-verify", false, "Verify all patch files match hash chain entries")
+	jsonOutput := fs.Bool("json", false, "Print status in JSON format to stdout")
 	print := fs.Bool("p", false, "Print hashchain to stdout")
 	verbose := fs.Bool("v", false, "Be verbose")
 	if err := fs.Parse(args); err != nil {
@@ -110,11 +189,12 @@ func Status(argv0 string, args ...string) error {
 	if err := secpkg.UpToDate("codechain"); err != nil {
 		return err
 	}
-	c, err := hashchain.ReadFile(def.HashchainFile)
+	c, err := readHashChain()
 	if err != nil {
 		return err
 	}
 	defer c.Close()
+	c.SetObjectionPolicy(*blockNacks)
 	if *deepVerify {
 		err := c.DeepVerify(treeDirA, def.PatchDir, def.ExcludePaths)
 		if err != nil {
@@ -125,5 +205,8 @@ func Status(argv0 string, args ...string) error {
 		c.Print()
 		return nil
 	}
+	if *jsonOutput {
+		return statusJSON(c)
+	}
 	return status(c)
 }
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/log"
+)
+
+// Tag implements the 'tag' command.
+func Tag(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s name [treehash]\n", argv0)
+		fmt.Fprintf(os.Stderr, "Tag published treehash (default: last published) with name.\n")
+		fs.PrintDefaults()
+	}
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() != 1 && fs.NArg() != 2 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	treeHashStr := c.LastTreeHash()
+	if fs.NArg() == 2 {
+		treeHashStr = fs.Arg(1)
+	}
+	h, err := hex.Decode(treeHashStr, 32)
+	if err != nil {
+		return fmt.Errorf("cannot decode treehash: %s", err)
+	}
+	var treeHash [32]byte
+	copy(treeHash[:], h)
+	line, err := c.Tag(fs.Arg(0), treeHash)
+	if err != nil {
+		return err
+	}
+	fmt.Println(line)
+	return nil
+}
@@ -8,21 +8,37 @@ import (
 	"github.com/frankbraun/codechain/secpkg"
 	"github.com/frankbraun/codechain/tree"
 	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/hex"
 )
 
+// resolveTreeHash resolves s to a tree hash. If s is not a tree hash, it is
+// looked up as a tag in the hash chain.
+func resolveTreeHash(s string) (string, error) {
+	if _, err := hex.Decode(s, 32); err == nil {
+		return s, nil
+	}
+	c, err := readHashChain()
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	return c.ResolveTreeHash(s)
+}
+
 // TreeHash implements the 'treehash' command.
 func TreeHash(argv0 string, args ...string) error {
 	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s\n", argv0)
+		fmt.Fprintf(os.Stderr, "Usage: %s [treehash|tag]\n", argv0)
 		fmt.Fprintf(os.Stderr, "Show tree hash or tree list of current directory.\n")
+		fmt.Fprintf(os.Stderr, "If a treehash or tag is given, compare it with tree hash of current directory.\n")
 		fs.PrintDefaults()
 	}
 	list := fs.Bool("l", false, "Print tree list instead of hash")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
-	if fs.NArg() != 0 {
+	if fs.NArg() > 1 || (*list && fs.NArg() != 0) {
 		fs.Usage()
 		return flag.ErrHelp
 	}
@@ -40,6 +56,17 @@ func TreeHash(argv0 string, args ...string) error {
 		if err != nil {
 			return err
 		}
+		if fs.NArg() == 1 {
+			treeHash, err := resolveTreeHash(fs.Arg(0))
+			if err != nil {
+				return err
+			}
+			if treeHash != hex.Encode(hash[:]) {
+				return fmt.Errorf("tree hash %x doesn't match %s", hash[:], fs.Arg(0))
+			}
+			fmt.Printf("tree matches %s\n", fs.Arg(0))
+			return nil
+		}
 		fmt.Printf("%x\n", hash[:])
 	}
 	return nil
@@ -0,0 +1,136 @@
+package command
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/tree"
+	"github.com/frankbraun/codechain/util"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/file"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/lockfile"
+	"github.com/frankbraun/codechain/util/log"
+)
+
+// Exit codes of the 'verify' command (1 is used for all other errors and 2
+// for usage errors).
+const (
+	ExitBrokenChain     = 3 // hash chain verification failed
+	ExitBrokenPatches   = 4 // patch files do not match the hash chain
+	ExitUnsignedTree    = 5 // tree is in hash chain, but not the last signed tree
+	ExitDirtyTree       = 6 // tree is not in hash chain
+	ExitTooFewReviewers = 7 // last signed tree has not enough reviewers
+)
+
+// reviewers returns the number of distinct reviewers of treeHash in hash
+// chain c, not counting the publisher.
+func reviewers(c *hashchain.HashChain, treeHash string) int {
+	if treeHash == tree.EmptyHash {
+		return 0
+	}
+	publisher, _ := c.SignerInfo(treeHash)
+	n := 0
+	for _, pubKey := range c.Reviewers(treeHash) {
+		if pubKey != publisher {
+			n++
+		}
+	}
+	return n
+}
+
+func verify(minReviewers int, blockNacks bool) error {
+	// verify hash chain (without checkpoint)
+	exists, err := file.Exists(def.HashchainFile)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("file '%s' doesn't exist", def.HashchainFile)
+	}
+	// lock and read the hash chain first, so that only verification errors
+	// (and not locking or I/O errors) are reported as a broken chain
+	lock, err := lockfile.Create(def.HashchainFile)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	buf, err := ioutil.ReadFile(def.HashchainFile)
+	if err != nil {
+		return err
+	}
+	c, err := hashchain.Read(bytes.NewReader(buf))
+	if err != nil {
+		return &ExitError{Code: ExitBrokenChain, Err: err}
+	}
+	c.SetObjectionPolicy(blockNacks)
+
+	// verify patches in temporary directory
+	tmpdir, err := ioutil.TempDir("", "codechain_verify")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpdir)
+	if err := c.DeepVerify(tmpdir, def.PatchDir, def.ExcludePaths); err != nil {
+		return &ExitError{Code: ExitBrokenPatches, Err: err}
+	}
+
+	// compare tree with last signed tree hash
+	treeHash, err := tree.Hash(".", def.ExcludePaths)
+	if err != nil {
+		return err
+	}
+	h := hex.Encode(treeHash[:])
+	signed, _ := c.LastSignedTreeHash()
+	if h != signed {
+		if util.ContainsString(c.TreeHashes(), h) {
+			return exitErrorf(ExitUnsignedTree,
+				"tree %s is not the last signed tree %s", h, signed)
+		}
+		return exitErrorf(ExitDirtyTree, "tree %s is dirty", h)
+	}
+
+	// check reviewers
+	if n := reviewers(c, signed); n < minReviewers {
+		return exitErrorf(ExitTooFewReviewers,
+			"tree %s has %d reviewers (%d required)", signed, n, minReviewers)
+	}
+	fmt.Printf("tree matches last signed tree %s\n", signed)
+	return nil
+}
+
+// Verify implements the 'verify' command.
+func Verify(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-block-nacks] [-reviewers n]\n", argv0)
+		fmt.Fprintf(os.Stderr, "Verify hash chain, patches, and that tree is the last signed tree.\n")
+		fmt.Fprintf(os.Stderr, "Exit codes: %d broken chain, %d broken patches, %d unsigned tree,\n",
+			ExitBrokenChain, ExitBrokenPatches, ExitUnsignedTree)
+		fmt.Fprintf(os.Stderr, "%d dirty tree, %d too few reviewers.\n",
+			ExitDirtyTree, ExitTooFewReviewers)
+		fs.PrintDefaults()
+	}
+	blockNacks := fs.Bool("block-nacks", false, "Releases with unresolved objections do not count as signed")
+	minReviewers := fs.Int("reviewers", 0, "Minimum number of reviewers (not counting publisher)")
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := secpkg.UpToDate("codechain"); err != nil {
+		return err
+	}
+	return verify(*minReviewers, *blockNacks)
+}
@@ -0,0 +1,163 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/file"
+	"github.com/frankbraun/codechain/util/lockfile"
+)
+
+func TestVerify(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "command_test")
+	if err != nil {
+		t.Fatalf("TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	hashchainFile := filepath.Join("..", "hashchain", "testdata", "hashchain_b")
+	hashchainFile, err = filepath.Abs(hashchainFile)
+	if err != nil {
+		t.Fatalf("filepath.Abs() failed: %v", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd() failed: %v", err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(tmpdir); err != nil {
+		t.Fatalf("os.Chdir() failed: %v", err)
+	}
+	if err := os.MkdirAll(def.PatchDir, 0755); err != nil {
+		t.Fatalf("os.MkdirAll() failed: %v", err)
+	}
+
+	// patches are missing
+	if err := file.Copy(hashchainFile, def.HashchainFile); err != nil {
+		t.Fatalf("file.Copy() failed: %v", err)
+	}
+	err = verify(0, false)
+	if exitErr, ok := err.(*ExitError); !ok || exitErr.Code != ExitBrokenPatches {
+		t.Errorf("verify() should fail with ExitBrokenPatches (has %v)", err)
+	}
+
+	// hash chain is broken
+	buf, err := ioutil.ReadFile(def.HashchainFile)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() failed: %v", err)
+	}
+	broken := strings.Replace(string(buf), "initial release", "initial releasE", 1)
+	if err := ioutil.WriteFile(def.HashchainFile, []byte(broken), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile() failed: %v", err)
+	}
+	err = verify(0, false)
+	if exitErr, ok := err.(*ExitError); !ok || exitErr.Code != ExitBrokenChain {
+		t.Errorf("verify() should fail with ExitBrokenChain (has %v)", err)
+	}
+
+	// hash chain is locked (not a broken chain)
+	lock, err := lockfile.Create(def.HashchainFile)
+	if err != nil {
+		t.Fatalf("lockfile.Create() failed: %v", err)
+	}
+	defer lock.Release()
+	err = verify(0, false)
+	if _, ok := err.(*ExitError); ok || err == nil {
+		t.Errorf("verify() should fail with generic error (has %v)", err)
+	}
+}
+
+func TestVerifyTree(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "command_test")
+	if err != nil {
+		t.Fatalf("TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd() failed: %v", err)
+	}
+	defer os.Chdir(wd)
+	projectDir := filepath.Join(tmpdir, "project")
+	if err := os.MkdirAll(filepath.Join(projectDir, def.CodechainDir), 0755); err != nil {
+		t.Fatalf("os.MkdirAll() failed: %v", err)
+	}
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatalf("os.Chdir() failed: %v", err)
+	}
+	writeTree := func(content string) {
+		if err := ioutil.WriteFile("a.txt", []byte(content), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile() failed: %v", err)
+		}
+	}
+	checkExit := func(code, minReviewers int) {
+		err := verify(minReviewers, false)
+		if code == 0 {
+			if err != nil {
+				t.Errorf("verify() failed: %v", err)
+			}
+			return
+		}
+		if exitErr, ok := err.(*ExitError); !ok || exitErr.Code != code {
+			t.Errorf("verify() should fail with exit code %d (has %v)", code, err)
+		}
+	}
+
+	// publish and sign release 1, publish release 2 without signing it
+	sA := testSigner(t)
+	c, _, err := hashchain.Start(def.HashchainFile, sA, nil)
+	if err != nil {
+		t.Fatalf("hashchain.Start() failed: %v", err)
+	}
+	testPublish(t, c, sA, tmpdir, 1)
+	if _, err := c.Signature(c.Head(), sA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	testPublish(t, c, sA, tmpdir, 2)
+	if err := c.Close(); err != nil {
+		t.Fatalf("c.Close() failed: %v", err)
+	}
+
+	// tree is last signed tree
+	writeTree("1\n")
+	checkExit(0, 0)
+	// last signed tree has only been signed by the publisher
+	checkExit(ExitTooFewReviewers, 1)
+	// tree is in hash chain, but not signed
+	writeTree("2\n")
+	checkExit(ExitUnsignedTree, 0)
+	// tree is not in hash chain
+	writeTree("3\n")
+	checkExit(ExitDirtyTree, 0)
+
+	// second signer signs release 2
+	c, err = hashchain.ReadFile(def.HashchainFile)
+	if err != nil {
+		t.Fatalf("hashchain.ReadFile() failed: %v", err)
+	}
+	sB := testSigner(t)
+	pubB := sB.PublicKey()
+	sig, err := sB.Sign(pubB[:])
+	if err != nil {
+		t.Fatalf("sB.Sign() failed: %v", err)
+	}
+	if _, err := c.AddKey(1, pubB, sig, nil); err != nil {
+		t.Fatalf("c.AddKey() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), sA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), sB, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("c.Close() failed: %v", err)
+	}
+	writeTree("2\n")
+	checkExit(0, 1)
+	checkExit(ExitTooFewReviewers, 2)
+}
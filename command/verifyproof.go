@@ -0,0 +1,56 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/log"
+)
+
+// VerifyProof implements the 'verify-proof' command.
+func VerifyProof(argv0 string, args ...string) error {
+	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-t treehash] head proof.txt\n", argv0)
+		fmt.Fprintf(os.Stderr, "Verify proof.txt against trusted head (does not need a hash chain).\n")
+		fs.PrintDefaults()
+	}
+	treeHash := fs.String("t", "", "Check that the proof is for the given treehash")
+	verbose := fs.Bool("v", false, "Be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verbose {
+		log.Std = log.NewStd(os.Stdout)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	h, err := hex.Decode(fs.Arg(0), 32)
+	if err != nil {
+		return fmt.Errorf("cannot decode head: %s", err)
+	}
+	var head [32]byte
+	copy(head[:], h)
+	fp, err := os.Open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	p, err := hashchain.ReadProof(fp)
+	if err != nil {
+		return err
+	}
+	if *treeHash != "" && *treeHash != p.TreeHash() {
+		return fmt.Errorf("proof is for treehash %s, not %s", p.TreeHash(), *treeHash)
+	}
+	if err := p.Verify(head); err != nil {
+		return err
+	}
+	fmt.Printf("treehash %s is signed as of head %x\n", p.TreeHash(), head)
+	return nil
+}
@@ -32,10 +32,8 @@ func (c *HashChain) AddKey(weight int, pubKey [32]byte, signature [64]byte, comm
 		linkType:   linktype.AddKey,
 		typeFields: typeFields,
 	}
-	c.chain = append(c.chain, l)
-
 	// verify
-	if err := c.verify(); err != nil {
+	if err := c.appendLink(l); err != nil {
 		return "", err
 	}
 
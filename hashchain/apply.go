@@ -1,22 +1,62 @@
 package hashchain
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/frankbraun/codechain/sync"
 	"github.com/frankbraun/codechain/util/def"
 )
 
 // Apply to current working directory and check head if not nil.
+//
+// Apply syncs to the last signed tree hash which has not been revoked (or
+// objected to, see SetObjectionPolicy) and refuses to sync to revoked tree
+// hashes. If the current working directory is at a revoked tree hash, it is
+// rolled back to the last signed tree hash.
 func (c *HashChain) Apply(head *[32]byte, patchDir string) error {
-	targetHash, _ := c.LastSignedTreeHash()
+	targetHash, idx := c.LastSignedTreeHash()
 	treeHashes := c.TreeHashes()
 	if head != nil {
 		if err := c.CheckHead(*head); err != nil {
 			return err
 		}
 	}
-	err := sync.Dir(".", targetHash, patchDir, treeHashes, def.ExcludePaths, false)
+	revoked := c.RevokedTreeHashes()
+	for i := c.LastSignedIndex(); i > idx; i-- {
+		if reason, ok := revoked[treeHashes[i]]; ok {
+			fmt.Fprintf(os.Stderr, "WARNING: skipping revoked release %s: %s\n",
+				treeHashes[i], reason)
+		} else {
+			fmt.Fprintf(os.Stderr, "WARNING: skipping release %s with objections\n",
+				treeHashes[i])
+		}
+	}
+	err := sync.Dir(".", targetHash, patchDir, "", treeHashes, revoked, def.ExcludePaths, false)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// ApplyTreeHash applies all patches up to the given treeHash to the current
+// working directory. The treeHash must be signed and must not be revoked (or
+// objected to, see SetObjectionPolicy).
+func (c *HashChain) ApplyTreeHash(treeHash, patchDir string) error {
+	treeHashes := c.TreeHashes()
+	idx := -1
+	for i, h := range treeHashes {
+		if h == treeHash {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 || idx > c.LastSignedIndex() {
+		return fmt.Errorf("hashchain: tree hash not signed: %s", treeHash)
+	}
+	if c.blockObjected && len(c.Objections(treeHash)) > 0 {
+		return fmt.Errorf("hashchain: tree hash has unresolved objections: %s", treeHash)
+	}
+	revoked := c.RevokedTreeHashes()
+	return sync.Dir(".", treeHash, patchDir, "", treeHashes, revoked, def.ExcludePaths, false)
+}
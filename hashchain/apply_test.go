@@ -0,0 +1,118 @@
+package hashchain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/frankbraun/codechain/patchfile"
+	"github.com/frankbraun/codechain/tree"
+	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/hex"
+)
+
+func TestApplyRevoked(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "hashchain_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// create releases and patches between them
+	contents := []string{"", "a\n", "a\nb\n"}
+	patchDir := filepath.Join(tmpdir, "patches")
+	if err := os.Mkdir(patchDir, 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	var treeHashes [][32]byte
+	for i, content := range contents {
+		dir := filepath.Join(tmpdir, "release", strconv.Itoa(i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("os.MkdirAll() failed: %v", err)
+		}
+		if content != "" {
+			fn := filepath.Join(dir, "a.txt")
+			if err := ioutil.WriteFile(fn, []byte(content), 0644); err != nil {
+				t.Fatalf("ioutil.WriteFile() failed: %v", err)
+			}
+		}
+		h, err := tree.Hash(dir, nil)
+		if err != nil {
+			t.Fatalf("tree.Hash() failed: %v", err)
+		}
+		treeHashes = append(treeHashes, *h)
+		if i == 0 {
+			continue
+		}
+		prev := filepath.Join(tmpdir, "release", strconv.Itoa(i-1))
+		f, err := os.Create(filepath.Join(patchDir, hex.Encode(treeHashes[i-1][:])))
+		if err != nil {
+			t.Fatalf("os.Create() failed: %v", err)
+		}
+		if err := patchfile.Diff(patchfile.Version, f, prev, dir, nil); err != nil {
+			t.Fatalf("patchfile.Diff() failed: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("f.Close() failed: %v", err)
+		}
+	}
+
+	// publish and sign releases
+	c, _, err := Start(filepath.Join(tmpdir, "hashchain"), signerA, nil)
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer c.Close()
+	for _, h := range treeHashes[1:] {
+		if _, err := c.Source(h, signerA, nil); err != nil {
+			t.Fatalf("c.Source() failed: %v", err)
+		}
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+
+	// apply last release in working directory with an excluded file
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd() failed: %v", err)
+	}
+	defer os.Chdir(wd)
+	treeDir := filepath.Join(tmpdir, "tree")
+	excluded := filepath.Join(treeDir, def.DefaultCodechainDir, "keep")
+	if err := os.MkdirAll(filepath.Dir(excluded), 0755); err != nil {
+		t.Fatalf("os.MkdirAll() failed: %v", err)
+	}
+	if err := ioutil.WriteFile(excluded, []byte("keep"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile() failed: %v", err)
+	}
+	if err := os.Chdir(treeDir); err != nil {
+		t.Fatalf("os.Chdir() failed: %v", err)
+	}
+	if err := c.Apply(nil, patchDir); err != nil {
+		t.Fatalf("c.Apply() failed: %v", err)
+	}
+
+	// revoke last release, apply must roll back to the previous one
+	if _, err := c.Revoke(treeHashes[2], signerA, []byte("broken")); err != nil {
+		t.Fatalf("c.Revoke() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	if err := c.Apply(nil, patchDir); err != nil {
+		t.Fatalf("c.Apply() failed: %v", err)
+	}
+	h, err := tree.Hash(".", def.ExcludePaths)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+	if *h != treeHashes[1] {
+		t.Error("c.Apply() did not roll back revoked release")
+	}
+	if _, err := os.Stat(excluded); err != nil {
+		t.Errorf("c.Apply() removed excluded file: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package hashchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/log"
+)
+
+// checkpoint denotes the last line of an already verified prefix of a hash
+// chain. It is stored in a checkpoint file as a single line:
+//
+//	line-number hash-of-line
+//
+// A checkpoint is not authenticated. Everybody who can write to the
+// checkpoint file can make unverified lines count as verified. Therefore,
+// checkpoint files must be stored outside of directories which are
+// distributed with the hash chain (see CheckpointFile).
+type checkpoint struct {
+	line int
+	hash [32]byte
+}
+
+// CheckpointFile returns the name of the checkpoint file for the hash chain
+// stored in filename. The checkpoint file is located in dir, which should be
+// a private directory of the user (like a subdirectory of the home
+// directory). The name is derived from the absolute path of filename, so
+// every hash chain gets its own checkpoint file.
+func CheckpointFile(dir, filename string) (string, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, hex.Encode(h[:])), nil
+}
+
+// readCheckpoint reads the checkpoint from filename. If the file doesn't
+// exist or cannot be parsed, nil is returned and the checkpoint is ignored.
+func readCheckpoint(filename string) *checkpoint {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.Printf("hashchain: cannot read checkpoint: %s", err)
+		return nil
+	}
+	fields := strings.Fields(string(buf))
+	if len(fields) != 2 {
+		log.Printf("hashchain: cannot parse checkpoint: %s", filename)
+		return nil
+	}
+	line, err := strconv.Atoi(fields[0])
+	if err != nil || line < 0 {
+		log.Printf("hashchain: cannot parse checkpoint line: %s", fields[0])
+		return nil
+	}
+	hash, err := hex.Decode(fields[1], 32)
+	if err != nil {
+		log.Printf("hashchain: cannot parse checkpoint hash: %s", err)
+		return nil
+	}
+	cp := &checkpoint{line: line}
+	copy(cp.hash[:], hash)
+	return cp
+}
+
+// verified returns the number of links in chain which are covered by
+// checkpoint cp. Because every link contains the hash of the previous one,
+// a matching hash at the checkpoint line covers the entire prefix.
+func (cp *checkpoint) verified(chain []*link) int {
+	if cp == nil || cp.line >= len(chain) {
+		return 0
+	}
+	hash := chain[cp.line].Hash()
+	if !bytes.Equal(hash[:], cp.hash[:]) {
+		return 0
+	}
+	return cp.line + 1
+}
+
+// writeCheckpoint writes the head of the (verified) hash chain c as
+// checkpoint to filename, if it differs from old.
+func (c *HashChain) writeCheckpoint(filename string, old *checkpoint) error {
+	cp := checkpoint{
+		line: len(c.chain) - 1,
+		hash: c.Head(),
+	}
+	if old != nil && old.line == cp.line && bytes.Equal(old.hash[:], cp.hash[:]) {
+		return nil // nothing changed
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	data := fmt.Sprintf("%d %x\n", cp.line, cp.hash[:])
+	return ioutil.WriteFile(filename, []byte(data), 0600)
+}
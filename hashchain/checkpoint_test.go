@@ -0,0 +1,119 @@
+package hashchain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "hashchain_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// create chain with a few entries
+	filename := filepath.Join(tmpdir, "hashchain")
+	checkpointFile, err := CheckpointFile(filepath.Join(tmpdir, "checkpoints"), filename)
+	if err != nil {
+		t.Fatalf("CheckpointFile() failed: %v", err)
+	}
+	other, err := CheckpointFile(filepath.Join(tmpdir, "checkpoints"), filename+"2")
+	if err != nil {
+		t.Fatalf("CheckpointFile() failed: %v", err)
+	}
+	if other == checkpointFile {
+		t.Error("CheckpointFile() should differ for different hash chains")
+	}
+	c, _, err := Start(filename, signerA, nil)
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if _, err := c.Source(helloHash, signerA, nil); err != nil {
+		t.Fatalf("c.Source() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	head := c.Head()
+	c.Close()
+
+	// first read writes checkpoint
+	c, err = ReadFileCheckpoint(filename, checkpointFile)
+	if err != nil {
+		t.Fatalf("ReadFileCheckpoint() failed: %v", err)
+	}
+	if c.verified != 0 {
+		t.Errorf("c.verified = %d, want 0", c.verified)
+	}
+	c.Close()
+	cp := readCheckpoint(checkpointFile)
+	if cp == nil {
+		t.Fatal("readCheckpoint() returned nil")
+	}
+	if cp.line != 2 || cp.hash != head {
+		t.Errorf("checkpoint (%d, %x) doesn't match head (2, %x)", cp.line, cp.hash, head)
+	}
+
+	// second read uses checkpoint
+	c, err = ReadFileCheckpoint(filename, checkpointFile)
+	if err != nil {
+		t.Fatalf("ReadFileCheckpoint() failed: %v", err)
+	}
+	if c.verified != 3 {
+		t.Errorf("c.verified = %d, want 3", c.verified)
+	}
+	if c.Head() != head {
+		t.Error("head differs after reading with checkpoint")
+	}
+
+	// failed append leaves chain intact
+	if _, err := c.Source(helloHash, signerA, nil); err == nil {
+		t.Fatal("c.Source() should fail for duplicate tree hash")
+	}
+	if c.Head() != head {
+		t.Error("failed append changed head")
+	}
+	c.Close()
+
+	// checkpoint beyond the chain is ignored
+	if err := ioutil.WriteFile(checkpointFile, []byte("5 "+helloHashHex+"\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile() failed: %v", err)
+	}
+	c, err = ReadFileCheckpoint(filename, checkpointFile)
+	if err != nil {
+		t.Fatalf("ReadFileCheckpoint() failed: %v", err)
+	}
+	if c.verified != 0 {
+		t.Errorf("c.verified = %d, want 0", c.verified)
+	}
+	c.Close()
+
+	// mismatching checkpoint is ignored
+	if err := ioutil.WriteFile(checkpointFile, []byte("1 "+helloHashHex+"\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile() failed: %v", err)
+	}
+	c, err = ReadFileCheckpoint(filename, checkpointFile)
+	if err != nil {
+		t.Fatalf("ReadFileCheckpoint() failed: %v", err)
+	}
+	if c.verified != 0 {
+		t.Errorf("c.verified = %d, want 0", c.verified)
+	}
+	c.Close()
+
+	// garbage checkpoint is ignored
+	if err := ioutil.WriteFile(checkpointFile, []byte("garbage"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile() failed: %v", err)
+	}
+	c, err = ReadFileCheckpoint(filename, checkpointFile)
+	if err != nil {
+		t.Fatalf("ReadFileCheckpoint() failed: %v", err)
+	}
+	if c.verified != 0 {
+		t.Errorf("c.verified = %d, want 0", c.verified)
+	}
+	c.Close()
+}
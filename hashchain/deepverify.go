@@ -25,5 +25,5 @@ func (c *HashChain) DeepVerify(treeDir, patchDir string, excludePaths []string)
 
 	// sync takes care of the rest
 	targetHash := treeHashes[len(treeHashes)-1]
-	return sync.Dir(treeDir, targetHash, patchDir, treeHashes, excludePaths, false)
+	return sync.Dir(treeDir, targetHash, patchDir, "", treeHashes, nil, excludePaths, false)
 }
@@ -17,7 +17,7 @@ Hex encodings have to be lowercase. All public keys are Ed25519 keys and they
 and their signatures are encoded in base64 (URL encoding without padding).
 Comments are arbitrary UTF-8 sequences, but cannot contain newlines.
 
-There are six different types of hash chain entries:
+There are eleven different types of hash chain entries:
 
   cstart
   source
@@ -25,6 +25,11 @@ There are six different types of hash chain entries:
   addkey
   remkey
   sigctl
+  expkey
+  rotkey
+  revoke
+  tag
+  note
 
 A hash chain must start with a cstart entry and that is the only line where
 this type must appear.
@@ -98,6 +103,85 @@ signatures to approve state changes (the threshold).
   hash-of-previous current-time sigctl m
 
 
+Type expkey
+
+An expkey entry marks a signature pubkey for expiry at the given time.
+
+  hash-of-previous current-time expkey pubkey expiry
+
+The expiry is encoded as an ISO 8601 string in UTC (like current-time) and
+must lie after the current-time of the entry. Like the other key changes it
+has to be approved by m signatures. Once approved, the key cannot sign entries
+with a current-time at or after the expiry anymore, so its signatures stop
+counting towards m. Signatures made before the expiry remain valid. Removing or
+re-adding the key clears the expiry.
+
+
+Type rotkey
+
+A rotkey entry marks a signature pubkey for replacement by a new pubkey in a
+single step.
+
+  hash-of-previous current-time rotkey old-pubkey new-pubkey signature
+
+The signature is made by new-pubkey over old-pubkey and new-pubkey. Once
+approved by m signatures, new-pubkey replaces old-pubkey in the list of
+approved signature keys and inherits its weight and comment. Thereby the total
+weight n and the signature threshold m stay unchanged. A possible expiry of
+old-pubkey is not inherited.
+
+
+Type revoke
+
+A revoke entry marks a published source tree state as revoked, because it
+turned out to be malicious or badly broken.
+
+  hash-of-previous current-time revoke tree-hash pubkey signature reason
+
+The signature by pubkey is over the source tree hash and the reason, which
+must not be empty. The tree-hash must have been published with a source entry
+before. Like other state changes a revocation has to be approved by m
+signatures. Signed revocations are never synced to and the last signed tree
+hash skips revoked source tree states.
+
+
+Type tag
+
+A tag entry assigns a name (like a version number) to a published source tree
+state.
+
+  hash-of-previous current-time tag name tree-hash
+
+The name must not contain white spaces, must not look like a tree hash, and
+must be unique in the hash chain. The tree-hash must have been published with
+a source entry before. Like other state changes a tag has to be approved by m
+signatures, only signed tags can be used in place of tree hashes.
+
+
+Type note
+
+A note entry annotates a previous hash chain entry with a text from a signer,
+for example to record why the signer refuses to sign a source entry.
+
+  hash-of-previous current-time note hash-of-chain-entry pubkey signature flag text
+
+The signature by pubkey is over the hash-of-previous, the hash-of-chain-entry,
+the current-time (as 64-bit big-endian integer), the flag, a single white
+space, and the text, which must not be empty. Including the position in the
+hash chain prevents the replay of old notes. The flag is one of:
+
+  info  plain annotation
+  nack  objection against the annotated entry
+  ack   resolves previous objections of pubkey against the annotated entry
+
+Notes do not change the state of the hash chain and do not have to be
+approved. An objection is unresolved until the same signer adds an ack note
+for the same entry. Only unresolved objections of active signers count. By
+default objections are informational, but clients can choose that source
+entries with unresolved objections do not count as signed (codechain apply
+-block-nacks). Secure packages are always installed and updated that way.
+
+
 Example
 
 An example of a hash chain.
@@ -53,3 +53,40 @@ var ErrNothingToMerge = errors.New("hashchain: nothing to merge")
 
 // ErrHeadNotFound is returned if the head could not be found in hash chain.
 var ErrHeadNotFound = errors.New("hashchain: head not found")
+
+// ErrSignerExpired is returned if the key of a signer has expired.
+var ErrSignerExpired = errors.New("hashchain: signer key expired")
+
+// ErrExpiryNotInFuture is returned if the expiry of an expkey entry is not
+// after the entry itself.
+var ErrExpiryNotInFuture = errors.New("hashchain: key expiry must be after entry time")
+
+// ErrWrongSigRotKey is returned when the signature of a rotkey entry doesn't validate.
+var ErrWrongSigRotKey = errors.New("hashchain: rotkey signature doesn't validate")
+
+// ErrWrongSigRevoke is returned when the signature of a revoke entry doesn't validate.
+var ErrWrongSigRevoke = errors.New("hashchain: revoke signature doesn't validate")
+
+// ErrRevokeReasonEmpty is returned when a revoke entry has no reason.
+var ErrRevokeReasonEmpty = errors.New("hashchain: revoke reason must not be empty")
+
+// ErrInvalidTagName is returned when a tag name is empty, contains white
+// spaces, or looks like a tree hash.
+var ErrInvalidTagName = errors.New("hashchain: invalid tag name")
+
+// ErrInvalidProof is returned when a proof cannot be parsed or omits an entry
+// which has to be replayed.
+var ErrInvalidProof = errors.New("hashchain: invalid proof")
+
+// ErrProofHeadMismatch is returned when a proof does not end in the trusted
+// head.
+var ErrProofHeadMismatch = errors.New("hashchain: proof doesn't match head")
+
+// ErrWrongSigNote is returned when the signature of a note entry doesn't validate.
+var ErrWrongSigNote = errors.New("hashchain: note signature doesn't validate")
+
+// ErrNoteTextEmpty is returned when a note entry has no text.
+var ErrNoteTextEmpty = errors.New("hashchain: note text must not be empty")
+
+// ErrInvalidNoteFlag is returned when a note entry has an unknown flag.
+var ErrInvalidNoteFlag = errors.New("hashchain: invalid note flag")
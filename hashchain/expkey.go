@@ -0,0 +1,40 @@
+package hashchain
+
+import (
+	"fmt"
+
+	"github.com/frankbraun/codechain/hashchain/linktype"
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/time"
+)
+
+// ExpireKey adds a pubkey expiry entry to hash chain. After the given expiry
+// (Unix time) the key cannot sign anymore.
+func (c *HashChain) ExpireKey(pubKey [32]byte, expiry int64) (string, error) {
+	// check arguments
+	now := time.Now()
+	if expiry <= now {
+		return "", ErrExpiryNotInFuture
+	}
+
+	// create entry
+	l := &link{
+		previous: c.Head(),
+		datum:    now,
+		linkType: linktype.ExpireKey,
+		typeFields: []string{
+			base64.Encode(pubKey[:]),
+			time.Format(expiry),
+		},
+	}
+	// verify
+	if err := c.appendLink(l); err != nil {
+		return "", err
+	}
+
+	// save
+	if _, err := fmt.Fprintln(c.fp, l.String()); err != nil {
+		return "", err
+	}
+	return l.StringColor(), nil
+}
@@ -0,0 +1,106 @@
+package hashchain
+
+import (
+	"crypto/ed25519"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frankbraun/codechain/hashchain/linktype"
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/time"
+)
+
+func TestExpireKey(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "hashchain_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// start chain and add pubB
+	filename := filepath.Join(tmpdir, "hashchain")
+	c, _, err := Start(filename, signerA, nil)
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer c.Close()
+	sig := ed25519.Sign(secB[:], pubB[:])
+	var signature [64]byte
+	copy(signature[:], sig)
+	if _, err := c.AddKey(1, pubB, signature, nil); err != nil {
+		t.Fatalf("c.AddKey() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+
+	// expiry must be in the future
+	if _, err := c.ExpireKey(pubB, time.Now()); err != ErrExpiryNotInFuture {
+		t.Errorf("c.ExpireKey() should fail with ErrExpiryNotInFuture")
+	}
+
+	// expire pubB
+	expiry := time.Now() + 3600
+	if _, err := c.ExpireKey(pubB, expiry); err != nil {
+		t.Fatalf("c.ExpireKey() failed: %v", err)
+	}
+	pub := base64.Encode(pubB[:])
+	if c.SignerExpiry(pub) != 0 {
+		t.Error("expiry should not be set before it is signed")
+	}
+	infos, err := c.UnsignedInfo("", "", false)
+	if err != nil {
+		t.Fatalf("c.UnsignedInfo() failed: %v", err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("expected one unsigned entry, got %d", len(infos))
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	if c.SignerExpiry(pub) != expiry {
+		t.Errorf("wrong signer expiry: %d != %d", c.SignerExpiry(pub), expiry)
+	}
+
+	// signature of pubB before expiry is valid
+	if _, err := c.Signature(c.Head(), signerB, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+
+	// signature of pubB after expiry is rejected
+	linkHash := c.Head()
+	sig = ed25519.Sign(secB[:], linkHash[:])
+	c.chain = append(c.chain, &link{
+		previous: linkHash,
+		datum:    expiry,
+		linkType: linktype.Signature,
+		typeFields: []string{
+			hex.Encode(linkHash[:]),
+			pub,
+			base64.Encode(sig),
+		},
+	})
+	if err := c.verify(); err != ErrSignerExpired {
+		t.Errorf("c.verify() should fail with ErrSignerExpired: %v", err)
+	}
+	c.chain = c.chain[:len(c.chain)-1]
+	if err := c.verify(); err != nil {
+		t.Fatalf("c.verify() failed: %v", err)
+	}
+
+	// read
+	if err := c.Close(); err != nil {
+		t.Fatalf("c.Close() failed: %v", err)
+	}
+	c2, err := ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	defer c2.Close()
+	if c2.SignerExpiry(pub) != expiry {
+		t.Errorf("wrong signer expiry after read: %d != %d", c2.SignerExpiry(pub), expiry)
+	}
+}
@@ -12,10 +12,13 @@ import (
 
 // HashChain of threshold signatures over a chain of code changes.
 type HashChain struct {
-	lock  lockfile.Lock
-	fp    *os.File
-	chain []*link
-	state *state.State
+	lock     lockfile.Lock
+	fp       *os.File
+	chain    []*link
+	state    *state.State
+	verified int // number of links with already verified signatures
+
+	blockObjected bool // see SetObjectionPolicy
 }
 
 // Close the underlying file pointer of hash chain and release lock.
@@ -71,10 +74,47 @@ func (c *HashChain) LastTreeHash() string {
 	return c.state.LastTreeHash()
 }
 
-// LastSignedTreeHash returns the last signed tree hash and its index.
+// LastSignedTreeHash returns the last signed tree hash which has not been
+// revoked and its index. If objections block sources (see
+// SetObjectionPolicy), tree hashes with unresolved objections are skipped,
+// too.
 // The first signed tree hash is tree.EmptyHash with index 0.
 func (c *HashChain) LastSignedTreeHash() (string, int) {
-	return c.state.LastSignedTreeHash()
+	treeHash, idx := c.state.LastSignedTreeHash()
+	treeHashes := c.state.TreeHashes()
+	var objections map[int][]Note
+	if c.blockObjected {
+		objections = c.objections()
+	}
+	for idx > 0 {
+		_, revoked := c.state.Revoked(treeHash)
+		objected := len(objections[c.state.SourceLine(treeHash)]) > 0
+		if !revoked && !objected {
+			break
+		}
+		idx--
+		treeHash = treeHashes[idx]
+	}
+	return treeHash, idx
+}
+
+// LastSignedIndex returns the index of the last signed tree hash, regardless
+// of whether it has been revoked or not.
+func (c *HashChain) LastSignedIndex() int {
+	_, idx := c.state.LastSignedTreeHash()
+	return idx
+}
+
+// Revoked returns the reason why the given treeHash has been revoked and
+// true, if the revocation has been signed. Otherwise, it returns false.
+func (c *HashChain) Revoked(treeHash string) (string, bool) {
+	return c.state.Revoked(treeHash)
+}
+
+// RevokedTreeHashes returns a map of all signed revocations (tree hash ->
+// reason).
+func (c *HashChain) RevokedTreeHashes() map[string]string {
+	return c.state.RevokedTreeHashes()
 }
 
 // TreeHashes returns a list of all tree hashes in order (starting from
@@ -104,6 +144,12 @@ func (c *HashChain) SignerWeight(pubKey string) int {
 	return c.state.SignerWeight(pubKey)
 }
 
+// SignerExpiry returns the expiry of the signer with given pubKey as Unix
+// time. It returns 0, if the key of the signer does not expire.
+func (c *HashChain) SignerExpiry(pubKey string) int64 {
+	return c.state.SignerExpiry(pubKey)
+}
+
 // SignerInfo returns signer pubKey and comment for patch with given treeHash.
 func (c *HashChain) SignerInfo(treeHash string) (string, string) {
 	link := c.chain[c.state.SourceLine(treeHash)]
@@ -13,6 +13,7 @@ import (
 
 	"github.com/frankbraun/codechain/util/file"
 	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/signer"
 )
 
 const (
@@ -26,6 +27,8 @@ var (
 	secA      [64]byte
 	pubB      [32]byte
 	secB      [64]byte
+	signerA   signer.Signer
+	signerB   signer.Signer
 	helloHash [32]byte
 )
 
@@ -42,6 +45,8 @@ func init() {
 	}
 	copy(secB[:], sec[:])
 	copy(pubB[:], pub[:])
+	signerA = signer.New(secA)
+	signerB = signer.New(secB)
 	hash, err := hex.Decode(helloHashHex, 32)
 	if err != nil {
 		panic(err)
@@ -57,7 +62,7 @@ func TestStartEmpty(t *testing.T) {
 	defer os.RemoveAll(tmpdir)
 
 	filename := filepath.Join(tmpdir, "hashchain")
-	c, l, err := Start(filename, secA, nil)
+	c, l, err := Start(filename, signerA, nil)
 	if err != nil {
 		t.Fatalf("Start() failed: %v", err)
 	}
@@ -84,6 +89,29 @@ func TestStartEmpty(t *testing.T) {
 	}
 }
 
+// brokenSigner creates invalid signatures.
+type brokenSigner struct {
+	signer.Signer
+}
+
+func (s brokenSigner) Sign(msg []byte) ([64]byte, error) {
+	return [64]byte{}, nil
+}
+
+func TestStartWrongSig(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "hashchain_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	filename := filepath.Join(tmpdir, "hashchain")
+	_, _, err = Start(filename, brokenSigner{signerA}, nil)
+	if err != ErrWrongSigCStart {
+		t.Errorf("Start() should fail with ErrWrongSigCStart (has %v)", err)
+	}
+}
+
 func TestStartSourceSign(t *testing.T) {
 	tmpdir, err := ioutil.TempDir("", "hashchain_test")
 	if err != nil {
@@ -93,7 +121,7 @@ func TestStartSourceSign(t *testing.T) {
 
 	// start empty chain
 	filename := filepath.Join(tmpdir, "hashchain")
-	c, l, err := Start(filename, secA, []byte("comment"))
+	c, l, err := Start(filename, signerA, []byte("comment"))
 	if err != nil {
 		t.Fatalf("Start() failed: %v", err)
 	}
@@ -101,14 +129,14 @@ func TestStartSourceSign(t *testing.T) {
 	fmt.Println(l)
 
 	// add hello.go
-	l, err = c.Source(helloHash, secA, []byte("add hello.go"))
+	l, err = c.Source(helloHash, signerA, []byte("add hello.go"))
 	if err != nil {
 		t.Fatalf("c.Source() failed: %v", err)
 	}
 	fmt.Println(l)
 
 	// sign hello.go (detached)
-	detachedSig, err := c.Signature(c.Head(), secA, true)
+	detachedSig, err := c.Signature(c.Head(), signerA, true)
 	if err != nil {
 		t.Fatalf("c.Signature() failed: %v", err)
 	}
@@ -131,7 +159,7 @@ func TestStartAddKeySignSigCtlSign(t *testing.T) {
 
 	// start empty chain
 	filename := filepath.Join(tmpdir, "hashchain")
-	c, l, err := Start(filename, secA, []byte("this is a comment"))
+	c, l, err := Start(filename, signerA, []byte("this is a comment"))
 	if err != nil {
 		t.Fatalf("Start() failed: %v", err)
 	}
@@ -155,7 +183,7 @@ func TestStartAddKeySignSigCtlSign(t *testing.T) {
 	}
 
 	// sign other signer
-	l, err = c.Signature(c.Head(), secA, false)
+	l, err = c.Signature(c.Head(), signerA, false)
 	if err != nil {
 		t.Fatalf("c.Signature() failed: %v", err)
 	}
@@ -179,7 +207,7 @@ func TestStartAddKeySignSigCtlSign(t *testing.T) {
 	fmt.Println(l)
 
 	// sign sigctl
-	l, err = c.Signature(c.Head(), secB, false)
+	l, err = c.Signature(c.Head(), signerB, false)
 	if err != nil {
 		t.Fatalf("c.Signature() failed: %v", err)
 	}
@@ -195,7 +223,7 @@ func TestStartAddKeySignRemKeySign(t *testing.T) {
 
 	// start empty chain
 	filename := filepath.Join(tmpdir, "hashchain")
-	c, l, err := Start(filename, secA, []byte("this is a comment"))
+	c, l, err := Start(filename, signerA, []byte("this is a comment"))
 	if err != nil {
 		t.Fatalf("Start() failed: %v", err)
 	}
@@ -219,7 +247,7 @@ func TestStartAddKeySignRemKeySign(t *testing.T) {
 	}
 
 	// sign other signer
-	l, err = c.Signature(c.Head(), secA, false)
+	l, err = c.Signature(c.Head(), signerA, false)
 	if err != nil {
 		t.Fatalf("c.Signature() failed: %v", err)
 	}
@@ -239,7 +267,7 @@ func TestStartAddKeySignRemKeySign(t *testing.T) {
 	fmt.Println(l)
 
 	// sign sigctl
-	l, err = c.Signature(c.Head(), secB, false)
+	l, err = c.Signature(c.Head(), signerB, false)
 	if err != nil {
 		t.Fatalf("c.Signature() failed: %v", err)
 	}
@@ -266,7 +294,7 @@ func TestStartAddSameKey(t *testing.T) {
 
 	// start empty chain
 	filename := filepath.Join(tmpdir, "hashchain")
-	c, l, err := Start(filename, secA, []byte("this is a comment"))
+	c, l, err := Start(filename, signerA, []byte("this is a comment"))
 	if err != nil {
 		t.Fatalf("Start() failed: %v", err)
 	}
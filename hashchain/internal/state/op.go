@@ -108,3 +108,73 @@ func newSigCtlOp(m int) *sigCtlOp {
 func (op *sigCtlOp) String() string {
 	return linktype.SignatureControl + " " + strconv.Itoa(op.m)
 }
+
+type expKeyOP struct {
+	signable
+	pubKey string
+	expiry int64
+}
+
+func newExpKeyOP(pubKey string, expiry int64) *expKeyOP {
+	return &expKeyOP{
+		pubKey: pubKey,
+		expiry: expiry,
+	}
+}
+
+func (op *expKeyOP) String() string {
+	return linktype.ExpireKey + " " + op.pubKey + " " + strconv.FormatInt(op.expiry, 10)
+}
+
+type rotKeyOP struct {
+	signable
+	oldPubKey string
+	newPubKey string
+}
+
+func newRotKeyOP(oldPubKey, newPubKey string) *rotKeyOP {
+	return &rotKeyOP{
+		oldPubKey: oldPubKey,
+		newPubKey: newPubKey,
+	}
+}
+
+func (op *rotKeyOP) String() string {
+	return linktype.RotateKey + " " + op.oldPubKey + " " + op.newPubKey
+}
+
+type revokeOP struct {
+	signable
+	treeHash string
+	pubKey   string
+	reason   string
+}
+
+func newRevokeOP(treeHash, pubKey, reason string) *revokeOP {
+	return &revokeOP{
+		treeHash: treeHash,
+		pubKey:   pubKey,
+		reason:   reason,
+	}
+}
+
+func (op *revokeOP) String() string {
+	return linktype.Revoke + " " + op.treeHash + " " + op.pubKey + " " + op.reason
+}
+
+type tagOP struct {
+	signable
+	name     string
+	treeHash string
+}
+
+func newTagOP(name, treeHash string) *tagOP {
+	return &tagOP{
+		name:     name,
+		treeHash: treeHash,
+	}
+}
+
+func (op *tagOP) String() string {
+	return linktype.Tag + " " + op.name + " " + op.treeHash
+}
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/frankbraun/codechain/hashchain/linktype"
 	"github.com/frankbraun/codechain/tree"
 	"github.com/frankbraun/codechain/util"
 	"github.com/frankbraun/codechain/util/base64"
 	"github.com/frankbraun/codechain/util/hex"
 	"github.com/frankbraun/codechain/util/log"
+	"github.com/frankbraun/codechain/util/time"
 )
 
 // State hold the state of a hashchain.
@@ -20,10 +22,14 @@ type State struct {
 	signerWeights      map[string]int    // pubkey (in base64) -> weight
 	signerComments     map[string]string // pubkey (in base64) -> comment
 	signerBarriers     map[string]int    // pubkey (in base64) -> line number up to he signed
+	signerExpiries     map[string]int64  // pubkey (in base64) -> expiry (Unix time)
 	linkHashes         map[string]int    // link hash -> line number
 	treeHashes         map[string]string // tree hash -> link hash
 	signedTreeHashes   []string          // all signed tree hashes, starting from empty tree
 	signedTreeComments []string          // all signed tree comments
+	revoked            map[string]string // tree hash -> reason (signed revocations)
+	tags               map[string]string // tag name -> tree hash (signed tags)
+	tagNames           []string          // all signed tag names in order
 	unconfirmedOPs     []op              // unconfirmed operations
 }
 
@@ -36,10 +42,13 @@ func New(pubKey, comment string) *State {
 		signerWeights:      make(map[string]int),
 		signerComments:     make(map[string]string),
 		signerBarriers:     make(map[string]int),
+		signerExpiries:     make(map[string]int64),
 		linkHashes:         make(map[string]int),
 		treeHashes:         make(map[string]string),
 		signedTreeHashes:   []string{tree.EmptyHash},
 		signedTreeComments: []string{""},
+		revoked:            make(map[string]string),
+		tags:               make(map[string]string),
 		unconfirmedOPs:     []op{nop},
 	}
 	s.signerWeights[pubKey] = 1 // default weight for first signer
@@ -131,6 +140,13 @@ func (s *State) NotSigner(pubKey [32]byte) error {
 			if op.pubKey == pub {
 				return nil
 			}
+		case *rotKeyOP:
+			if op.newPubKey == pub {
+				return errors.New("state: duplicate addkey (unsigned rotkey)")
+			}
+			if op.oldPubKey == pub {
+				return nil
+			}
 		}
 	}
 	_, ok := s.signerWeights[pub]
@@ -157,6 +173,52 @@ func (s *State) NotPublished(treeHash string) error {
 	return nil
 }
 
+// NotRevoked makes sure that the given treeHash has not been revoked before
+// (unconfirmed or confirmed).
+func (s *State) NotRevoked(treeHash string) error {
+	if _, ok := s.revoked[treeHash]; ok {
+		return errors.New("state: duplicate revoke (signed)")
+	}
+	for i := s.signedLine + 1; i < len(s.unconfirmedOPs); i++ {
+		switch op := s.unconfirmedOPs[i].(type) {
+		case *revokeOP:
+			if op.treeHash == treeHash {
+				return errors.New("state: duplicate revoke (unsigned)")
+			}
+		}
+	}
+	return nil
+}
+
+// Revoked returns the reason why the given treeHash has been revoked and
+// true, if the revocation has been signed. Otherwise, it returns false.
+func (s *State) Revoked(treeHash string) (string, bool) {
+	reason, ok := s.revoked[treeHash]
+	return reason, ok
+}
+
+// RevokedTreeHashes returns a map of all signed revocations (tree hash ->
+// reason).
+func (s *State) RevokedTreeHashes() map[string]string {
+	revoked := make(map[string]string)
+	for treeHash, reason := range s.revoked {
+		revoked[treeHash] = reason
+	}
+	return revoked
+}
+
+// Tag returns the tree hash for the given tag name and true, if the tag has
+// been signed. Otherwise, it returns false.
+func (s *State) Tag(name string) (string, bool) {
+	treeHash, ok := s.tags[name]
+	return treeHash, ok
+}
+
+// Tags returns a list of all signed tag names in order.
+func (s *State) Tags() []string {
+	return append([]string{}, s.tagNames...)
+}
+
 // LastTreeHash returns the most current tree hash.
 func (s *State) LastTreeHash() string {
 	for i := len(s.unconfirmedOPs) - 1; i > s.signedLine; i-- {
@@ -214,6 +276,14 @@ func (s *State) lastWeight(pubKey [32]byte) (int, error) {
 			if op.pubKey == pub {
 				return 0, errors.New("state: duplicate remkey")
 			}
+		case *rotKeyOP:
+			if op.oldPubKey == pub {
+				return 0, errors.New("state: pubkey already rotated")
+			}
+			if op.newPubKey == pub {
+				// the new key inherits the weight of the old key
+				pub = op.oldPubKey
+			}
 		}
 	}
 	w, ok := s.signerWeights[pub]
@@ -242,6 +312,19 @@ func (s *State) SignerWeight(pubKey string) int {
 	return s.signerWeights[pubKey]
 }
 
+// SignerExpiry returns the expiry of the signer with given pubKey as Unix
+// time. It returns 0, if the key of the signer does not expire.
+func (s *State) SignerExpiry(pubKey string) int64 {
+	return s.signerExpiries[pubKey]
+}
+
+// Expired checks whether the key of the signer with given pubKey is expired
+// at the given datum.
+func (s *State) Expired(pubKey [32]byte, datum int64) bool {
+	expiry, ok := s.signerExpiries[base64.Encode(pubKey[:])]
+	return ok && datum >= expiry
+}
+
 // AddSourceHash adds treeHash at given linkHash to state.
 func (s *State) AddSourceHash(linkHash, treeHash, pubKey [32]byte, comment string) {
 	link := hex.Encode(linkHash[:])
@@ -252,6 +335,44 @@ func (s *State) AddSourceHash(linkHash, treeHash, pubKey [32]byte, comment strin
 	s.unconfirmedOPs = append(s.unconfirmedOPs, op)
 }
 
+// AddRevocation adds a revocation of the published treeHash with reason by
+// pubKey to state (unconfirmed).
+func (s *State) AddRevocation(treeHash, pubKey [32]byte, reason string) error {
+	tree := hex.Encode(treeHash[:])
+	if _, ok := s.treeHashes[tree]; !ok {
+		return fmt.Errorf("state: cannot revoke unpublished treehash: %s", tree)
+	}
+	if err := s.NotRevoked(tree); err != nil {
+		return err
+	}
+	op := newRevokeOP(tree, base64.Encode(pubKey[:]), reason)
+	s.unconfirmedOPs = append(s.unconfirmedOPs, op)
+	return nil
+}
+
+// AddTag adds a tag with given name for the published treeHash to state
+// (unconfirmed).
+func (s *State) AddTag(name string, treeHash [32]byte) error {
+	tree := hex.Encode(treeHash[:])
+	if _, ok := s.treeHashes[tree]; !ok {
+		return fmt.Errorf("state: cannot tag unpublished treehash: %s", tree)
+	}
+	if _, ok := s.tags[name]; ok {
+		return errors.New("state: duplicate tag (signed)")
+	}
+	for i := s.signedLine + 1; i < len(s.unconfirmedOPs); i++ {
+		switch op := s.unconfirmedOPs[i].(type) {
+		case *tagOP:
+			if op.name == name {
+				return errors.New("state: duplicate tag (unsigned)")
+			}
+		}
+	}
+	op := newTagOP(name, tree)
+	s.unconfirmedOPs = append(s.unconfirmedOPs, op)
+	return nil
+}
+
 // AddSigner adds pubKey with weight to state (unconfirmed).
 func (s *State) AddSigner(pubKey [32]byte, weight int, comment string) {
 	pub := base64.Encode(pubKey[:])
@@ -283,6 +404,14 @@ func (s *State) RemoveSigner(pubKey [32]byte) error {
 			n -= op.weight
 		case *sigCtlOp:
 			m = op.m
+		case *expKeyOP:
+			continue
+		case *rotKeyOP:
+			continue
+		case *revokeOP:
+			continue
+		case *tagOP:
+			continue
 		default:
 			return errors.New("state: RemoveSigner(): unknown OP type")
 		}
@@ -295,6 +424,30 @@ func (s *State) RemoveSigner(pubKey [32]byte) error {
 
 }
 
+// SetExpiry sets the expiry of the signer with given pubKey (unconfirmed).
+func (s *State) SetExpiry(pubKey [32]byte, expiry int64) error {
+	if _, err := s.lastWeight(pubKey); err != nil {
+		return err
+	}
+	op := newExpKeyOP(base64.Encode(pubKey[:]), expiry)
+	s.unconfirmedOPs = append(s.unconfirmedOPs, op)
+	return nil
+}
+
+// RotateSigner replaces the signer oldPubKey with newPubKey (unconfirmed).
+// The new key inherits weight and comment of the old key.
+func (s *State) RotateSigner(oldPubKey, newPubKey [32]byte) error {
+	if _, err := s.lastWeight(oldPubKey); err != nil {
+		return err
+	}
+	if err := s.NotSigner(newPubKey); err != nil {
+		return err
+	}
+	op := newRotKeyOP(base64.Encode(oldPubKey[:]), base64.Encode(newPubKey[:]))
+	s.unconfirmedOPs = append(s.unconfirmedOPs, op)
+	return nil
+}
+
 // SetSignatureControl sets new signature control m (unconfirmed).
 func (s *State) SetSignatureControl(m int) {
 	op := newSigCtlOp(m)
@@ -341,13 +494,36 @@ func (s *State) Sign(linkHash, pubKey [32]byte) error {
 				s.signerWeights[op.pubKey] = op.weight
 				s.signerComments[op.pubKey] = op.comment
 				s.signerBarriers[op.pubKey] = i
+				delete(s.signerExpiries, op.pubKey)
 			case *remKeyOP:
 				s.n -= op.weight
 				delete(s.signerWeights, op.pubKey)
 				delete(s.signerComments, op.pubKey)
 				delete(s.signerBarriers, op.pubKey)
+				delete(s.signerExpiries, op.pubKey)
 			case *sigCtlOp:
 				s.m = op.m
+			case *expKeyOP:
+				if _, ok := s.signerWeights[op.pubKey]; ok {
+					s.signerExpiries[op.pubKey] = op.expiry
+				}
+			case *rotKeyOP:
+				w, ok := s.signerWeights[op.oldPubKey]
+				if !ok {
+					return errors.New("state: Sign(): unknown rotkey pubKey")
+				}
+				s.signerWeights[op.newPubKey] = w
+				s.signerComments[op.newPubKey] = s.signerComments[op.oldPubKey]
+				s.signerBarriers[op.newPubKey] = s.signerBarriers[op.oldPubKey]
+				delete(s.signerWeights, op.oldPubKey)
+				delete(s.signerComments, op.oldPubKey)
+				delete(s.signerBarriers, op.oldPubKey)
+				delete(s.signerExpiries, op.oldPubKey)
+			case *revokeOP:
+				s.revoked[op.treeHash] = op.reason
+			case *tagOP:
+				s.tags[op.name] = op.treeHash
+				s.tagNames = append(s.tagNames, op.name)
 			default:
 				return errors.New("state: Sign(): unknown OP type")
 			}
@@ -362,12 +538,32 @@ func (s *State) Sign(linkHash, pubKey [32]byte) error {
 	return nil
 }
 
-// UnsignedInfo returns a string slice with information about all unsigned
-// entries suitable for printing.
-// If TreeHash is defined it returns info until that treeHash.
-// If omitSource is true source lines are omitted
-func (s *State) UnsignedInfo(pubKey, treeHash string, omitSource bool) ([]string, error) {
-	var infos []string
+// AddNote adds a note to state. Notes do not change the state, therefore
+// they do not have to be confirmed.
+func (s *State) AddNote() {
+	s.unconfirmedOPs = append(s.unconfirmedOPs, nop)
+}
+
+// UnsignedOP describes an unsigned operation of the hash chain.
+type UnsignedOP struct {
+	Line       int    // line number of the operation
+	Type       string // link type of the operation
+	Signatures int    // accumulated signature weight
+	TreeHash   string // source, revoke, tag
+	PubKey     string // source, addkey, remkey, expkey, rotkey (old key)
+	NewPubKey  string // rotkey
+	Weight     int    // addkey, remkey
+	M          int    // sigctl
+	Expiry     int64  // expkey
+	Name       string // tag
+	Comment    string // source, addkey, remkey, expkey, rotkey (signer comment), revoke (reason)
+}
+
+// UnsignedOPs returns a list of all unsigned operations.
+// If TreeHash is defined it returns operations until that treeHash.
+// If omitSource is true source operations are omitted.
+func (s *State) UnsignedOPs(pubKey, treeHash string, omitSource bool) ([]UnsignedOP, error) {
+	var ops []UnsignedOP
 	end := len(s.unconfirmedOPs)
 	if treeHash != "" {
 		end = s.SourceLine(treeHash)
@@ -384,21 +580,112 @@ func (s *State) UnsignedInfo(pubKey, treeHash string, omitSource bool) ([]string
 			if omitSource {
 				continue
 			}
-			info := fmt.Sprintf("%d source %s %s", op.signatures(), op.treeHash, op.comment)
-			infos = append(infos, info)
+			ops = append(ops, UnsignedOP{
+				Line:       i,
+				Type:       linktype.Source,
+				Signatures: op.signatures(),
+				TreeHash:   op.treeHash,
+				PubKey:     op.pubKey,
+				Comment:    op.comment,
+			})
 		case *addKeyOP:
-			info := fmt.Sprintf("%d addkey %d %s %s", op.signatures(), op.weight, op.pubKey, op.comment)
-			infos = append(infos, info)
+			ops = append(ops, UnsignedOP{
+				Line:       i,
+				Type:       linktype.AddKey,
+				Signatures: op.signatures(),
+				PubKey:     op.pubKey,
+				Weight:     op.weight,
+				Comment:    op.comment,
+			})
 		case *remKeyOP:
-			info := fmt.Sprintf("%d remkey %d %s %s", op.signatures(), op.weight, op.pubKey,
-				s.signerComments[op.pubKey]) // shows only comments from already confirmed signers, but that's fine
-			infos = append(infos, info)
+			ops = append(ops, UnsignedOP{
+				Line:       i,
+				Type:       linktype.RemoveKey,
+				Signatures: op.signatures(),
+				PubKey:     op.pubKey,
+				Weight:     op.weight,
+				Comment:    s.signerComments[op.pubKey], // shows only comments from already confirmed signers, but that's fine
+			})
 		case *sigCtlOp:
-			info := fmt.Sprintf("%d sigctl %d", op.signatures(), op.m)
-			infos = append(infos, info)
+			ops = append(ops, UnsignedOP{
+				Line:       i,
+				Type:       linktype.SignatureControl,
+				Signatures: op.signatures(),
+				M:          op.m,
+			})
+		case *expKeyOP:
+			ops = append(ops, UnsignedOP{
+				Line:       i,
+				Type:       linktype.ExpireKey,
+				Signatures: op.signatures(),
+				PubKey:     op.pubKey,
+				Expiry:     op.expiry,
+				Comment:    s.signerComments[op.pubKey],
+			})
+		case *rotKeyOP:
+			ops = append(ops, UnsignedOP{
+				Line:       i,
+				Type:       linktype.RotateKey,
+				Signatures: op.signatures(),
+				PubKey:     op.oldPubKey,
+				NewPubKey:  op.newPubKey,
+				Comment:    s.signerComments[op.oldPubKey],
+			})
+		case *revokeOP:
+			ops = append(ops, UnsignedOP{
+				Line:       i,
+				Type:       linktype.Revoke,
+				Signatures: op.signatures(),
+				TreeHash:   op.treeHash,
+				PubKey:     op.pubKey,
+				Comment:    op.reason,
+			})
+		case *tagOP:
+			ops = append(ops, UnsignedOP{
+				Line:       i,
+				Type:       linktype.Tag,
+				Signatures: op.signatures(),
+				TreeHash:   op.treeHash,
+				Name:       op.name,
+			})
 		default:
-			return nil, errors.New("state: Sign(): unknown OP type")
+			return nil, errors.New("state: UnsignedOPs(): unknown OP type")
+		}
+	}
+	return ops, nil
+}
+
+// UnsignedInfo returns a string slice with information about all unsigned
+// entries suitable for printing.
+// If TreeHash is defined it returns info until that treeHash.
+// If omitSource is true source lines are omitted
+func (s *State) UnsignedInfo(pubKey, treeHash string, omitSource bool) ([]string, error) {
+	ops, err := s.UnsignedOPs(pubKey, treeHash, omitSource)
+	if err != nil {
+		return nil, err
+	}
+	var infos []string
+	for _, op := range ops {
+		var info string
+		switch op.Type {
+		case linktype.Source:
+			info = fmt.Sprintf("%d source %s %s", op.Signatures, op.TreeHash, op.Comment)
+		case linktype.AddKey, linktype.RemoveKey:
+			info = fmt.Sprintf("%d %s %d %s %s", op.Signatures, op.Type, op.Weight, op.PubKey, op.Comment)
+		case linktype.SignatureControl:
+			info = fmt.Sprintf("%d sigctl %d", op.Signatures, op.M)
+		case linktype.ExpireKey:
+			info = fmt.Sprintf("%d expkey %s %s %s", op.Signatures, op.PubKey,
+				time.Format(op.Expiry), op.Comment)
+		case linktype.RotateKey:
+			info = fmt.Sprintf("%d rotkey %s %s %s", op.Signatures, op.PubKey,
+				op.NewPubKey, op.Comment)
+		case linktype.Revoke:
+			info = fmt.Sprintf("%d revoke %s %s", op.Signatures, op.TreeHash, op.Comment)
+		case linktype.Tag:
+			info = fmt.Sprintf("%d tag %s %s", op.Signatures, op.Name, op.TreeHash)
 		}
+		infos = append(infos, info)
 	}
 	return infos, nil
 }
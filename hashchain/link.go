@@ -101,6 +101,27 @@ func (l *link) StringColor() string {
 		s += color.RedString(l.typeFields[0])
 	case "sigctl":
 		s += color.HiRedString(l.typeFields[0])
+	case "rotkey":
+		s += color.RedString(l.typeFields[0]) + " " +
+			color.RedString(l.typeFields[1]) + " " +
+			color.BlueString(l.typeFields[2])
+	case "revoke":
+		s += color.CyanString(l.typeFields[0]) + " " +
+			color.RedString(l.typeFields[1]) + " " +
+			color.BlueString(l.typeFields[2]) + " " +
+			color.YellowString(l.typeFields[3])
+	case "tag":
+		s += color.YellowString(l.typeFields[0]) + " " +
+			color.CyanString(l.typeFields[1])
+	case "expkey":
+		s += color.RedString(l.typeFields[0]) + " " +
+			color.WhiteString(l.typeFields[1])
+	case "note":
+		s += color.GreenString(l.typeFields[0]) + " " +
+			color.RedString(l.typeFields[1]) + " " +
+			color.BlueString(l.typeFields[2]) + " " +
+			l.typeFields[3] + " " +
+			color.YellowString(l.typeFields[4])
 	default:
 		panic("hashchain: unknown link type")
 	}
@@ -18,3 +18,18 @@ const RemoveKey = "remkey"
 
 // SignatureControl link type.
 const SignatureControl = "sigctl"
+
+// ExpireKey link type.
+const ExpireKey = "expkey"
+
+// RotateKey link type.
+const RotateKey = "rotkey"
+
+// Revoke link type.
+const Revoke = "revoke"
+
+// Tag link type.
+const Tag = "tag"
+
+// Note link type.
+const Note = "note"
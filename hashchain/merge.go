@@ -18,11 +18,8 @@ func (c *HashChain) Merge(src *HashChain) error {
 	for ; i < len(src.chain); i++ {
 		var l link
 		l = *src.chain[i]
-		c.chain = append(c.chain, &l)
-		// Verifiying the entire chain after every entry is a bit excessive,
-		// especially because src is already verified.
-		// But better be safe than sorry.
-		if err := c.verify(); err != nil {
+		// verify every entry incrementally against the state of c
+		if err := c.appendLink(&l); err != nil {
 			return err
 		}
 		// save
@@ -0,0 +1,90 @@
+package hashchain
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/frankbraun/codechain/hashchain/linktype"
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/signer"
+	"github.com/frankbraun/codechain/util/time"
+)
+
+// Flags of note entries.
+const (
+	NoteInfo      = "info" // plain annotation
+	NoteObjection = "nack" // objection against the annotated entry
+	NoteResolve   = "ack"  // resolves previous objections of the same signer
+)
+
+// checkNoteFlag makes sure that flag is a valid note flag.
+func checkNoteFlag(flag string) error {
+	switch flag {
+	case NoteInfo, NoteObjection, NoteResolve:
+		return nil
+	}
+	return ErrInvalidNoteFlag
+}
+
+// noteMsg returns the message signed by note entries. It includes the
+// hash-of-previous and the current-time of the note entry itself, which
+// binds the signature to its position in the hash chain and prevents the
+// replay of old notes (like an old ack after a newer nack).
+func noteMsg(previous, linkHash []byte, datum int64, flag, text string) []byte {
+	msg := append([]byte{}, previous...)
+	msg = append(msg, linkHash...)
+	var d [8]byte
+	binary.BigEndian.PutUint64(d[:], uint64(datum))
+	msg = append(msg, d[:]...)
+	msg = append(msg, flag...)
+	msg = append(msg, ' ')
+	return append(msg, text...)
+}
+
+// Note adds a note entry with given flag and text for the entry with
+// linkHash signed by signer s to the hash chain.
+func (c *HashChain) Note(linkHash [32]byte, s signer.Signer, flag string, text []byte) (string, error) {
+	// check arguments
+	if err := checkNoteFlag(flag); err != nil {
+		return "", err
+	}
+	if len(text) == 0 {
+		return "", ErrNoteTextEmpty
+	}
+	pub := s.PublicKey()
+	if err := c.signatureCheckArgs(linkHash, pub); err != nil {
+		return "", err
+	}
+
+	// create entry
+	l := &link{
+		previous: c.Head(),
+		datum:    time.Now(),
+		linkType: linktype.Note,
+	}
+
+	// create signature
+	msg := noteMsg(l.previous[:], linkHash[:], l.datum, flag, string(text))
+	sig, err := s.Sign(msg)
+	if err != nil {
+		return "", err
+	}
+	l.typeFields = []string{
+		hex.Encode(linkHash[:]),
+		base64.Encode(pub[:]),
+		base64.Encode(sig[:]),
+		flag,
+		string(text),
+	}
+	// verify
+	if err := c.appendLink(l); err != nil {
+		return "", err
+	}
+
+	// save
+	if _, err := fmt.Fprintln(c.fp, l.String()); err != nil {
+		return "", err
+	}
+	return l.StringColor(), nil
+}
@@ -0,0 +1,124 @@
+package hashchain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frankbraun/codechain/hashchain/linktype"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/time"
+)
+
+func TestNote(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "hashchain_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// start chain and publish two signed releases
+	filename := filepath.Join(tmpdir, "hashchain")
+	c, _, err := Start(filename, signerA, nil)
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer c.Close()
+	start := c.Head()
+	if _, err := c.Source(helloHash, signerA, nil); err != nil {
+		t.Fatalf("c.Source() failed: %v", err)
+	}
+	var otherHash [32]byte
+	otherHash[0] = 1
+	if _, err := c.Source(otherHash, signerA, []byte("second release")); err != nil {
+		t.Fatalf("c.Source() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	other := hex.Encode(otherHash[:])
+	hello := hex.Encode(helloHash[:])
+
+	// invalid notes
+	otherLink := c.LinkHash(other)
+	if _, err := c.Note(otherLink, signerA, "veto", []byte("text")); err != ErrInvalidNoteFlag {
+		t.Error("c.Note() should fail with ErrInvalidNoteFlag")
+	}
+	if _, err := c.Note(otherLink, signerA, NoteObjection, nil); err != ErrNoteTextEmpty {
+		t.Error("c.Note() should fail with ErrNoteTextEmpty")
+	}
+	var unknownLink [32]byte
+	if _, err := c.Note(unknownLink, signerA, NoteInfo, []byte("text")); err == nil {
+		t.Error("c.Note() should fail for unknown link hash")
+	}
+
+	// object to second release
+	if _, err := c.Note(otherLink, signerA, NoteObjection, []byte("breaks  the build")); err != nil {
+		t.Fatalf("c.Note() failed: %v", err)
+	}
+	if objections := c.Objections(other); len(objections) != 1 ||
+		objections[0].Text != "breaks  the build" || objections[0].Line != 4 {
+		t.Errorf("wrong objections: %+v", objections)
+	}
+	if h, _ := c.LastSignedTreeHash(); h != other {
+		t.Errorf("objections should not block by default: %s", h)
+	}
+	c.SetObjectionPolicy(true)
+	if h, idx := c.LastSignedTreeHash(); h != hello || idx != 1 {
+		t.Errorf("last signed tree hash should skip objected release: %s, %d", h, idx)
+	}
+	if releases := c.Releases(); releases[1].Signed || !releases[0].Signed {
+		t.Errorf("objected release should not count as signed: %+v", releases)
+	}
+
+	// annotate cstart (not part of the log) and resolve objection
+	if _, err := c.Note(start, signerA, NoteInfo, []byte("hello world")); err != nil {
+		t.Fatalf("c.Note() failed: %v", err)
+	}
+	log := c.Log()
+	if len(log) != 3 || log[1].Signed || len(log[1].Notes) != 1 ||
+		log[2].Type != linktype.Note || log[2].Comment != "hello world" {
+		t.Errorf("wrong log: %+v", log)
+	}
+	if _, err := c.Note(otherLink, signerA, NoteResolve, []byte("fixed")); err != nil {
+		t.Fatalf("c.Note() failed: %v", err)
+	}
+	if h, _ := c.LastSignedTreeHash(); h != other {
+		t.Errorf("resolved objection should not block: %s", h)
+	}
+
+	// replayed notes (at a different position) are rejected
+	for _, i := range []int{4, len(c.chain) - 1} {
+		replay := &link{
+			previous:   c.Head(),
+			datum:      time.Now(),
+			linkType:   linktype.Note,
+			typeFields: c.chain[i].typeFields,
+		}
+		if err := c.appendLink(replay); err != ErrWrongSigNote {
+			t.Errorf("replay of note %d should fail with ErrWrongSigNote: %v", i, err)
+		}
+	}
+	if h, _ := c.LastSignedTreeHash(); h != other {
+		t.Errorf("replayed objection should not block: %s", h)
+	}
+
+	// read
+	if err := c.Close(); err != nil {
+		t.Fatalf("c.Close() failed: %v", err)
+	}
+	c2, err := ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	defer c2.Close()
+	notes := c2.Notes()
+	if len(notes) != 3 || !notes[0].Resolved || notes[0].Target != 2 ||
+		notes[1].Target != 0 || notes[2].Flag != NoteResolve {
+		t.Errorf("wrong notes after read: %+v", notes)
+	}
+	if objections := c2.UnresolvedObjections(); len(objections) != 0 {
+		t.Errorf("objection should be resolved: %+v", objections)
+	}
+}
@@ -0,0 +1,216 @@
+package hashchain
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/frankbraun/codechain/hashchain/linktype"
+	"github.com/frankbraun/codechain/tree"
+	"github.com/frankbraun/codechain/util"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/log"
+)
+
+const proofHeader = "codechain-proof"
+
+// Proof is a self-contained proof that a tree hash is signed as of a given
+// head of a hash chain. It can be verified without access to the hash chain,
+// the patches, or the source tree.
+//
+// A proof replays only the entries which establish the signer set, the
+// signature threshold, and the signatures over the source entry of the tree
+// hash (cstart, addkey, remkey, rotkey, expkey, sigctl, signtr, and the
+// source and revoke entries of the tree hash). All other entries up to the
+// head are omitted. Every entry commits to the complete previous entry,
+// therefore omitted entries are still needed to link the replayed entries to
+// the head. They are written without their hash-of-previous (which the
+// verifier recomputes) and are only hashed and checked for their type, not
+// replayed. The proof format is:
+//
+//	codechain-proof tree-hash head
+//	hash chain entries (one per line, omitted entries start with "- ")
+type Proof struct {
+	treeHash string
+	head     [32]byte
+	chain    []*link
+	omitted  []bool // entries which are not replayed
+}
+
+// omittable returns true, if link l cannot change the signer set, the
+// signature threshold, or the status of treeHash. Such links do not have to
+// be replayed to verify a proof for treeHash.
+func omittable(l *link, treeHash string) bool {
+	switch l.linkType {
+	case linktype.Source, linktype.Revoke:
+		return len(l.typeFields) > 0 && l.typeFields[0] != treeHash
+	case linktype.Tag, linktype.Note:
+		return true
+	}
+	return false
+}
+
+// TreeHash returns the tree hash proven by proof p.
+func (p *Proof) TreeHash() string {
+	return p.treeHash
+}
+
+// Head returns the head the proof p is relative to.
+func (p *Proof) Head() [32]byte {
+	return p.head
+}
+
+// checkSigned checks that treeHash is signed and has not been revoked.
+func (c *HashChain) checkSigned(treeHash string) error {
+	if treeHash == tree.EmptyHash {
+		return fmt.Errorf("hashchain: cannot prove empty tree hash")
+	}
+	_, idx := c.state.LastSignedTreeHash()
+	if !util.ContainsString(c.state.TreeHashes()[:idx+1], treeHash) {
+		return fmt.Errorf("hashchain: treehash %s is not signed", treeHash)
+	}
+	if reason, revoked := c.state.Revoked(treeHash); revoked {
+		return fmt.Errorf("hashchain: treehash %s has been revoked: %s",
+			treeHash, reason)
+	}
+	return nil
+}
+
+// Prove returns a proof that treeHash is signed as of the given head, which
+// must be contained in hash chain c.
+func (c *HashChain) Prove(treeHash string, head [32]byte) (*Proof, error) {
+	log.Printf("hashchain.Prove(%s, %x)", treeHash, head)
+	for i, l := range c.chain {
+		h := l.Hash()
+		if !bytes.Equal(h[:], head[:]) {
+			continue
+		}
+		// compute state as of head, all signatures have been verified before
+		prefix := &HashChain{chain: c.chain[:i+1], verified: i + 1}
+		if err := prefix.verify(); err != nil {
+			return nil, err
+		}
+		if err := prefix.checkSigned(treeHash); err != nil {
+			return nil, err
+		}
+		p := &Proof{
+			treeHash: treeHash,
+			head:     head,
+			chain:    prefix.chain,
+			omitted:  make([]bool, len(prefix.chain)),
+		}
+		for j := 1; j < len(p.chain); j++ {
+			p.omitted[j] = omittable(p.chain[j], treeHash)
+		}
+		return p, nil
+	}
+	return nil, ErrHeadNotFound
+}
+
+// skipLink adds the omitted link i of the hash chain to the state without
+// replaying it.
+func (c *HashChain) skipLink(i int) error {
+	if err := c.checkPrevious(i); err != nil {
+		return err
+	}
+	c.state.AddNote()
+	c.state.AddLinkHash(c.chain[i].Hash(), i)
+	return nil
+}
+
+// Verify proof p against the trusted head. All replayed entries contained in
+// the proof are fully verified (including signatures), omitted entries are
+// only hashed.
+func (p *Proof) Verify(head [32]byte) error {
+	if !bytes.Equal(p.head[:], head[:]) {
+		return ErrProofHeadMismatch
+	}
+	c := &HashChain{chain: p.chain}
+	for i, l := range c.chain {
+		if p.omitted[i] {
+			if i == 0 || !omittable(l, p.treeHash) {
+				return ErrInvalidProof
+			}
+			if err := c.skipLink(i); err != nil {
+				return err
+			}
+		} else if err := c.verifyLink(i); err != nil {
+			return err
+		}
+	}
+	h := c.Head()
+	if !bytes.Equal(h[:], head[:]) {
+		return ErrProofHeadMismatch
+	}
+	return c.checkSigned(p.treeHash)
+}
+
+// Write proof p to w.
+func (p *Proof) Write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s %s %x\n", proofHeader, p.treeHash, p.head)
+	if err != nil {
+		return err
+	}
+	for i, l := range p.chain {
+		line := l.String()
+		if p.omitted[i] {
+			// hash-of-previous is recomputed by the verifier
+			line = "- " + strings.SplitN(line, " ", 2)[1]
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadProof reads a proof from r. The proof is not verified, use Verify.
+func ReadProof(r io.Reader) (*Proof, error) {
+	log.Printf("hashchain.ReadProof()")
+	br := bufio.NewReader(r)
+	header, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("hashchain: cannot read proof header: %s", err)
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 || fields[0] != proofHeader {
+		return nil, ErrInvalidProof
+	}
+	if _, err := hex.Decode(fields[1], 32); err != nil {
+		return nil, fmt.Errorf("hashchain: cannot decode proof treehash: %s", err)
+	}
+	h, err := hex.Decode(fields[2], 32)
+	if err != nil {
+		return nil, fmt.Errorf("hashchain: cannot decode proof head: %s", err)
+	}
+	p := &Proof{treeHash: fields[1]}
+	copy(p.head[:], h)
+	s := bufio.NewScanner(br)
+	for s.Scan() {
+		text := s.Text()
+		omitted := strings.HasPrefix(text, "- ")
+		if omitted {
+			// link omitted entry to the previous one
+			if len(p.chain) == 0 {
+				return nil, ErrInvalidProof
+			}
+			prev := p.chain[len(p.chain)-1].Hash()
+			text = hex.Encode(prev[:]) + text[1:]
+		}
+		l, err := parseLink(text)
+		if err != nil {
+			return nil, err
+		}
+		p.chain = append(p.chain, l)
+		p.omitted = append(p.omitted, omitted)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.chain) == 0 {
+		return nil, ErrInvalidProof
+	}
+	return p, nil
+}
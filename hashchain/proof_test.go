@@ -0,0 +1,144 @@
+package hashchain
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frankbraun/codechain/util/hex"
+)
+
+func TestProof(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "hashchain_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// start chain, publish and sign release, publish unsigned release
+	filename := filepath.Join(tmpdir, "hashchain")
+	c, _, err := Start(filename, signerA, nil)
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer c.Close()
+	if _, err := c.Source(helloHash, signerA, nil); err != nil {
+		t.Fatalf("c.Source() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	signedHead := c.Head()
+	var otherHash [32]byte
+	otherHash[0] = 1
+	if _, err := c.Source(otherHash, signerA, nil); err != nil {
+		t.Fatalf("c.Source() failed: %v", err)
+	}
+	hello := hex.Encode(helloHash[:])
+	other := hex.Encode(otherHash[:])
+
+	// cannot prove unsigned tree hash or unknown head
+	if _, err := c.Prove(other, c.Head()); err == nil {
+		t.Error("c.Prove() should fail for unsigned tree hash")
+	}
+	var unknownHead [32]byte
+	if _, err := c.Prove(hello, unknownHead); err != ErrHeadNotFound {
+		t.Error("c.Prove() should fail with ErrHeadNotFound")
+	}
+
+	// prove signed tree hash as of signed head
+	p, err := c.Prove(hello, signedHead)
+	if err != nil {
+		t.Fatalf("c.Prove() failed: %v", err)
+	}
+	if len(p.chain) != 3 {
+		t.Errorf("proof should contain 3 entries, not %d", len(p.chain))
+	}
+	var b bytes.Buffer
+	if err := p.Write(&b); err != nil {
+		t.Fatalf("p.Write() failed: %v", err)
+	}
+	proof := b.String()
+
+	// read and verify proof
+	p2, err := ReadProof(strings.NewReader(proof))
+	if err != nil {
+		t.Fatalf("ReadProof() failed: %v", err)
+	}
+	if p2.TreeHash() != hello || p2.Head() != signedHead {
+		t.Error("read proof differs")
+	}
+	if err := p2.Verify(signedHead); err != nil {
+		t.Errorf("p.Verify() failed: %v", err)
+	}
+	if err := p2.Verify(c.Head()); err != ErrProofHeadMismatch {
+		t.Error("p.Verify() should fail with ErrProofHeadMismatch")
+	}
+
+	// tampered proofs
+	tampered := strings.Replace(proof, hello, other, 1)
+	p3, err := ReadProof(strings.NewReader(tampered))
+	if err != nil {
+		t.Fatalf("ReadProof() failed: %v", err)
+	}
+	if err := p3.Verify(signedHead); err == nil {
+		t.Error("p.Verify() should fail for tampered tree hash")
+	}
+	lines := strings.Split(proof, "\n")
+	truncated := strings.Join(lines[:len(lines)-2], "\n") + "\n"
+	p4, err := ReadProof(strings.NewReader(truncated))
+	if err != nil {
+		t.Fatalf("ReadProof() failed: %v", err)
+	}
+	if err := p4.Verify(signedHead); err == nil {
+		t.Error("p.Verify() should fail for truncated proof")
+	}
+	if _, err := ReadProof(strings.NewReader("garbage\n")); err != ErrInvalidProof {
+		t.Error("ReadProof() should fail with ErrInvalidProof")
+	}
+
+	// other sources and tags are omitted, but still link to the head
+	if _, err := c.Tag("v2", otherHash); err != nil {
+		t.Fatalf("c.Tag() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	p, err = c.Prove(hello, c.Head())
+	if err != nil {
+		t.Fatalf("c.Prove() failed: %v", err)
+	}
+	b.Reset()
+	if err := p.Write(&b); err != nil {
+		t.Fatalf("p.Write() failed: %v", err)
+	}
+	proof = b.String()
+	if n := strings.Count(proof, "\n- "); n != 2 {
+		t.Errorf("proof should omit 2 entries, not %d", n)
+	}
+	p5, err := ReadProof(strings.NewReader(proof))
+	if err != nil {
+		t.Fatalf("ReadProof() failed: %v", err)
+	}
+	if err := p5.Verify(c.Head()); err != nil {
+		t.Errorf("p.Verify() failed: %v", err)
+	}
+
+	// entries which have to be replayed cannot be omitted
+	lines = strings.Split(proof, "\n")
+	for i, line := range lines {
+		if strings.Contains(line, " signtr ") {
+			lines[i] = "- " + strings.SplitN(line, " ", 2)[1]
+		}
+	}
+	p6, err := ReadProof(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatalf("ReadProof() failed: %v", err)
+	}
+	if err := p6.Verify(c.Head()); err != ErrInvalidProof {
+		t.Error("p.Verify() should fail with ErrInvalidProof")
+	}
+}
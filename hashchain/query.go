@@ -0,0 +1,494 @@
+package hashchain
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/frankbraun/codechain/hashchain/linktype"
+	"github.com/frankbraun/codechain/util"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/time"
+)
+
+// Entry is the parsed form of a single hash chain entry.
+// Only the fields defined for the given link type are set.
+type Entry struct {
+	Line      int    `json:"line"`                 // line number (starting from 0)
+	Hash      string `json:"hash"`                 // hash of entry
+	Previous  string `json:"previous"`             // hash-of-previous
+	Time      int64  `json:"time"`                 // current-time (Unix time)
+	Type      string `json:"type"`                 // link type
+	PubKey    string `json:"pubkey,omitempty"`     // cstart, source, signtr, addkey, remkey, expkey, rotkey (old), revoke, note
+	NewPubKey string `json:"new_pubkey,omitempty"` // rotkey
+	Nonce     string `json:"nonce,omitempty"`      // cstart
+	Signature string `json:"signature,omitempty"`  // cstart, source, signtr, addkey, rotkey, revoke, note
+	TreeHash  string `json:"treehash,omitempty"`   // source, revoke, tag
+	LinkHash  string `json:"linkhash,omitempty"`   // signtr, note
+	Weight    int    `json:"weight,omitempty"`     // addkey
+	M         int    `json:"m,omitempty"`          // sigctl
+	Expiry    int64  `json:"expiry,omitempty"`     // expkey (Unix time)
+	Name      string `json:"name,omitempty"`       // tag
+	Flag      string `json:"flag,omitempty"`       // note
+	Comment   string `json:"comment,omitempty"`    // cstart, source, addkey, revoke (reason), note (text)
+}
+
+func (l *link) entry(line int) Entry {
+	h := l.Hash()
+	e := Entry{
+		Line:     line,
+		Hash:     hex.Encode(h[:]),
+		Previous: hex.Encode(l.previous[:]),
+		Time:     l.datum,
+		Type:     l.linkType,
+	}
+	comment := func(i int) string {
+		if len(l.typeFields) > i {
+			return l.typeFields[i]
+		}
+		return ""
+	}
+	// the hash chain has been verified, we can rely on the field layout
+	switch l.linkType {
+	case linktype.ChainStart:
+		e.PubKey = l.typeFields[0]
+		e.Nonce = l.typeFields[1]
+		e.Signature = l.typeFields[2]
+		e.Comment = comment(3)
+	case linktype.Source:
+		e.TreeHash = l.typeFields[0]
+		e.PubKey = l.typeFields[1]
+		e.Signature = l.typeFields[2]
+		e.Comment = comment(3)
+	case linktype.Signature:
+		e.LinkHash = l.typeFields[0]
+		e.PubKey = l.typeFields[1]
+		e.Signature = l.typeFields[2]
+	case linktype.AddKey:
+		e.Weight, _ = strconv.Atoi(l.typeFields[0])
+		e.PubKey = l.typeFields[1]
+		e.Signature = l.typeFields[2]
+		e.Comment = comment(3)
+	case linktype.RemoveKey:
+		e.PubKey = l.typeFields[0]
+	case linktype.SignatureControl:
+		e.M, _ = strconv.Atoi(l.typeFields[0])
+	case linktype.ExpireKey:
+		e.PubKey = l.typeFields[0]
+		e.Expiry, _ = time.Parse(l.typeFields[1])
+	case linktype.Revoke:
+		e.TreeHash = l.typeFields[0]
+		e.PubKey = l.typeFields[1]
+		e.Signature = l.typeFields[2]
+		e.Comment = l.typeFields[3]
+	case linktype.Tag:
+		e.Name = l.typeFields[0]
+		e.TreeHash = l.typeFields[1]
+	case linktype.RotateKey:
+		e.PubKey = l.typeFields[0]
+		e.NewPubKey = l.typeFields[1]
+		e.Signature = l.typeFields[2]
+	case linktype.Note:
+		e.LinkHash = l.typeFields[0]
+		e.PubKey = l.typeFields[1]
+		e.Signature = l.typeFields[2]
+		e.Flag = l.typeFields[3]
+		e.Comment = l.typeFields[4]
+	}
+	return e
+}
+
+// Iterator iterates over the entries of a hash chain.
+type Iterator struct {
+	c    *HashChain
+	line int
+}
+
+// Iterator returns a new iterator over all entries of hash chain c.
+// Call Next before the first call to Entry.
+func (c *HashChain) Iterator() *Iterator {
+	return &Iterator{c: c, line: -1}
+}
+
+// Next advances the iterator to the next entry and reports whether there
+// was one.
+func (it *Iterator) Next() bool {
+	if it.line+1 >= len(it.c.chain) {
+		return false
+	}
+	it.line++
+	return true
+}
+
+// Entry returns the current entry of the iterator.
+func (it *Iterator) Entry() Entry {
+	return it.c.chain[it.line].entry(it.line)
+}
+
+// Entries returns all entries of hash chain c in order.
+func (c *HashChain) Entries() []Entry {
+	entries := make([]Entry, 0, len(c.chain))
+	for it := c.Iterator(); it.Next(); {
+		entries = append(entries, it.Entry())
+	}
+	return entries
+}
+
+// Key describes an active signer of a hash chain.
+type Key struct {
+	PubKey  string `json:"pubkey"`
+	Weight  int    `json:"weight"`
+	Expiry  int64  `json:"expiry,omitempty"` // Unix time, 0 if the key doesn't expire
+	Comment string `json:"comment,omitempty"`
+}
+
+// Keys returns all active signers of hash chain c, sorted by pubkey.
+func (c *HashChain) Keys() []Key {
+	var keys []Key
+	for pubKey := range c.state.Signer() {
+		keys = append(keys, Key{
+			PubKey:  pubKey,
+			Weight:  c.state.SignerWeight(pubKey),
+			Expiry:  c.state.SignerExpiry(pubKey),
+			Comment: c.state.SignerComment(pubKey),
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].PubKey < keys[j].PubKey })
+	return keys
+}
+
+// Release describes a published source tree of a hash chain.
+type Release struct {
+	Line       int    `json:"line"`
+	TreeHash   string `json:"treehash"`
+	PubKey     string `json:"pubkey"`
+	Comment    string `json:"comment,omitempty"`
+	Signed     bool   `json:"signed"`               // see SetObjectionPolicy
+	Revoked    string `json:"revoked,omitempty"`    // reason, if revocation is signed
+	Objections []Note `json:"objections,omitempty"` // unresolved objections of signers
+}
+
+// Releases returns all published source trees of hash chain c in order.
+func (c *HashChain) Releases() []Release {
+	var releases []Release
+	signedLine := c.state.SignedLine()
+	objections := c.objections()
+	for i, l := range c.chain {
+		if l.linkType != linktype.Source {
+			continue
+		}
+		r := Release{
+			Line:       i,
+			TreeHash:   l.typeFields[0],
+			PubKey:     l.typeFields[1],
+			Objections: objections[i],
+		}
+		r.Signed = i <= signedLine && !(c.blockObjected && len(r.Objections) > 0)
+		if len(l.typeFields) > 3 {
+			r.Comment = l.typeFields[3]
+		}
+		r.Revoked, _ = c.state.Revoked(r.TreeHash)
+		releases = append(releases, r)
+	}
+	return releases
+}
+
+// Note describes a note entry of a hash chain.
+type Note struct {
+	Line     int    `json:"line"`
+	Time     int64  `json:"time"`
+	Target   int    `json:"target"` // line number of the annotated entry
+	PubKey   string `json:"pubkey"`
+	Flag     string `json:"flag"` // NoteInfo, NoteObjection, or NoteResolve
+	Text     string `json:"text"`
+	Resolved bool   `json:"resolved,omitempty"` // objection has been resolved
+}
+
+// Notes returns all note entries of hash chain c in order. An objection is
+// resolved, if the same signer added a NoteResolve note for the same entry
+// afterwards.
+func (c *HashChain) Notes() []Note {
+	lines := make(map[string]int)
+	var notes []Note
+	for i, l := range c.chain {
+		h := l.Hash()
+		lines[hex.Encode(h[:])] = i
+		if l.linkType != linktype.Note {
+			continue
+		}
+		n := Note{
+			Line:   i,
+			Time:   l.datum,
+			Target: lines[l.typeFields[0]],
+			PubKey: l.typeFields[1],
+			Flag:   l.typeFields[3],
+			Text:   l.typeFields[4],
+		}
+		if n.Flag == NoteResolve {
+			for j := range notes {
+				o := &notes[j]
+				if o.Flag == NoteObjection && o.Target == n.Target && o.PubKey == n.PubKey {
+					o.Resolved = true
+				}
+			}
+		}
+		notes = append(notes, n)
+	}
+	return notes
+}
+
+// objections returns the unresolved objections of active signers, indexed by
+// the line number of the entry they object to.
+func (c *HashChain) objections() map[int][]Note {
+	objections := make(map[int][]Note)
+	for _, n := range c.UnresolvedObjections() {
+		objections[n.Target] = append(objections[n.Target], n)
+	}
+	return objections
+}
+
+// UnresolvedObjections returns all unresolved objections of active signers in
+// order.
+func (c *HashChain) UnresolvedObjections() []Note {
+	signers := c.state.Signer()
+	var objections []Note
+	for _, n := range c.Notes() {
+		if n.Flag == NoteObjection && !n.Resolved && signers[n.PubKey] {
+			objections = append(objections, n)
+		}
+	}
+	return objections
+}
+
+// Objections returns the unresolved objections of active signers against the
+// source entry of the given treeHash.
+func (c *HashChain) Objections(treeHash string) []Note {
+	if !util.ContainsString(c.TreeHashes(), treeHash) {
+		return nil
+	}
+	return c.objections()[c.state.SourceLine(treeHash)]
+}
+
+// SetObjectionPolicy sets whether source entries with unresolved objections
+// of active signers count as signed (block is false, the default) or not
+// (block is true). This affects LastSignedTreeHash, Apply, Releases, Log, and
+// Status. Secure packages (see secpkg) are always installed and updated with
+// objections blocking.
+func (c *HashChain) SetObjectionPolicy(block bool) {
+	c.blockObjected = block
+}
+
+// Review describes a signature entry which covers a source entry.
+type Review struct {
+	Line   int    `json:"line"`
+	Time   int64  `json:"time"`
+	PubKey string `json:"pubkey"`
+}
+
+// reviews returns the reviews of all source entries of hash chain c, indexed
+// by the line number of the source entry. Signature entries sign all entries
+// up to the entry they refer to, only the first signature of every signer is
+// returned for a source entry.
+func (c *HashChain) reviews() map[int][]Review {
+	lines := make(map[string]int)
+	var sources []int
+	reviews := make(map[int][]Review)
+	seen := make(map[int]map[string]bool)
+	for i, l := range c.chain {
+		h := l.Hash()
+		lines[hex.Encode(h[:])] = i
+		switch l.linkType {
+		case linktype.Source:
+			sources = append(sources, i)
+			seen[i] = make(map[string]bool)
+		case linktype.Signature:
+			signed := lines[l.typeFields[0]]
+			pubKey := l.typeFields[1]
+			for _, source := range sources {
+				if source > signed || seen[source][pubKey] {
+					continue
+				}
+				seen[source][pubKey] = true
+				reviews[source] = append(reviews[source], Review{
+					Line:   i,
+					Time:   l.datum,
+					PubKey: pubKey,
+				})
+			}
+		}
+	}
+	return reviews
+}
+
+// Reviewers returns the public keys of all signers who signed the source
+// entry of the given treeHash with a signature entry, in the order of their
+// first signature. The publisher of treeHash is only contained, if they
+// signed it with a separate signature entry.
+func (c *HashChain) Reviewers(treeHash string) []string {
+	for i, l := range c.chain {
+		if l.linkType != linktype.Source || l.typeFields[0] != treeHash {
+			continue
+		}
+		var reviewers []string
+		for _, r := range c.reviews()[i] {
+			reviewers = append(reviewers, r.PubKey)
+		}
+		return reviewers
+	}
+	return nil
+}
+
+// LogEntry is an entry of the history of a hash chain (see Log).
+type LogEntry struct {
+	Entry
+	Signed  bool     `json:"signed"`            // entry is signed (see SetObjectionPolicy)
+	Revoked string   `json:"revoked,omitempty"` // reason, if revocation is signed (source)
+	Reviews []Review `json:"reviews,omitempty"` // source
+	Notes   []Note   `json:"notes,omitempty"`   // notes for entry
+}
+
+// Log returns the history of hash chain c in order: All source entries
+// together with the signature entries which cover them and all key and
+// threshold changes (addkey, remkey, expkey, rotkey, and sigctl) in between.
+// Notes are returned with the entries they annotate, notes for other entries
+// are returned as separate entries.
+func (c *HashChain) Log() []LogEntry {
+	var log []LogEntry
+	signedLine := c.state.SignedLine()
+	reviews := c.reviews()
+	objections := c.objections()
+	notes := make(map[int][]Note) // target line -> notes
+	targets := make(map[int]int)  // note line -> target line
+	for _, n := range c.Notes() {
+		notes[n.Target] = append(notes[n.Target], n)
+		targets[n.Line] = n.Target
+	}
+	logged := make(map[int]bool)
+	for i, l := range c.chain {
+		switch l.linkType {
+		case linktype.Source, linktype.AddKey, linktype.RemoveKey,
+			linktype.ExpireKey, linktype.RotateKey, linktype.SignatureControl:
+		case linktype.Note:
+			// targets always come before their notes
+			if logged[targets[i]] {
+				continue
+			}
+		default:
+			continue
+		}
+		logged[i] = true
+		e := LogEntry{
+			Entry:  l.entry(i),
+			Signed: i <= signedLine,
+			Notes:  notes[i],
+		}
+		if l.linkType == linktype.Source {
+			e.Revoked, _ = c.state.Revoked(e.TreeHash)
+			e.Reviews = reviews[i]
+			if c.blockObjected && len(objections[i]) > 0 {
+				e.Signed = false
+			}
+		}
+		log = append(log, e)
+	}
+	return log
+}
+
+// Tag describes a signed tag of a hash chain.
+type Tag struct {
+	Name     string `json:"name"`
+	TreeHash string `json:"treehash"`
+}
+
+// TagList returns all signed tags of hash chain c in order.
+func (c *HashChain) TagList() []Tag {
+	var tags []Tag
+	for _, name := range c.state.Tags() {
+		treeHash, _ := c.state.Tag(name)
+		tags = append(tags, Tag{Name: name, TreeHash: treeHash})
+	}
+	return tags
+}
+
+// UnsignedEntry describes an unsigned entry of a hash chain together with
+// the signature weight it has accumulated so far.
+type UnsignedEntry struct {
+	Line       int    `json:"line"`
+	Type       string `json:"type"`
+	Signatures int    `json:"signatures"`
+	TreeHash   string `json:"treehash,omitempty"`
+	PubKey     string `json:"pubkey,omitempty"`
+	NewPubKey  string `json:"new_pubkey,omitempty"`
+	Weight     int    `json:"weight,omitempty"`
+	M          int    `json:"m,omitempty"`
+	Expiry     int64  `json:"expiry,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+}
+
+// Unsigned returns all unsigned entries of hash chain c.
+// If TreeHash is defined it returns entries until that treeHash.
+// If omitSource is true source entries are omitted.
+func (c *HashChain) Unsigned(pubKey, treeHash string, omitSource bool) ([]UnsignedEntry, error) {
+	ops, err := c.state.UnsignedOPs(pubKey, treeHash, omitSource)
+	if err != nil {
+		return nil, err
+	}
+	var entries []UnsignedEntry
+	for _, op := range ops {
+		entries = append(entries, UnsignedEntry{
+			Line:       op.Line,
+			Type:       op.Type,
+			Signatures: op.Signatures,
+			TreeHash:   op.TreeHash,
+			PubKey:     op.PubKey,
+			NewPubKey:  op.NewPubKey,
+			Weight:     op.Weight,
+			M:          op.M,
+			Expiry:     op.Expiry,
+			Name:       op.Name,
+			Comment:    op.Comment,
+		})
+	}
+	return entries, nil
+}
+
+// Status is a summary of the state of a hash chain.
+type Status struct {
+	Head               string          `json:"head"`
+	LastSignedHead     string          `json:"last_signed_head"`
+	SignedLine         int             `json:"signed_line"`
+	M                  int             `json:"m"`
+	N                  int             `json:"n"`
+	Signers            []Key           `json:"signers"`
+	LastTreeHash       string          `json:"last_treehash"`
+	LastSignedTreeHash string          `json:"last_signed_treehash"`
+	Releases           []Release       `json:"releases"`
+	Tags               []Tag           `json:"tags,omitempty"`
+	Unsigned           []UnsignedEntry `json:"unsigned"`
+	Objections         []Note          `json:"objections,omitempty"` // unresolved objections of signers
+}
+
+// Status returns a summary of the state of hash chain c.
+func (c *HashChain) Status() (*Status, error) {
+	unsigned, err := c.Unsigned("", "", false)
+	if err != nil {
+		return nil, err
+	}
+	head := c.Head()
+	signedHead, signedLine := c.LastSignedHead()
+	lastSignedTreeHash, _ := c.LastSignedTreeHash()
+	return &Status{
+		Head:               hex.Encode(head[:]),
+		LastSignedHead:     hex.Encode(signedHead[:]),
+		SignedLine:         signedLine,
+		M:                  c.M(),
+		N:                  c.N(),
+		Signers:            c.Keys(),
+		LastTreeHash:       c.LastTreeHash(),
+		LastSignedTreeHash: lastSignedTreeHash,
+		Releases:           c.Releases(),
+		Tags:               c.TagList(),
+		Unsigned:           unsigned,
+		Objections:         c.UnresolvedObjections(),
+	}, nil
+}
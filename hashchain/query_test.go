@@ -0,0 +1,99 @@
+package hashchain
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/frankbraun/codechain/hashchain/linktype"
+	"github.com/frankbraun/codechain/tree"
+)
+
+func TestQuery(t *testing.T) {
+	c, err := ReadFile(filepath.Join("testdata", "hashchain_b"))
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	c.Close()
+
+	entries := c.Entries()
+	if len(entries) != 5 {
+		t.Fatalf("wrong number of entries: %d != 5", len(entries))
+	}
+	types := []string{
+		linktype.ChainStart,
+		linktype.Source,
+		linktype.Signature,
+		linktype.Source,
+		linktype.Signature,
+	}
+	for i, e := range entries {
+		if e.Line != i {
+			t.Errorf("entry %d has wrong line %d", i, e.Line)
+		}
+		if e.Type != types[i] {
+			t.Errorf("entry %d has wrong type %s", i, e.Type)
+		}
+		if i > 0 && e.Previous != entries[i-1].Hash {
+			t.Errorf("entry %d does not link to previous entry", i)
+		}
+	}
+	if entries[0].Comment != "John Doe <john.doe@mailinator.com>" {
+		t.Errorf("wrong cstart comment: %s", entries[0].Comment)
+	}
+	if entries[3].TreeHash != "a06e099f02939933881240cf689b089580e2b637b6aab25189ed37353294f6cb" {
+		t.Errorf("wrong source tree hash: %s", entries[3].TreeHash)
+	}
+	if entries[4].Previous != headStr {
+		t.Errorf("wrong previous hash: %s", entries[4].Previous)
+	}
+
+	keys := c.Keys()
+	if len(keys) != 1 || keys[0].Weight != 1 || keys[0].PubKey != entries[0].PubKey {
+		t.Errorf("wrong keys: %v", keys)
+	}
+
+	releases := c.Releases()
+	if len(releases) != 2 {
+		t.Fatalf("wrong number of releases: %d != 2", len(releases))
+	}
+	for _, r := range releases {
+		if !r.Signed {
+			t.Errorf("release %s should be signed", r.TreeHash)
+		}
+	}
+
+	reviewers := c.Reviewers(entries[3].TreeHash)
+	if len(reviewers) != 1 || reviewers[0] != entries[4].PubKey {
+		t.Errorf("wrong reviewers: %v", reviewers)
+	}
+	if reviewers := c.Reviewers(tree.EmptyHash); len(reviewers) != 0 {
+		t.Errorf("empty tree should not have reviewers: %v", reviewers)
+	}
+
+	log := c.Log()
+	if len(log) != 2 {
+		t.Fatalf("wrong number of log entries: %d != 2", len(log))
+	}
+	for i, line := range []int{1, 3} {
+		e := log[i]
+		if e.Line != line || e.Type != linktype.Source || !e.Signed {
+			t.Errorf("wrong log entry: %+v", e)
+		}
+		if len(e.Reviews) != 1 || e.Reviews[0].Line != line+1 ||
+			e.Reviews[0].PubKey != entries[line+1].PubKey ||
+			e.Reviews[0].Time != entries[line+1].Time {
+			t.Errorf("wrong reviews: %+v", e.Reviews)
+		}
+	}
+
+	s, err := c.Status()
+	if err != nil {
+		t.Fatalf("c.Status() failed: %v", err)
+	}
+	if s.Head != entries[4].Hash || s.LastSignedHead != lastSignedHeadB {
+		t.Error("wrong status head")
+	}
+	if s.SignedLine != 3 || s.M != 1 || s.N != 1 || len(s.Unsigned) != 0 {
+		t.Errorf("wrong status: %+v", s)
+	}
+}
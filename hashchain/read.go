@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/frankbraun/codechain/hashchain/linktype"
 	"github.com/frankbraun/codechain/util/file"
 	"github.com/frankbraun/codechain/util/hex"
 	"github.com/frankbraun/codechain/util/lockfile"
@@ -14,43 +15,60 @@ import (
 	"github.com/frankbraun/codechain/util/time"
 )
 
-func (c *HashChain) read(r io.Reader) error {
+// parseLink parses a single hash chain entry from text.
+func parseLink(text string) (*link, error) {
+	line := strings.SplitN(text, " ", 4)
+	if len(line) != 4 {
+		return nil, fmt.Errorf("could not split into 4 space separated parts: %s", line)
+	}
+	previous, err := hex.Decode(line[0], 32)
+	if err != nil {
+		return nil, err
+	}
+	var prev [32]byte
+	copy(prev[:], previous)
+	t, err := time.Parse(line[1])
+	if err != nil {
+		return nil, fmt.Errorf("hashchain: cannot parse time '%s': %s", line[1], err)
+	}
+	// the last type field can contain white spaces (comments and the
+	// like), which is the fourth field for all link types except note
+	n := 4
+	if line[2] == linktype.Note {
+		n = 5
+	}
+	l := &link{
+		previous:   prev,
+		datum:      t,
+		linkType:   line[2],
+		typeFields: strings.SplitN(line[3], " ", n),
+	}
+	if l.String() != text {
+		return nil, fmt.Errorf("hashchain: cannot reproduce line:\n%s", text)
+	}
+	return l, nil
+}
+
+func (c *HashChain) parse(r io.Reader) error {
 	// read hash chain
 	s := bufio.NewScanner(r)
 	for s.Scan() {
 		// the parsing is very basic, the actual verification is done in c.verify()
 		text := s.Text()
 		log.Println(text)
-		line := strings.SplitN(text, " ", 4)
-		if len(line) != 4 {
-			return fmt.Errorf("could not split into 4 space separated parts: %s", line)
-		}
-		previous, err := hex.Decode(line[0], 32)
+		l, err := parseLink(text)
 		if err != nil {
 			return err
 		}
-		var prev [32]byte
-		copy(prev[:], previous)
-		t, err := time.Parse(line[1])
-		if err != nil {
-			return fmt.Errorf("hashchain: cannot parse time '%s': %s", line[1], err)
-		}
-		l := &link{
-			previous:   prev,
-			datum:      t,
-			linkType:   line[2],
-			typeFields: strings.SplitN(line[3], " ", 4),
-		}
-		if l.String() != text {
-			return fmt.Errorf("hashchain: cannot reproduce line:\n%s", text)
-		}
 		c.chain = append(c.chain, l)
 	}
-	if err := s.Err(); err != nil {
+	return s.Err()
+}
+
+func (c *HashChain) read(r io.Reader) error {
+	if err := c.parse(r); err != nil {
 		return err
 	}
-
-	// verify
 	return c.verify()
 }
 
@@ -66,7 +84,20 @@ func Read(r io.Reader) (*HashChain, error) {
 
 // ReadFile reads hash chain from filename and verifies it.
 func ReadFile(filename string) (*HashChain, error) {
-	log.Printf("hashchain.ReadFile(%s)", filename)
+	return ReadFileCheckpoint(filename, "")
+}
+
+// ReadFileCheckpoint reads hash chain from filename and verifies it.
+//
+// If checkpointFile is not empty, it is used as a cache of the already
+// verified prefix of the hash chain: If the checkpoint matches the hash chain,
+// the signatures contained in the prefix are not verified again (the hash
+// links are still checked and the state is still computed). After a
+// successful verification the checkpoint is updated to the current head.
+// Only use checkpoint files which cannot be written by others (see
+// CheckpointFile) and never use them for hash chains fetched from elsewhere.
+func ReadFileCheckpoint(filename, checkpointFile string) (*HashChain, error) {
+	log.Printf("hashchain.ReadFileCheckpoint(%s, %s)", filename, checkpointFile)
 	// check arguments
 	exists, err := file.Exists(filename)
 	if err != nil {
@@ -88,10 +119,26 @@ func ReadFile(filename string) (*HashChain, error) {
 		return nil, err
 	}
 
-	if err := c.read(c.fp); err != nil {
+	if err := c.parse(c.fp); err != nil {
 		c.Close()
 		return nil, err
 	}
+	var cp *checkpoint
+	if checkpointFile != "" {
+		cp = readCheckpoint(checkpointFile)
+		c.verified = cp.verified(c.chain)
+		log.Printf("hashchain: %d lines verified by checkpoint", c.verified)
+	}
+	if err := c.verify(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if checkpointFile != "" {
+		if err := c.writeCheckpoint(checkpointFile, cp); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
 
 	// having only one signer is VERY BAD NEWS, emit obnoxious warning here, so
 	// all tools will display it
@@ -11,7 +11,7 @@ import (
 // RemoveKey adds a pubkey remove entry to hash chain.
 func (c *HashChain) RemoveKey(pubKey [32]byte) (string, error) {
 	// check arguments
-	// not necessary, done by c.verify()
+	// not necessary, done by c.appendLink()
 
 	// create entry
 	l := &link{
@@ -20,10 +20,8 @@ func (c *HashChain) RemoveKey(pubKey [32]byte) (string, error) {
 		linkType:   linktype.RemoveKey,
 		typeFields: []string{base64.Encode(pubKey[:])},
 	}
-	c.chain = append(c.chain, l)
-
 	// verify
-	if err := c.verify(); err != nil {
+	if err := c.appendLink(l); err != nil {
 		return "", err
 	}
 
@@ -0,0 +1,56 @@
+package hashchain
+
+import (
+	"fmt"
+
+	"github.com/frankbraun/codechain/hashchain/linktype"
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/signer"
+	"github.com/frankbraun/codechain/util/time"
+)
+
+// Revoke adds a revoke entry for the published treeHash with given reason
+// signed by signer s to the hash chain.
+func (c *HashChain) Revoke(treeHash [32]byte, s signer.Signer, reason []byte) (string, error) {
+	// check arguments
+	if len(reason) == 0 {
+		return "", ErrRevokeReasonEmpty
+	}
+	pub := s.PublicKey()
+	pubKey := base64.Encode(pub[:])
+	signers := c.Signer()
+	if !signers[pubKey] {
+		return "", fmt.Errorf("hashchain: pubkey %s is not an active signer", pubKey)
+	}
+
+	// create signature
+	msg := append(treeHash[:], reason...)
+	sig, err := s.Sign(msg)
+	if err != nil {
+		return "", err
+	}
+
+	// create entry
+	l := &link{
+		previous: c.Head(),
+		datum:    time.Now(),
+		linkType: linktype.Revoke,
+		typeFields: []string{
+			hex.Encode(treeHash[:]),
+			pubKey,
+			base64.Encode(sig[:]),
+			string(reason),
+		},
+	}
+	// verify
+	if err := c.appendLink(l); err != nil {
+		return "", err
+	}
+
+	// save
+	if _, err := fmt.Fprintln(c.fp, l.String()); err != nil {
+		return "", err
+	}
+	return l.StringColor(), nil
+}
@@ -0,0 +1,103 @@
+package hashchain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frankbraun/codechain/tree"
+	"github.com/frankbraun/codechain/util/hex"
+)
+
+func TestRevoke(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "hashchain_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// start chain and publish two releases
+	filename := filepath.Join(tmpdir, "hashchain")
+	c, _, err := Start(filename, signerA, nil)
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer c.Close()
+	if _, err := c.Source(helloHash, signerA, nil); err != nil {
+		t.Fatalf("c.Source() failed: %v", err)
+	}
+	var otherHash [32]byte
+	otherHash[0] = 1
+	if _, err := c.Source(otherHash, signerA, []byte("second release")); err != nil {
+		t.Fatalf("c.Source() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	other := hex.Encode(otherHash[:])
+	if h, idx := c.LastSignedTreeHash(); h != other || idx != 2 {
+		t.Fatalf("wrong last signed tree hash: %s, %d", h, idx)
+	}
+
+	// reason must not be empty
+	if _, err := c.Revoke(otherHash, signerA, nil); err != ErrRevokeReasonEmpty {
+		t.Error("c.Revoke() should fail with ErrRevokeReasonEmpty")
+	}
+	// cannot revoke unpublished tree hash
+	var unknownHash [32]byte
+	unknownHash[0] = 2
+	if _, err := c.Revoke(unknownHash, signerA, []byte("unknown")); err == nil {
+		t.Error("c.Revoke() should fail for unpublished tree hash")
+	}
+
+	// revoke second release
+	if _, err := c.Revoke(otherHash, signerA, []byte("contains backdoor")); err != nil {
+		t.Fatalf("c.Revoke() failed: %v", err)
+	}
+	if _, revoked := c.Revoked(other); revoked {
+		t.Error("revocation should not be active before it is signed")
+	}
+	// cannot revoke twice
+	if _, err := c.Revoke(otherHash, signerA, []byte("again")); err == nil {
+		t.Error("c.Revoke() should fail for already revoked tree hash")
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	reason, revoked := c.Revoked(other)
+	if !revoked || reason != "contains backdoor" {
+		t.Errorf("tree hash should be revoked: %s", reason)
+	}
+	hello := hex.Encode(helloHash[:])
+	if h, idx := c.LastSignedTreeHash(); h != hello || idx != 1 {
+		t.Errorf("last signed tree hash should skip revoked release: %s, %d", h, idx)
+	}
+	if c.LastSignedIndex() != 2 {
+		t.Errorf("wrong last signed index: %d", c.LastSignedIndex())
+	}
+
+	// revoke first release, too
+	if _, err := c.Revoke(helloHash, signerA, []byte("also broken")); err != nil {
+		t.Fatalf("c.Revoke() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	if h, idx := c.LastSignedTreeHash(); h != tree.EmptyHash || idx != 0 {
+		t.Errorf("last signed tree hash should be empty tree: %s, %d", h, idx)
+	}
+
+	// read
+	if err := c.Close(); err != nil {
+		t.Fatalf("c.Close() failed: %v", err)
+	}
+	c2, err := ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	defer c2.Close()
+	if len(c2.RevokedTreeHashes()) != 2 {
+		t.Errorf("wrong number of revoked tree hashes after read")
+	}
+}
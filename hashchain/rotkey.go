@@ -0,0 +1,51 @@
+package hashchain
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/frankbraun/codechain/hashchain/linktype"
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/signer"
+	"github.com/frankbraun/codechain/util/time"
+)
+
+// RotateKey adds a key rotation entry to hash chain which replaces the signer
+// oldPubKey with newPubKey. The signature must be made by newPubKey over
+// oldPubKey and newPubKey.
+func (c *HashChain) RotateKey(oldPubKey, newPubKey [32]byte, signature [64]byte) (string, error) {
+	// check arguments
+	msg := append(oldPubKey[:], newPubKey[:]...)
+	if !ed25519.Verify(newPubKey[:], msg, signature[:]) {
+		return "", ErrWrongSigRotKey
+	}
+
+	// create entry
+	l := &link{
+		previous: c.Head(),
+		datum:    time.Now(),
+		linkType: linktype.RotateKey,
+		typeFields: []string{
+			base64.Encode(oldPubKey[:]),
+			base64.Encode(newPubKey[:]),
+			base64.Encode(signature[:]),
+		},
+	}
+	// verify
+	if err := c.appendLink(l); err != nil {
+		return "", err
+	}
+
+	// save
+	if _, err := fmt.Fprintln(c.fp, l.String()); err != nil {
+		return "", err
+	}
+	return l.StringColor(), nil
+}
+
+// RotateKeySignature returns the signature of the new signer s over
+// oldPubKey and the public key of s, as required for RotateKey.
+func RotateKeySignature(oldPubKey [32]byte, s signer.Signer) ([64]byte, error) {
+	newPubKey := s.PublicKey()
+	return s.Sign(append(oldPubKey[:], newPubKey[:]...))
+}
@@ -0,0 +1,130 @@
+package hashchain
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/signer"
+)
+
+func rotateKeySignature(t *testing.T, oldPubKey [32]byte, s signer.Signer) [64]byte {
+	sig, err := RotateKeySignature(oldPubKey, s)
+	if err != nil {
+		t.Fatalf("RotateKeySignature() failed: %v", err)
+	}
+	return sig
+}
+
+func TestRotateKey(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "hashchain_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// start chain with pubA, add pubB with weight 2, and set m = 3
+	filename := filepath.Join(tmpdir, "hashchain")
+	c, _, err := Start(filename, signerA, []byte("Alice"))
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer c.Close()
+	comment := []byte("Bob")
+	sig := ed25519.Sign(secB[:], append(pubB[:], comment...))
+	var signature [64]byte
+	copy(signature[:], sig)
+	if _, err := c.AddKey(2, pubB, signature, comment); err != nil {
+		t.Fatalf("c.AddKey() failed: %v", err)
+	}
+	if _, err := c.SignatureControl(3); err != nil {
+		t.Fatalf("c.SignatureControl() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+
+	// generate new key for Bob
+	pub, sec, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() failed: %v", err)
+	}
+	var pubC [32]byte
+	var secC [64]byte
+	copy(pubC[:], pub)
+	copy(secC[:], sec)
+	signerC := signer.New(secC)
+
+	// signature must be made by the new key
+	if _, err := c.RotateKey(pubB, pubC, rotateKeySignature(t, pubB, signerB)); err != ErrWrongSigRotKey {
+		t.Errorf("c.RotateKey() should fail with ErrWrongSigRotKey (has %v)", err)
+	}
+	// cannot rotate to an existing signer
+	if _, err := c.RotateKey(pubB, pubA, rotateKeySignature(t, pubB, signerA)); err == nil {
+		t.Error("c.RotateKey() should fail for existing signer")
+	}
+
+	// rotate pubB -> pubC
+	if _, err := c.RotateKey(pubB, pubC, rotateKeySignature(t, pubB, signerC)); err != nil {
+		t.Fatalf("c.RotateKey() failed: %v", err)
+	}
+	infos, err := c.UnsignedInfo("", "", false)
+	if err != nil {
+		t.Fatalf("c.UnsignedInfo() failed: %v", err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("expected one unsigned entry, got %d", len(infos))
+	}
+	// cannot rotate the same key twice
+	if _, err := c.RotateKey(pubB, pubA, rotateKeySignature(t, pubB, signerA)); err == nil {
+		t.Error("c.RotateKey() should fail for already rotated key")
+	}
+
+	// confirm rotation
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	if _, err := c.Signature(c.Head(), signerB, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+	b := base64.Encode(pubB[:])
+	n := base64.Encode(pubC[:])
+	signer := c.Signer()
+	if signer[b] || !signer[n] {
+		t.Fatal("pubB should have been replaced by pubC")
+	}
+	if c.SignerWeight(n) != 2 {
+		t.Errorf("wrong weight for rotated key: %d != 2", c.SignerWeight(n))
+	}
+	if c.SignerComment(n) != "Bob" {
+		t.Errorf("wrong comment for rotated key: %s", c.SignerComment(n))
+	}
+	if c.M() != 3 || c.N() != 3 {
+		t.Errorf("m and n should be unchanged: %d, %d", c.M(), c.N())
+	}
+
+	// old key cannot sign anymore, the new one can
+	if _, err := c.Signature(c.Head(), signerB, false); err == nil {
+		t.Error("c.Signature() should fail for rotated key")
+	}
+	if _, err := c.Signature(c.Head(), signerC, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+
+	// read
+	if err := c.Close(); err != nil {
+		t.Fatalf("c.Close() failed: %v", err)
+	}
+	c2, err := ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	defer c2.Close()
+	if c2.SignerWeight(n) != 2 {
+		t.Errorf("wrong weight for rotated key after read: %d != 2", c2.SignerWeight(n))
+	}
+}
@@ -25,10 +25,8 @@ func (c *HashChain) SignatureControl(m int) (string, error) {
 		linkType:   linktype.SignatureControl,
 		typeFields: []string{strconv.Itoa(m)},
 	}
-	c.chain = append(c.chain, l)
-
 	// verify
-	if err := c.verify(); err != nil {
+	if err := c.appendLink(l); err != nil {
 		return "", err
 	}
 
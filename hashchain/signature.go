@@ -7,6 +7,7 @@ import (
 	"github.com/frankbraun/codechain/hashchain/linktype"
 	"github.com/frankbraun/codechain/util/base64"
 	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/signer"
 	"github.com/frankbraun/codechain/util/time"
 )
 
@@ -21,27 +22,33 @@ func (c *HashChain) signatureCheckArgs(linkHash, pubKey [32]byte) error {
 		return fmt.Errorf("hashchain: not a valid signer: %s",
 			hex.Encode(pubKey[:]))
 	}
+	// make sure the key of the signer has not expired
+	if c.state.Expired(pubKey, time.Now()) {
+		return ErrSignerExpired
+	}
 	return nil
 }
 
-// Signature adds a signature entry for linkHash signed by secKey to the hash chain.
+// Signature adds a signature entry for linkHash signed by signer s to the hash chain.
 // If detached it just returns the signature without adding it.
-func (c *HashChain) Signature(linkHash [32]byte, secKey [64]byte, detached bool) (string, error) {
+func (c *HashChain) Signature(linkHash [32]byte, s signer.Signer, detached bool) (string, error) {
 	// check arguments
-	var pub [32]byte
-	copy(pub[:], secKey[32:])
+	pub := s.PublicKey()
 	if err := c.signatureCheckArgs(linkHash, pub); err != nil {
 		return "", err
 	}
 
 	// create signature
-	sig := ed25519.Sign(secKey[:], linkHash[:])
+	sig, err := s.Sign(linkHash[:])
+	if err != nil {
+		return "", err
+	}
 
 	// create entry
 	typeFields := []string{
 		hex.Encode(linkHash[:]),
 		base64.Encode(pub[:]),
-		base64.Encode(sig),
+		base64.Encode(sig[:]),
 	}
 	l := &link{
 		previous:   c.Head(),
@@ -49,16 +56,17 @@ func (c *HashChain) Signature(linkHash [32]byte, secKey [64]byte, detached bool)
 		linkType:   linktype.Signature,
 		typeFields: typeFields,
 	}
-	c.chain = append(c.chain, l)
-
 	// verify
-	if err := c.verify(); err != nil {
+	if err := c.appendLink(l); err != nil {
 		return "", err
 	}
 
 	// detached signature?
 	if detached {
-		c.chain = c.chain[:len(c.chain)-1] // remove chain entry again
+		// remove chain entry again and restore state
+		if err := c.removeLastLink(); err != nil {
+			return "", err
+		}
 		return fmt.Sprintf("%s %s %s", typeFields[0], typeFields[1], typeFields[2]), nil
 	}
 
@@ -108,10 +116,8 @@ func (c *HashChain) DetachedSignature(linkHash, pubKey, signature string) (strin
 		linkType:   linktype.Signature,
 		typeFields: typeFields,
 	}
-	c.chain = append(c.chain, l)
-
 	// verify
-	if err := c.verify(); err != nil {
+	if err := c.appendLink(l); err != nil {
 		return "", err
 	}
 
@@ -1,28 +1,28 @@
 package hashchain
 
 import (
-	"crypto/ed25519"
 	"fmt"
 
 	"github.com/frankbraun/codechain/hashchain/linktype"
 	"github.com/frankbraun/codechain/util"
 	"github.com/frankbraun/codechain/util/base64"
 	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/signer"
 	"github.com/frankbraun/codechain/util/time"
 )
 
 // Source adds a source entry for treeHash and optional comment signed by
-// secKey to the hash chain.
-func (c *HashChain) Source(treeHash [32]byte, secKey [64]byte, comment []byte) (string, error) {
+// signer s to the hash chain.
+func (c *HashChain) Source(treeHash [32]byte, s signer.Signer, comment []byte) (string, error) {
 	// check arguments
 	hash := hex.Encode(treeHash[:])
 	if util.ContainsString(c.TreeHashes(), hash) {
 		return "", fmt.Errorf("hashchain: treehash %s already published", hash)
 	}
-	pub := secKey[32:]
-	pubKey := base64.Encode(pub)
-	signer := c.Signer()
-	if !signer[pubKey] {
+	pub := s.PublicKey()
+	pubKey := base64.Encode(pub[:])
+	signers := c.Signer()
+	if !signers[pubKey] {
 		return "", fmt.Errorf("hashchain: pubkey %s is not an active signer", pubKey)
 	}
 
@@ -31,13 +31,16 @@ func (c *HashChain) Source(treeHash [32]byte, secKey [64]byte, comment []byte) (
 	if len(comment) > 0 {
 		msg = append(msg, comment...)
 	}
-	sig := ed25519.Sign(secKey[:], msg)
+	sig, err := s.Sign(msg)
+	if err != nil {
+		return "", err
+	}
 
 	// create entry
 	typeFields := []string{
 		hash,
 		pubKey,
-		base64.Encode(sig),
+		base64.Encode(sig[:]),
 	}
 	if len(comment) > 0 {
 		typeFields = append(typeFields, string(comment))
@@ -48,10 +51,8 @@ func (c *HashChain) Source(treeHash [32]byte, secKey [64]byte, comment []byte) (
 		linkType:   linktype.Source,
 		typeFields: typeFields,
 	}
-	c.chain = append(c.chain, l)
-
 	// verify
-	if err := c.verify(); err != nil {
+	if err := c.appendLink(l); err != nil {
 		return "", err
 	}
 
@@ -1,7 +1,6 @@
 package hashchain
 
 import (
-	"crypto/ed25519"
 	"crypto/rand"
 	"fmt"
 	"os"
@@ -10,11 +9,12 @@ import (
 	"github.com/frankbraun/codechain/util/base64"
 	"github.com/frankbraun/codechain/util/file"
 	"github.com/frankbraun/codechain/util/lockfile"
+	"github.com/frankbraun/codechain/util/signer"
 	"github.com/frankbraun/codechain/util/time"
 )
 
-// Start returns a new hash chain with signature control list m.
-func Start(filename string, secKey [64]byte, comment []byte) (*HashChain, string, error) {
+// Start returns a new hash chain with signer s as the initial signer.
+func Start(filename string, s signer.Signer, comment []byte) (*HashChain, string, error) {
 	// check arguments
 	exists, err := file.Exists(filename)
 	if err != nil {
@@ -42,16 +42,20 @@ func Start(filename string, secKey [64]byte, comment []byte) (*HashChain, string
 		c.lock.Release()
 		return nil, "", err
 	}
-	pub := secKey[32:]
-	msg := append(pub, nonce[:]...)
+	pub := s.PublicKey()
+	msg := append(pub[:], nonce[:]...)
 	if len(comment) > 0 {
 		msg = append(msg, comment...)
 	}
-	sig := ed25519.Sign(secKey[:], msg)
+	sig, err := s.Sign(msg)
+	if err != nil {
+		c.lock.Release()
+		return nil, "", err
+	}
 
 	// create entry
 	typeFields := []string{
-		base64.Encode(pub),
+		base64.Encode(pub[:]),
 		base64.Encode(nonce[:]),
 		base64.Encode(sig[:]),
 	}
@@ -64,10 +68,8 @@ func Start(filename string, secKey [64]byte, comment []byte) (*HashChain, string
 		linkType:   linktype.ChainStart,
 		typeFields: typeFields,
 	}
-	c.chain = append(c.chain, l)
-
 	// verify
-	if err := c.verify(); err != nil {
+	if err := c.appendLink(l); err != nil {
 		c.lock.Release()
 		return nil, "", err
 	}
@@ -0,0 +1,75 @@
+package hashchain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/frankbraun/codechain/hashchain/linktype"
+	"github.com/frankbraun/codechain/util"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/time"
+)
+
+// checkTagName makes sure that name is a valid tag name: It must be a
+// non-empty UTF-8 string without white spaces and must not look like a tree
+// hash.
+func checkTagName(name string) error {
+	if name == "" || !utf8.ValidString(name) || strings.IndexFunc(name, unicode.IsSpace) != -1 {
+		return ErrInvalidTagName
+	}
+	if _, err := hex.Decode(name, 32); err == nil {
+		return ErrInvalidTagName
+	}
+	return nil
+}
+
+// Tag adds a tag entry with given name for treeHash to the hash chain.
+func (c *HashChain) Tag(name string, treeHash [32]byte) (string, error) {
+	// check arguments
+	if err := checkTagName(name); err != nil {
+		return "", err
+	}
+
+	// create entry
+	l := &link{
+		previous:   c.Head(),
+		datum:      time.Now(),
+		linkType:   linktype.Tag,
+		typeFields: []string{name, hex.Encode(treeHash[:])},
+	}
+	// verify
+	if err := c.appendLink(l); err != nil {
+		return "", err
+	}
+
+	// save
+	if _, err := fmt.Fprintln(c.fp, l.String()); err != nil {
+		return "", err
+	}
+	return l.StringColor(), nil
+}
+
+// TagTreeHash returns the tree hash for the given tag name and true, if the
+// tag has been signed. Otherwise, it returns false.
+func (c *HashChain) TagTreeHash(name string) (string, bool) {
+	return c.state.Tag(name)
+}
+
+// Tags returns a list of all signed tag names in order.
+func (c *HashChain) Tags() []string {
+	return c.state.Tags()
+}
+
+// ResolveTreeHash resolves s, which is either a tree hash or a signed tag
+// name, to a tree hash contained in the hash chain.
+func (c *HashChain) ResolveTreeHash(s string) (string, error) {
+	if treeHash, ok := c.state.Tag(s); ok {
+		return treeHash, nil
+	}
+	if util.ContainsString(c.TreeHashes(), s) {
+		return s, nil
+	}
+	return "", fmt.Errorf("hashchain: unknown treehash or tag: %s", s)
+}
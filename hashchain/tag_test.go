@@ -0,0 +1,92 @@
+package hashchain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frankbraun/codechain/util/hex"
+)
+
+func TestTag(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "hashchain_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// start chain and publish release
+	filename := filepath.Join(tmpdir, "hashchain")
+	c, _, err := Start(filename, signerA, nil)
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer c.Close()
+	if _, err := c.Source(helloHash, signerA, nil); err != nil {
+		t.Fatalf("c.Source() failed: %v", err)
+	}
+
+	// invalid tag names
+	for _, name := range []string{"", "v1 .0", "v1.0\t", helloHashHex} {
+		if _, err := c.Tag(name, helloHash); err != ErrInvalidTagName {
+			t.Errorf("c.Tag(%q) should fail with ErrInvalidTagName", name)
+		}
+	}
+	// cannot tag unpublished tree hash
+	var unknownHash [32]byte
+	if _, err := c.Tag("v0.1", unknownHash); err == nil {
+		t.Error("c.Tag() should fail for unpublished tree hash")
+	}
+
+	// tag release
+	if _, err := c.Tag("v1.0", helloHash); err != nil {
+		t.Fatalf("c.Tag() failed: %v", err)
+	}
+	if _, err := c.ResolveTreeHash("v1.0"); err == nil {
+		t.Error("unsigned tag should not resolve")
+	}
+	// cannot use same tag name twice
+	if _, err := c.Tag("v1.0", helloHash); err == nil {
+		t.Error("c.Tag() should fail for duplicate tag")
+	}
+	if _, err := c.Signature(c.Head(), signerA, false); err != nil {
+		t.Fatalf("c.Signature() failed: %v", err)
+	}
+
+	// resolve
+	treeHash, err := c.ResolveTreeHash("v1.0")
+	if err != nil {
+		t.Fatalf("c.ResolveTreeHash() failed: %v", err)
+	}
+	if treeHash != helloHashHex {
+		t.Errorf("tag resolved to wrong tree hash: %s", treeHash)
+	}
+	treeHash, err = c.ResolveTreeHash(helloHashHex)
+	if err != nil {
+		t.Fatalf("c.ResolveTreeHash() failed: %v", err)
+	}
+	if treeHash != helloHashHex {
+		t.Errorf("tree hash resolved to wrong tree hash: %s", treeHash)
+	}
+	if _, err := c.ResolveTreeHash("v2.0"); err == nil {
+		t.Error("unknown tag should not resolve")
+	}
+	if _, err := c.ResolveTreeHash(hex.Encode(unknownHash[:])); err == nil {
+		t.Error("unknown tree hash should not resolve")
+	}
+
+	// read
+	if err := c.Close(); err != nil {
+		t.Fatalf("c.Close() failed: %v", err)
+	}
+	c2, err := ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	defer c2.Close()
+	tags := c2.Tags()
+	if len(tags) != 1 || tags[0] != "v1.0" {
+		t.Errorf("wrong tags after read: %v", tags)
+	}
+}
@@ -3,7 +3,6 @@ package hashchain
 import (
 	"bytes"
 	"crypto/ed25519"
-	"crypto/sha256"
 	"errors"
 	"fmt"
 	"strconv"
@@ -13,6 +12,7 @@ import (
 	"github.com/frankbraun/codechain/util/base64"
 	"github.com/frankbraun/codechain/util/hex"
 	"github.com/frankbraun/codechain/util/log"
+	"github.com/frankbraun/codechain/util/time"
 )
 
 // hash-of-previous current-time cstart pubkey nonce signature [comment]
@@ -48,7 +48,7 @@ func (c *HashChain) verifyChainStartType(i int, fields []string) error {
 	// validate fields
 	msg := append(pubKey, nonce...)
 	msg = append(msg, comment...)
-	if !ed25519.Verify(pubKey, msg, sig) {
+	if !c.verifySig(i, pubKey, msg, sig) {
 		return ErrWrongSigCStart
 	}
 
@@ -90,7 +90,7 @@ func (c *HashChain) verifySourceType(i int, fields []string) error {
 
 	// validate fields
 	msg := append(treeHash, comment...)
-	if !ed25519.Verify(pubKey, msg, sig) {
+	if !c.verifySig(i, pubKey, msg, sig) {
 		return ErrWrongSigSource
 	}
 	// make sure pubkey it is a valid signer
@@ -99,6 +99,9 @@ func (c *HashChain) verifySourceType(i int, fields []string) error {
 	if !c.state.HasSigner(p) {
 		return fmt.Errorf("hashchain: not a valid signer: %s", pub)
 	}
+	if c.state.Expired(p, c.chain[i].datum) {
+		return ErrSignerExpired
+	}
 	// make sure treehash has not been published before
 	if err = c.state.NotPublished(tree); err != nil {
 		return err
@@ -139,7 +142,7 @@ func (c *HashChain) verifySignatureType(i int, fields []string) error {
 	}
 
 	// validate fields
-	if !ed25519.Verify(pubKey, linkHash, sig) {
+	if !c.verifySig(i, pubKey, linkHash, sig) {
 		return ErrWrongSigSignature
 	}
 	// make sure link hash does exist
@@ -149,9 +152,14 @@ func (c *HashChain) verifySignatureType(i int, fields []string) error {
 		return fmt.Errorf("hashchain: link hash doesn't exist: %s", link)
 	}
 
-	// update state
+	// make sure pubkey has not expired
 	var p [32]byte
 	copy(p[:], pubKey[:])
+	if c.state.Expired(p, c.chain[i].datum) {
+		return ErrSignerExpired
+	}
+
+	// update state
 	return c.state.Sign(l, p)
 }
 
@@ -189,7 +197,7 @@ func (c *HashChain) verifyAddKeyType(i int, fields []string) error {
 	// validate fields
 	var p [32]byte
 	copy(p[:], pubKey)
-	if !ed25519.Verify(p[:], append(pubKey, comment...), sig) {
+	if !c.verifySig(i, p[:], append(pubKey, comment...), sig) {
 		return ErrWrongSigAddKey
 	}
 	if err = c.state.NotSigner(p); err != nil {
@@ -257,7 +265,290 @@ func (c *HashChain) verifySignatureControlType(i int, fields []string) error {
 	return nil
 }
 
-// verify hash chain.
+// hash-of-previous current-time expkey pubkey expiry
+func (c *HashChain) verifyExpireKeyType(i int, fields []string) error {
+	log.Printf("%d verify expkey", i)
+	// check arguments
+	if i == 0 {
+		return ErrMustStartWithCStart
+	}
+	if len(fields) != 2 {
+		return ErrWrongTypeFields
+	}
+
+	// parse type fields
+	pub := fields[0]
+	pubKey, err := base64.Decode(pub, 32)
+	if err != nil {
+		return err
+	}
+	expiry, err := time.Parse(fields[1])
+	if err != nil {
+		return fmt.Errorf("hashchain: cannot parse expiry: %s", fields[1])
+	}
+
+	// validate fields
+	if expiry <= c.chain[i].datum {
+		return ErrExpiryNotInFuture
+	}
+
+	// update state
+	var p [32]byte
+	copy(p[:], pubKey)
+	return c.state.SetExpiry(p, expiry)
+}
+
+// hash-of-previous current-time rotkey old-pubkey new-pubkey signature
+func (c *HashChain) verifyRotateKeyType(i int, fields []string) error {
+	log.Printf("%d verify rotkey", i)
+	// check arguments
+	if i == 0 {
+		return ErrMustStartWithCStart
+	}
+	if len(fields) != 3 {
+		return ErrWrongTypeFields
+	}
+
+	// parse type fields
+	oldPubKey, err := base64.Decode(fields[0], 32)
+	if err != nil {
+		return err
+	}
+	newPubKey, err := base64.Decode(fields[1], 32)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.Decode(fields[2], 64)
+	if err != nil {
+		return err
+	}
+
+	// validate fields
+	msg := append(oldPubKey, newPubKey...)
+	if !c.verifySig(i, newPubKey, msg, sig) {
+		return ErrWrongSigRotKey
+	}
+
+	// update state
+	var o, n [32]byte
+	copy(o[:], oldPubKey)
+	copy(n[:], newPubKey)
+	return c.state.RotateSigner(o, n)
+}
+
+// hash-of-previous current-time revoke tree-hash pubkey signature reason
+func (c *HashChain) verifyRevokeType(i int, fields []string) error {
+	log.Printf("%d verify revoke", i)
+	// check arguments
+	if i == 0 {
+		return ErrMustStartWithCStart
+	}
+	if len(fields) != 4 {
+		return ErrWrongTypeFields
+	}
+
+	// parse type fields
+	treeHash, err := hex.Decode(fields[0], 32)
+	if err != nil {
+		return err
+	}
+	pub := fields[1]
+	pubKey, err := base64.Decode(pub, 32)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.Decode(fields[2], 64)
+	if err != nil {
+		return err
+	}
+	reason := fields[3]
+
+	// validate fields
+	if reason == "" {
+		return ErrRevokeReasonEmpty
+	}
+	msg := append(treeHash, reason...)
+	if !c.verifySig(i, pubKey, msg, sig) {
+		return ErrWrongSigRevoke
+	}
+	// make sure pubkey it is a valid signer
+	var p [32]byte
+	copy(p[:], pubKey)
+	if !c.state.HasSigner(p) {
+		return fmt.Errorf("hashchain: not a valid signer: %s", pub)
+	}
+	if c.state.Expired(p, c.chain[i].datum) {
+		return ErrSignerExpired
+	}
+
+	// update state
+	var t [32]byte
+	copy(t[:], treeHash)
+	return c.state.AddRevocation(t, p, reason)
+}
+
+// hash-of-previous current-time tag name tree-hash
+func (c *HashChain) verifyTagType(i int, fields []string) error {
+	log.Printf("%d verify tag", i)
+	// check arguments
+	if i == 0 {
+		return ErrMustStartWithCStart
+	}
+	if len(fields) != 2 {
+		return ErrWrongTypeFields
+	}
+
+	// parse type fields
+	name := fields[0]
+	treeHash, err := hex.Decode(fields[1], 32)
+	if err != nil {
+		return err
+	}
+
+	// validate fields
+	if err := checkTagName(name); err != nil {
+		return err
+	}
+
+	// update state
+	var t [32]byte
+	copy(t[:], treeHash)
+	return c.state.AddTag(name, t)
+}
+
+// hash-of-previous current-time note hash-of-chain-entry pubkey signature flag text
+func (c *HashChain) verifyNoteType(i int, fields []string) error {
+	log.Printf("%d verify note", i)
+	// check arguments
+	if i == 0 {
+		return ErrMustStartWithCStart
+	}
+	if len(fields) != 5 {
+		return ErrWrongTypeFields
+	}
+
+	// parse type fields
+	link := fields[0]
+	linkHash, err := hex.Decode(link, 32)
+	if err != nil {
+		return err
+	}
+	pub := fields[1]
+	pubKey, err := base64.Decode(pub, 32)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.Decode(fields[2], 64)
+	if err != nil {
+		return err
+	}
+	flag := fields[3]
+	text := fields[4]
+
+	// validate fields
+	if err := checkNoteFlag(flag); err != nil {
+		return err
+	}
+	if text == "" {
+		return ErrNoteTextEmpty
+	}
+	prev := c.chain[i].previous
+	msg := noteMsg(prev[:], linkHash, c.chain[i].datum, flag, text)
+	if !c.verifySig(i, pubKey, msg, sig) {
+		return ErrWrongSigNote
+	}
+	// make sure link hash does exist
+	var l [32]byte
+	copy(l[:], linkHash)
+	if !c.state.HasLinkHash(l) {
+		return fmt.Errorf("hashchain: link hash doesn't exist: %s", link)
+	}
+	// make sure pubkey it is a valid signer
+	var p [32]byte
+	copy(p[:], pubKey)
+	if !c.state.HasSigner(p) {
+		return fmt.Errorf("hashchain: not a valid signer: %s", pub)
+	}
+	if c.state.Expired(p, c.chain[i].datum) {
+		return ErrSignerExpired
+	}
+
+	// update state
+	c.state.AddNote()
+	return nil
+}
+
+// checkPrevious checks that link i of the hash chain refers to the link
+// before it and that time is not going backwards.
+func (c *HashChain) checkPrevious(i int) error {
+	l := c.chain[i]
+	prevHash := emptyTree
+	var prevDatum int64
+	if i > 0 {
+		prevHash = c.chain[i-1].Hash()
+		prevDatum = c.chain[i-1].datum
+	}
+
+	// make sure we actually have a hash chain
+	if !bytes.Equal(prevHash[:], l.previous[:]) {
+		return ErrLinkBroken
+	}
+
+	// make sure time is ascending
+	if l.datum < prevDatum {
+		return ErrDescendingTime
+	}
+	return nil
+}
+
+// verifyLink verifies link i of the hash chain against the current state and
+// updates the state accordingly. The state must reflect all links before i.
+func (c *HashChain) verifyLink(i int) error {
+	l := c.chain[i]
+	if err := c.checkPrevious(i); err != nil {
+		return err
+	}
+
+	var err error
+	switch l.linkType {
+	case linktype.ChainStart:
+		err = c.verifyChainStartType(i, l.typeFields)
+	case linktype.Source:
+		err = c.verifySourceType(i, l.typeFields)
+	case linktype.Signature:
+		err = c.verifySignatureType(i, l.typeFields)
+	case linktype.AddKey:
+		err = c.verifyAddKeyType(i, l.typeFields)
+	case linktype.RemoveKey:
+		err = c.verifyRemoveKeyType(i, l.typeFields)
+	case linktype.SignatureControl:
+		err = c.verifySignatureControlType(i, l.typeFields)
+	case linktype.ExpireKey:
+		err = c.verifyExpireKeyType(i, l.typeFields)
+	case linktype.RotateKey:
+		err = c.verifyRotateKeyType(i, l.typeFields)
+	case linktype.Revoke:
+		err = c.verifyRevokeType(i, l.typeFields)
+	case linktype.Tag:
+		err = c.verifyTagType(i, l.typeFields)
+	case linktype.Note:
+		err = c.verifyNoteType(i, l.typeFields)
+	default:
+		err = ErrUnknownLinkType
+	}
+	if err != nil {
+		return err
+	}
+
+	// store link hash and line number
+	c.state.AddLinkHash(l.Hash(), i)
+	if c.state.LinkHashes() != c.state.OPs() {
+		return errors.New("c.state.LinkHashes() != c.state.OPs()") // should never happen
+	}
+	return nil
+}
+
+// verify entire hash chain and rebuild the state from scratch.
 func (c *HashChain) verify() error {
 	// basic check
 	if len(c.chain) == 0 {
@@ -265,51 +556,49 @@ func (c *HashChain) verify() error {
 	}
 
 	// iterate over all links
-	prevHash := emptyTree
-	var prevDatum int64
-	for i, l := range c.chain {
-		// make sure we actually have a hash chain
-		if !bytes.Equal(prevHash[:], l.previous[:]) {
-			return ErrLinkBroken
-		}
-
-		// make sure time is ascending
-		if l.datum < prevDatum {
-			return ErrDescendingTime
-		}
-
-		var err error
-		switch l.linkType {
-		case linktype.ChainStart:
-			err = c.verifyChainStartType(i, l.typeFields)
-		case linktype.Source:
-			err = c.verifySourceType(i, l.typeFields)
-		case linktype.Signature:
-			err = c.verifySignatureType(i, l.typeFields)
-		case linktype.AddKey:
-			err = c.verifyAddKeyType(i, l.typeFields)
-		case linktype.RemoveKey:
-			err = c.verifyRemoveKeyType(i, l.typeFields)
-		case linktype.SignatureControl:
-			err = c.verifySignatureControlType(i, l.typeFields)
-		default:
-			err = ErrUnknownLinkType
-		}
-		if err != nil {
+	for i := range c.chain {
+		if err := c.verifyLink(i); err != nil {
 			return err
 		}
+	}
 
-		// store link hash and line number
-		c.state.AddLinkHash(l.Hash(), i)
-		if c.state.LinkHashes() != c.state.OPs() {
-			return errors.New("c.state.LinkHashes() != c.state.OPs()") // should never happen
+	// all clear
+	return nil
+}
+
+// appendLink appends l to the hash chain and verifies it incrementally
+// against the current state. If the verification fails, l is removed again
+// and the state is restored.
+func (c *HashChain) appendLink(l *link) error {
+	c.chain = append(c.chain, l)
+	if err := c.verifyLink(len(c.chain) - 1); err != nil {
+		if rerr := c.removeLastLink(); rerr != nil {
+			return rerr
 		}
+		return err
+	}
+	return nil
+}
 
-		// prepare for next entry
-		prevHash = sha256.Sum256([]byte(l.String()))
-		prevDatum = l.datum
+// removeLastLink removes the last link from the hash chain and restores the
+// state. All remaining links have been verified before, therefore their
+// signatures are not verified again.
+func (c *HashChain) removeLastLink() error {
+	c.chain = c.chain[:len(c.chain)-1]
+	c.verified = len(c.chain)
+	if len(c.chain) == 0 {
+		c.state = nil
+		return nil
 	}
+	return c.verify()
+}
 
-	// all clear
-	return nil
+// verifySig verifies the signature sig of msg by pubKey for link i.
+// Signatures of links which are part of an already verified prefix of the
+// hash chain (see checkpoint) are not verified again.
+func (c *HashChain) verifySig(i int, pubKey, msg, sig []byte) bool {
+	if i < c.verified {
+		return true
+	}
+	return ed25519.Verify(pubKey, msg, sig)
 }
@@ -29,6 +29,8 @@ const (
 	secondFileDiff                  // second file diff
 	addFile                         // add file state
 	diffFile                        // diff file state
+	moveTarget                      // move or copy target (version 3)
+	moveDiff                        // move or copy diff state (version 3)
 	terminal                        // end state
 )
 
@@ -45,7 +47,7 @@ func procStart(line string) (state, int, error) {
 	if err != nil {
 		return 0, 0, err
 	}
-	if version != 1 && version != 2 { // only support Version 1 and 2
+	if version < 1 || version > 5 { // only support Version 1 to 5
 		return 0, 0, ErrHeaderVersion
 	}
 	return treehash, version, nil
@@ -79,8 +81,53 @@ type mode int
 const (
 	regularFile mode = iota + 1
 	binaryFile
+	symlink // version 5
 )
 
+// parseFileLine parses a file diff line and returns its fields.
+func parseFileLine(line string, version int) (byte, mode, []byte, string, error) {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) != 4 {
+		return 0, 0, nil, "", ErrFileFieldsNum
+	}
+	switch fields[0] {
+	case "-", "+":
+	case "m", "c":
+		if version < 3 {
+			return 0, 0, nil, "", ErrFileField0
+		}
+	default:
+		return 0, 0, nil, "", ErrFileField0
+	}
+	var mode mode
+	switch fields[1] {
+	case "f":
+		mode = regularFile
+	case "x":
+		mode = binaryFile
+	case "l":
+		if version < 5 {
+			return 0, 0, nil, "", ErrFileField1
+		}
+		mode = symlink
+	default:
+		return 0, 0, nil, "", ErrFileField1
+	}
+	hash, err := hex.Decode(fields[2], 32)
+	if err != nil {
+		return 0, 0, nil, "", err
+	}
+	return fields[0][0], mode, hash, fields[3], nil
+}
+
+// perm returns the file permissions for mode.
+func (m mode) perm() os.FileMode {
+	if m == regularFile {
+		return 0644
+	}
+	return 0755
+}
+
 // + f hex_hash filename # add file, filename must not exist
 //
 // or
@@ -91,27 +138,42 @@ const (
 //
 // - f hex_hash filename_a # possible mode change
 // + x hex_hash filename_b # if filename_a and filname_b differ, filename_b must not exist
-func procFileDiff(line string, dir string, prevDiffInfo *diffInfo) (state, *diffInfo, error) {
-	fields := strings.SplitN(line, " ", 4)
-	if len(fields) != 4 {
-		return 0, nil, ErrFileFieldsNum
-	}
-	if fields[0] != "-" && fields[0] != "+" {
-		return 0, nil, ErrFileField0
-	}
-	if fields[1] != "f" && fields[1] != "x" {
-		return 0, nil, ErrFileField1
-	}
-	hash, err := hex.Decode(fields[2], 32)
+//
+// or (version 3)
+//
+// m f hex_hash filename_a # move file, filename_a must exist
+// + x hex_hash filename_b # filename_b must not exist
+//
+// or (version 3)
+//
+// c f hex_hash filename_a # copy file, filename_a must exist
+// + x hex_hash filename_b # filename_b must not exist
+//
+// Since version 5 the mode can also be 'l' (symlink), except for moves and
+// copies.
+func procFileDiff(line string, j *Journal, prevDiffInfo *diffInfo, version int) (state, *diffInfo, error) {
+	dir := j.dir
+	op, mode, hash, name, err := parseFileLine(line, version)
 	if err != nil {
 		return 0, nil, err
 	}
-	name := fields[3]
-	var mode mode
-	if fields[1] == "f" {
-		mode = regularFile
-	} else {
-		mode = binaryFile
+	if err := checkPath(dir, name); err != nil {
+		return 0, nil, err
+	}
+	if op == 'm' || op == 'c' {
+		// symlinks cannot be moved or copied
+		if mode == symlink {
+			return 0, nil, ErrSymlinkMode
+		}
+		// move or copy source, make sure it exists with the given hash
+		h, err := fileHash(filepath.Join(dir, name), mode)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !bytes.Equal(h[:], hash) {
+			return 0, nil, ErrFileHashMismatchBefore
+		}
+		return moveTarget, &diffInfo{mode, hash, name, op}, nil
 	}
 	if prevDiffInfo != nil {
 		// We read to two file info lines after another ('-' followed by '+').
@@ -129,24 +191,24 @@ func procFileDiff(line string, dir string, prevDiffInfo *diffInfo) (state, *diff
 			// Instead of "moving", we delete the previous file and then add
 			// the current file again.
 			oldpath := filepath.Join(dir, prevDiffInfo.name)
-			if err := os.Remove(oldpath); err != nil {
+			if err := j.remove(oldpath); err != nil {
 				return 0, nil, err
 			}
-			return addFile, &diffInfo{mode, hash, name}, nil
+			return addFile, &diffInfo{mode, hash, name, op}, nil
 		}
 		// The two files have the same name, check if their hash differs.
-		if bytes.Equal(hash[:], prevDiffInfo.hash) {
+		// Changes from and to symlinks always require a diff.
+		if bytes.Equal(hash[:], prevDiffInfo.hash) &&
+			mode != symlink && prevDiffInfo.mode != symlink {
 			// The hashes do not differ, check if we have to change the file
 			// permissions.
 			if mode != prevDiffInfo.mode {
 				// chmod
-				var perm os.FileMode
-				if mode == regularFile {
-					perm = 0644
-				} else {
-					perm = 0755
+				fn := filepath.Join(dir, name)
+				if err := j.record(fn); err != nil {
+					return 0, nil, err
 				}
-				if err := os.Chmod(filepath.Join(dir, name), perm); err != nil {
+				if err := os.Chmod(fn, mode.perm()); err != nil {
 					return 0, nil, err
 				}
 			}
@@ -155,8 +217,8 @@ func procFileDiff(line string, dir string, prevDiffInfo *diffInfo) (state, *diff
 			return fileDiff, nil, nil
 		}
 		// The hash differs, we have to process a diff next.
-		return diffFile, &diffInfo{mode, hash, name}, nil
-	} else if fields[0] == "+" {
+		return diffFile, &diffInfo{mode, hash, name, op}, nil
+	} else if op == '+' {
 		// add file
 		fn := filepath.Join(dir, name)
 		exists, err := file.Exists(fn)
@@ -166,10 +228,67 @@ func procFileDiff(line string, dir string, prevDiffInfo *diffInfo) (state, *diff
 		if exists {
 			return 0, nil, ErrAddTargetFileExists
 		}
-		return addFile, &diffInfo{mode, hash, name}, nil
+		return addFile, &diffInfo{mode, hash, name, op}, nil
 	}
 	// else: delete or diff?
-	return secondFileDiff, &diffInfo{mode, hash, name}, nil
+	return secondFileDiff, &diffInfo{mode, hash, name, op}, nil
+}
+
+// + x hex_hash filename_b # target of move or copy (version 3)
+//
+// If the hashes of source and target are the same, the source file is moved
+// or copied to the target file directly. Otherwise, a diff has to follow.
+func procMoveTarget(line string, j *Journal, src *diffInfo) (state, *diffInfo, error) {
+	dir := j.dir
+	op, mode, hash, name, err := parseFileLine(line, 3)
+	if err != nil {
+		return 0, nil, err
+	}
+	if op != '+' {
+		return 0, nil, ErrMoveTargetMissing
+	}
+	if err := checkPath(dir, name); err != nil {
+		return 0, nil, err
+	}
+	if mode == symlink {
+		return 0, nil, ErrSymlinkMode
+	}
+	// make sure target doesn't exist
+	fn := filepath.Join(dir, name)
+	exists, err := file.Exists(fn)
+	if err != nil {
+		return 0, nil, err
+	}
+	if exists {
+		return 0, nil, ErrMoveTargetFileExists
+	}
+	if !bytes.Equal(hash, src.hash) {
+		// The hash differs, we have to process a diff next.
+		return moveDiff, &diffInfo{mode, hash, name, op}, nil
+	}
+	if err := j.mkdirAll(filepath.Dir(fn)); err != nil {
+		return 0, nil, err
+	}
+	oldpath := filepath.Join(dir, src.name)
+	if err := j.record(fn); err != nil {
+		return 0, nil, err
+	}
+	if src.op == 'm' {
+		if err := j.record(oldpath); err != nil {
+			return 0, nil, err
+		}
+		if err := os.Rename(oldpath, fn); err != nil {
+			return 0, nil, err
+		}
+	} else {
+		if err := file.Copy(oldpath, fn); err != nil {
+			return 0, nil, err
+		}
+	}
+	if err := os.Chmod(fn, mode.perm()); err != nil {
+		return 0, nil, err
+	}
+	return fileDiff, nil, nil
 }
 
 // procResults process the final treehash line and returns the terminal state.
@@ -185,39 +304,51 @@ func procResult(line, dir string, excludePaths []string) (state, error) {
 
 type applyFunc func(w io.Writer, text string, patch []byte) error
 
-func apply(dir string, buf []byte, state state, prev, cur *diffInfo, applyFunc applyFunc) error {
+func apply(j *Journal, buf []byte, state state, prev, cur *diffInfo, applyFunc applyFunc) error {
+	dir := j.dir
 	var text string
+	if cur.mode == symlink {
+		return ErrSymlinkMode
+	}
 	fileB := filepath.Join(dir, cur.name)
-	if state == addFile {
-		if err := os.MkdirAll(filepath.Dir(fileB), 0755); err != nil {
+	if state == addFile || state == moveDiff {
+		if err := j.mkdirAll(filepath.Dir(fileB)); err != nil {
 			return err
 		}
-	} else {
+	}
+	replaceSymlink := false
+	if state != addFile {
 		fileA := filepath.Join(dir, prev.name)
-		hash, err := tree.SHA256(fileA)
+		hash, err := fileHash(fileA, prev.mode)
 		if err != nil {
 			return err
 		}
 		if !bytes.Equal(hash[:], prev.hash) {
 			return ErrFileHashMismatchBefore
 		}
-		buf, err := ioutil.ReadFile(fileA)
-		if err != nil {
-			return err
+		if prev.mode == symlink {
+			// replace symlink with file, the patch applies to empty text
+			if err := j.remove(fileA); err != nil {
+				return err
+			}
+			replaceSymlink = true
+		} else {
+			buf, err := ioutil.ReadFile(fileA)
+			if err != nil {
+				return err
+			}
+			text = string(buf)
 		}
-		text = string(buf)
 	}
 	var flag int
-	if state == addFile {
+	if state == addFile || state == moveDiff || replaceSymlink {
 		flag = os.O_CREATE | os.O_EXCL | os.O_WRONLY
 	} else {
 		flag = os.O_TRUNC | os.O_WRONLY
 	}
-	var perm os.FileMode
-	if cur.mode == regularFile {
-		perm = 0644
-	} else {
-		perm = 0755
+	perm := cur.mode.perm()
+	if err := j.record(fileB); err != nil {
+		return err
 	}
 	f, err := os.OpenFile(fileB, flag, perm)
 	if err != nil {
@@ -244,6 +375,12 @@ func apply(dir string, buf []byte, state state, prev, cur *diffInfo, applyFunc a
 			return err
 		}
 	}
+	// Remove the source of a move.
+	if state == moveDiff && prev.op == 'm' {
+		if err := j.remove(filepath.Join(dir, prev.name)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -251,6 +388,7 @@ type diffInfo struct {
 	mode
 	hash []byte
 	name string
+	op   byte // '-', '+', 'm', or 'c'
 }
 
 // scanNewlines is a split function for a Scanner that returns each line of
@@ -277,11 +415,33 @@ func scanNewlines(data []byte, atEOF bool) (advance int, token []byte, err error
 
 // Apply applies the patch read from r to the directory tree dir.
 // The paths given in excludePaths are excluded from all tree hash calculations.
+//
+// Apply is atomic: If the patch cannot be applied completely (or Apply is
+// interrupted), all changes are rolled back (see Journal).
 func Apply(dir string, r io.Reader, excludePaths []string) error {
+	j, err := NewJournal(dir)
+	if err != nil {
+		return err
+	}
+	return j.Run(func() error {
+		return j.Apply(r, excludePaths)
+	})
+}
+
+// Apply applies the patch read from r to the directory tree of journal j and
+// records all changes in j. The changes are not rolled back in case of error,
+// see Journal.Run.
+//
+// The paths given in excludePaths are excluded from all tree hash calculations.
+func (j *Journal) Apply(r io.Reader, excludePaths []string) error {
 	log.Println("patchfile.Apply()")
+	dir := j.dir
+	j.begin()
 	var (
 		prevDiffInfo *diffInfo
 		curDiffInfo  *diffInfo
+		startHash    string
+		endHash      string
 		err          error
 	)
 	s := bufio.NewScanner(r)
@@ -291,6 +451,9 @@ func Apply(dir string, r io.Reader, excludePaths []string) error {
 	state := start
 	version := 0
 	for s.Scan() {
+		if j.interrupted() {
+			return ErrInterrupted
+		}
 		line := s.Text()
 		log.Println("line:")
 		log.Println(line)
@@ -307,6 +470,7 @@ func Apply(dir string, r io.Reader, excludePaths []string) error {
 			if err != nil {
 				return err
 			}
+			startHash = strings.ToLower(strings.TrimPrefix(line, "treehash "))
 		case fileDiff:
 			log.Println("state: fileDiff")
 			fields := strings.SplitN(line, " ", 2)
@@ -316,9 +480,10 @@ func Apply(dir string, r io.Reader, excludePaths []string) error {
 				if err != nil {
 					return err
 				}
+				endHash = strings.ToLower(strings.TrimPrefix(line, "treehash "))
 			} else {
 				prevDiffInfo = nil
-				state, curDiffInfo, err = procFileDiff(line, dir, prevDiffInfo)
+				state, curDiffInfo, err = procFileDiff(line, j, prevDiffInfo, version)
 				if err != nil {
 					return err
 				}
@@ -327,10 +492,11 @@ func Apply(dir string, r io.Reader, excludePaths []string) error {
 			log.Println("state: secondFileDiff")
 			fields := strings.SplitN(line, " ", 2)
 			lookAhead := fields[0]
-			if lookAhead == "-" || lookAhead == "treehash" {
+			if lookAhead == "-" || lookAhead == "m" || lookAhead == "c" ||
+				lookAhead == "treehash" {
 				// delete
 				fn := filepath.Join(dir, curDiffInfo.name)
-				hash, err := tree.SHA256(fn)
+				hash, err := fileHash(fn, curDiffInfo.mode)
 				if err != nil {
 					return err
 				}
@@ -338,7 +504,7 @@ func Apply(dir string, r io.Reader, excludePaths []string) error {
 					return fmt.Errorf("patchfile: hash of file '%s' to delete doesn't match",
 						prevDiffInfo.name)
 				}
-				if err := os.Remove(fn); err != nil {
+				if err := j.remove(fn); err != nil {
 					return err
 				}
 				// reset
@@ -349,16 +515,27 @@ func Apply(dir string, r io.Reader, excludePaths []string) error {
 				if err != nil {
 					return err
 				}
+				endHash = strings.ToLower(strings.TrimPrefix(line, "treehash "))
 			} else {
 				prevDiffInfo = curDiffInfo
-				state, curDiffInfo, err = procFileDiff(line, dir, prevDiffInfo)
+				state, curDiffInfo, err = procFileDiff(line, j, prevDiffInfo, version)
 				if err != nil {
 					return err
 				}
 			}
+		case moveTarget:
+			log.Println("state: moveTarget")
+			prevDiffInfo = curDiffInfo
+			state, curDiffInfo, err = procMoveTarget(line, j, prevDiffInfo)
+			if err != nil {
+				return err
+			}
 		case addFile:
 			log.Println("state: addFile (fallthrough)")
 			fallthrough
+		case moveDiff:
+			log.Println("state: moveDiff (fallthrough)")
+			fallthrough
 		case diffFile:
 			log.Println("state: diffFile")
 			fields := strings.SplitN(line, " ", 2)
@@ -383,6 +560,23 @@ func Apply(dir string, r io.Reader, excludePaths []string) error {
 				if numLines < 1 {
 					return ErrDiffLinesNonPositive
 				}
+			case "symlink":
+				if version < 5 {
+					return ErrDiffModeUnknown
+				}
+				if numLines != 1 {
+					return ErrSymlinkLines
+				}
+			case "bindelta":
+				if version < 4 {
+					return ErrDiffModeUnknown
+				}
+				if state == addFile {
+					return ErrDeltaWithoutSource
+				}
+				if numLines < 1 {
+					return ErrDiffLinesNonPositive
+				}
 			default:
 				return ErrDiffModeUnknown
 			}
@@ -404,7 +598,7 @@ func Apply(dir string, r io.Reader, excludePaths []string) error {
 			switch lookAhead {
 			case "ascii85":
 				buf := strings.Join(lines, "")
-				err = apply(dir, []byte(buf), state, prevDiffInfo, curDiffInfo, ascii85Apply)
+				err = apply(j, []byte(buf), state, prevDiffInfo, curDiffInfo, ascii85Apply)
 				if err != nil {
 					return err
 				}
@@ -416,7 +610,7 @@ func Apply(dir string, r io.Reader, excludePaths []string) error {
 				if len(lines) > 0 {
 					buf = strings.Join(lines, "\n") + "\n"
 				}
-				err = apply(dir, []byte(buf), state, prevDiffInfo, curDiffInfo, dmpApply)
+				err = apply(j, []byte(buf), state, prevDiffInfo, curDiffInfo, dmpApply)
 				if err != nil {
 					return err
 				}
@@ -425,7 +619,24 @@ func Apply(dir string, r io.Reader, excludePaths []string) error {
 				curDiffInfo = nil
 			case "utf8file":
 				buf := strings.Join(lines, "\n")
-				err = apply(dir, []byte(buf), state, prevDiffInfo, curDiffInfo, utf8fileApply)
+				err = apply(j, []byte(buf), state, prevDiffInfo, curDiffInfo, utf8fileApply)
+				if err != nil {
+					return err
+				}
+				// reset
+				prevDiffInfo = nil
+				curDiffInfo = nil
+			case "symlink":
+				err = applySymlink(j, lines[0], state, prevDiffInfo, curDiffInfo)
+				if err != nil {
+					return err
+				}
+				// reset
+				prevDiffInfo = nil
+				curDiffInfo = nil
+			case "bindelta":
+				buf := strings.Join(lines, "")
+				err = apply(j, []byte(buf), state, prevDiffInfo, curDiffInfo, bindeltaApply)
 				if err != nil {
 					return err
 				}
@@ -448,5 +659,7 @@ func Apply(dir string, r io.Reader, excludePaths []string) error {
 	if state != terminal {
 		return ErrPrematurePatchfileEnd
 	}
+	j.startHash = startHash
+	j.endHash = endHash
 	return nil
 }
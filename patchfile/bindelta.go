@@ -0,0 +1,57 @@
+package patchfile
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/frankbraun/codechain/util/ascii85"
+	"github.com/frankbraun/codechain/util/bindelta"
+)
+
+// bindeltaDiff calculates a binary delta between fileA and fileB and writes
+// it to w as a "bindelta" section, if the delta is smaller than fileB.
+//
+// If bindeltaDiff wrote a section, it returns true. Otherwise, it returns
+// false and the caller should fall back to an "ascii85" section.
+func bindeltaDiff(w io.Writer, fileA, fileB string) (bool, error) {
+	a, err := ioutil.ReadFile(fileA)
+	if err != nil {
+		return false, err
+	}
+	b, err := ioutil.ReadFile(fileB)
+	if err != nil {
+		return false, err
+	}
+	delta := bindelta.Encode(a, b)
+	if len(delta) >= len(b) {
+		return false, nil
+	}
+	buf, lines, err := ascii85.Encode(delta)
+	if err != nil {
+		return false, err
+	}
+	fmt.Fprintf(w, "bindelta %d\n", lines)
+	if _, err := w.Write(buf); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// bindeltaApply decodes the ascii85 encoded binary delta in patch, applies it
+// to text, and writes the result to w. patch must not include the "bindelta"
+// section header.
+func bindeltaApply(w io.Writer, text string, patch []byte) error {
+	delta, err := ascii85.Decode(patch)
+	if err != nil {
+		return err
+	}
+	buf, err := bindelta.Decode([]byte(text), delta)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package patchfile
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Kinds of file changes described by a patchfile.
+const (
+	ChangeAdded      = "added"       // file has been added
+	ChangeModified   = "modified"    // file content (and maybe mode) changed
+	ChangeMode       = "mode"        // only the file mode changed
+	ChangeDeleted    = "deleted"     // file has been deleted
+	ChangeMovedTo    = "moved to"    // file has been moved to Other
+	ChangeMovedFrom  = "moved from"  // file has been moved from Other
+	ChangeCopiedFrom = "copied from" // file has been copied from Other
+)
+
+// Change describes how a single file is changed by a patchfile.
+type Change struct {
+	Kind     string   // one of the Change* constants
+	Filename string   // canonical filename
+	Mode     rune     // file mode after change ('f', 'x', or 'l'), 0 if removed
+	Hash     [32]byte // file hash after change, zero if removed
+	Other    string   // other filename for moves and copies
+}
+
+// fileLine is a parsed file diff line.
+type fileLine struct {
+	op   byte
+	mode rune
+	hash [32]byte
+	name string
+}
+
+func parseChangeLine(line string, version int) (*fileLine, error) {
+	op, _, hash, name, err := parseFileLine(line, version)
+	if err != nil {
+		return nil, err
+	}
+	fl := &fileLine{op: op, mode: rune(line[2]), name: name}
+	copy(fl.hash[:], hash)
+	return fl, nil
+}
+
+// Changes reads the patchfile from r and returns the file changes it
+// describes, in order. Only the file diff lines are parsed, the patches
+// themselves are skipped. Neither the tree hashes nor the patches are
+// verified, use Apply for that.
+func Changes(r io.Reader) ([]Change, error) {
+	var changes []Change
+	s := bufio.NewScanner(r)
+	buf := make([]byte, bufio.MaxScanTokenSize)
+	s.Buffer(buf, 64*1024*1024) // 64MB, entire files can be encoded as single lines
+	s.Split(scanNewlines)
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrPrematurePatchfileEnd
+	}
+	_, version, err := procStart(s.Text())
+	if err != nil {
+		return nil, err
+	}
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrPrematurePatchfileEnd
+	}
+	fields := strings.SplitN(s.Text(), " ", 2)
+	if len(fields) != 2 {
+		return nil, ErrTreeHashFieldsNum
+	}
+	if fields[0] != "treehash" {
+		return nil, ErrTreeHashFieldsText
+	}
+	// pending '-', 'm', or 'c' line, which might be followed by a '+' line
+	var prev *fileLine
+	flush := func() error {
+		if prev == nil {
+			return nil
+		}
+		if prev.op != '-' {
+			return ErrMoveTargetMissing
+		}
+		changes = append(changes, Change{Kind: ChangeDeleted, Filename: prev.name})
+		prev = nil
+		return nil
+	}
+	for s.Scan() {
+		line := s.Text()
+		fields := strings.SplitN(line, " ", 2)
+		switch fields[0] {
+		case "treehash":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			if s.Scan() {
+				return nil, ErrNotTerminal
+			}
+			if err := s.Err(); err != nil {
+				return nil, err
+			}
+			return changes, nil
+		case "ascii85", "dmppatch", "utf8file", "bindelta", "symlink":
+			// skip patch
+			if len(fields) != 2 {
+				return nil, ErrDiffLinesParse
+			}
+			numLines, err := strconv.Atoi(fields[1])
+			if err != nil || numLines < 0 {
+				return nil, ErrDiffLinesParse
+			}
+			for i := 0; i < numLines; i++ {
+				if !s.Scan() {
+					if err := s.Err(); err != nil {
+						return nil, err
+					}
+					return nil, ErrPrematureDiffEnd
+				}
+			}
+			continue
+		}
+		cur, err := parseChangeLine(line, version)
+		if err != nil {
+			return nil, err
+		}
+		if cur.op != '+' {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			prev = cur
+			continue
+		}
+		added := Change{
+			Kind:     ChangeAdded,
+			Filename: cur.name,
+			Mode:     cur.mode,
+			Hash:     cur.hash,
+		}
+		switch {
+		case prev == nil:
+		case prev.op == 'm':
+			changes = append(changes, Change{
+				Kind:     ChangeMovedTo,
+				Filename: prev.name,
+				Other:    cur.name,
+			})
+			added.Kind = ChangeMovedFrom
+			added.Other = prev.name
+		case prev.op == 'c':
+			added.Kind = ChangeCopiedFrom
+			added.Other = prev.name
+		case prev.name != cur.name:
+			changes = append(changes, Change{Kind: ChangeDeleted, Filename: prev.name})
+		case prev.hash == cur.hash:
+			added.Kind = ChangeMode
+		default:
+			added.Kind = ChangeModified
+		}
+		changes = append(changes, added)
+		prev = nil
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ErrPrematurePatchfileEnd
+}
@@ -0,0 +1,80 @@
+package patchfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "patchfile_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	hello, err := ioutil.ReadFile(filepath.Join("testdata", "hello", "hello.go"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() failed: %v", err)
+	}
+	a := filepath.Join(tmpdir, "a")
+	b := filepath.Join(tmpdir, "b")
+	files := []struct {
+		dir     string
+		name    string
+		content []byte
+		perm    os.FileMode
+	}{
+		{a, "deleted.txt", []byte("deleted\n"), 0644},
+		{a, "hello.go", hello, 0644},
+		{a, "mode.sh", []byte("mode\n"), 0644},
+		{a, "modified.txt", []byte("old\n"), 0644},
+		{b, "added.txt", []byte("added\n"), 0644},
+		{b, "cmd/hello.go", hello, 0644},
+		{b, "copy.sh", []byte("mode\n"), 0755},
+		{b, "mode.sh", []byte("mode\n"), 0755},
+		{b, "modified.txt", []byte("new\n"), 0644},
+	}
+	for _, f := range files {
+		fn := filepath.Join(f.dir, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			t.Fatalf("os.MkdirAll() failed: %v", err)
+		}
+		if err := ioutil.WriteFile(fn, f.content, f.perm); err != nil {
+			t.Fatalf("ioutil.WriteFile() failed: %v", err)
+		}
+	}
+	var patch bytes.Buffer
+	if err := Diff(Version, &patch, a, b, nil); err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+	changes, err := Changes(bytes.NewReader(patch.Bytes()))
+	if err != nil {
+		t.Fatalf("Changes() failed: %v", err)
+	}
+	kinds := map[string]string{
+		"added.txt":    ChangeAdded,
+		"cmd/hello.go": ChangeMovedFrom,
+		"copy.sh":      ChangeCopiedFrom,
+		"deleted.txt":  ChangeDeleted,
+		"hello.go":     ChangeMovedTo,
+		"mode.sh":      ChangeMode,
+		"modified.txt": ChangeModified,
+	}
+	if len(changes) != len(kinds) {
+		t.Fatalf("wrong number of changes: %d != %d\n%s", len(changes), len(kinds), patch.String())
+	}
+	for _, c := range changes {
+		if c.Kind != kinds[c.Filename] {
+			t.Errorf("%s: wrong kind of change %q (should be %q)", c.Filename, c.Kind, kinds[c.Filename])
+		}
+	}
+
+	// truncated patchfile
+	truncated := strings.Join(strings.Split(patch.String(), "\n")[:4], "\n")
+	if _, err := Changes(strings.NewReader(truncated)); err == nil {
+		t.Error("Changes() should fail on truncated patchfile")
+	}
+}
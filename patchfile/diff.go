@@ -21,12 +21,23 @@ func writeFileDeletion(w io.Writer, entry tree.ListEntry) {
 // addition to w.
 //
 // It determines if the file in entry is binary or UTF-8 and encodes it
-// accordingly as an "ascii85" or "dmppatch" patch.
+// accordingly as an "ascii85" or "dmppatch" patch. Symlinks are encoded as
+// "symlink" sections.
 func writeFileAddition(version int, w io.Writer, dir string, entry tree.ListEntry) error {
 	// file addition
 	fmt.Fprintf(w, "+ %c %x %s\n", entry.Mode, entry.Hash, entry.Filename)
+	return writeFileContent(version, w, dir, entry)
+}
+
+// writeFileContent writes the entire content of the tree list entry (in root
+// dir) to w.
+func writeFileContent(version int, w io.Writer, dir string, entry tree.ListEntry) error {
 	// filename regarding the root dir
 	filename := filepath.Join(dir, entry.Filename)
+	// symlinks are encoded as "symlink" sections
+	if entry.Mode == 'l' {
+		return symlinkDiff(w, filename)
+	}
 	// check if the file is binary
 	isBinary, err := file.IsBinary(filename)
 	if err != nil {
@@ -57,6 +68,22 @@ func writeFileAddition(version int, w io.Writer, dir string, entry tree.ListEntr
 	return nil
 }
 
+// writeFileAdditionOrMove writes the tree list entry (in root dir) as a file
+// move or copy to w, if it is contained in moves. Otherwise, it writes it as a
+// file addition.
+func writeFileAdditionOrMove(
+	version int,
+	w io.Writer,
+	dir string,
+	entry tree.ListEntry,
+	moves map[string]*move,
+) error {
+	if mv, ok := moves[entry.Filename]; ok {
+		return writeMove(w, entry, mv)
+	}
+	return writeFileAddition(version, w, dir, entry)
+}
+
 // writeFileDiff writes the diff between the tree list entryA (in directory a)
 // and tree list entryB (in directory b) as a file diff to w.
 //
@@ -65,7 +92,7 @@ func writeFileAddition(version int, w io.Writer, dir string, entry tree.ListEntr
 //
 // If the file hashes differ, the function determines if either of the files
 // is binary or both are UTF-8 and encodes the diff accordingly as an
-// "ascii85" or "dmppatch" patch.
+// "ascii85" (or "bindelta") or "dmppatch" patch.
 func writeFileDiff(version int, w io.Writer, a, b string, entryA, entryB tree.ListEntry) error {
 	// Assert that file diffs are only used if the file names are the same.
 	if !(entryA.Filename == entryB.Filename) {
@@ -77,6 +104,11 @@ func writeFileDiff(version int, w io.Writer, a, b string, entryA, entryB tree.Li
 		fmt.Fprintf(w, "- %c %x %s\n", entryA.Mode, entryA.Hash, entryA.Filename)
 		fmt.Fprintf(w, "+ %c %x %s\n", entryB.Mode, entryB.Hash, entryB.Filename)
 	}
+	// Changes from and to symlinks always replace the entire content.
+	if (entryA.Mode == 'l' || entryB.Mode == 'l') &&
+		(!bytes.Equal(entryA.Hash[:], entryB.Hash[:]) || entryA.Mode != entryB.Mode) {
+		return writeFileContent(version, w, b, entryB)
+	}
 	// Write actual patch, if the file hash changed.
 	if !bytes.Equal(entryA.Hash[:], entryB.Hash[:]) {
 		// Check if either of the files is binary.
@@ -91,6 +123,16 @@ func writeFileDiff(version int, w io.Writer, a, b string, entryA, entryB tree.Li
 			return err
 		}
 		if isBinaryA || isBinaryB {
+			// write "bindelta" patch, if possible and smaller
+			if version > 3 {
+				written, err := bindeltaDiff(w, filenameA, filenameB)
+				if err != nil {
+					return err
+				}
+				if written {
+					return nil
+				}
+			}
 			// write "ascii85" encoding
 			err := ascii85Diff(w, filenameB)
 			if err != nil {
@@ -117,13 +159,23 @@ func writeFileDiff(version int, w io.Writer, a, b string, entryA, entryB tree.Li
 	return nil
 }
 
+// containsSymlink returns true, if the tree list contains a symlink.
+func containsSymlink(list []tree.ListEntry) bool {
+	for _, entry := range list {
+		if entry.Mode == 'l' {
+			return true
+		}
+	}
+	return false
+}
+
 // Diff computes a patch between the directory trees rooted at a and b and
 // writes it to w. If a and b have the same tree hash ErrNoDifference is
 // returned. In case of error, some data might have been written to w already.
 // The paths given in excludePaths are excluded from all tree hash calculations.
 func Diff(version int, w io.Writer, a, b string, excludePaths []string) error {
-	// only support version 1 and 2
-	if version != 1 && version != 2 {
+	// only support version 1 to 5
+	if version < 1 || version > 5 {
 		return ErrHeaderVersion
 	}
 	// Calculate tree list of "source" directory tree.
@@ -136,12 +188,27 @@ func Diff(version int, w io.Writer, a, b string, excludePaths []string) error {
 	if err != nil {
 		return err
 	}
+	// Symlinks are only supported since version 5.
+	if version < 5 && (containsSymlink(listA) || containsSymlink(listB)) {
+		return ErrSymlinkVersion
+	}
 	// Hash directories trees and compare them.
 	hashA := tree.HashList(listA)
 	hashB := tree.HashList(listB)
 	if bytes.Equal(hashA[:], hashB[:]) {
 		return ErrNoDifference
 	}
+	// Find file moves and copies (version 3 and higher).
+	var (
+		moves   map[string]*move
+		sources map[string]bool
+	)
+	if version > 2 {
+		moves, sources, err = findMoves(version, a, b, listA, listB)
+		if err != nil {
+			return err
+		}
+	}
 	// version line
 	fmt.Fprintf(w, "codechain patchfile version %d\n", version)
 	// initial treehash line
@@ -157,11 +224,13 @@ func Diff(version int, w io.Writer, a, b string, excludePaths []string) error {
 				return err
 			}
 		} else if entryA.Filename < entryB.Filename {
-			writeFileDeletion(w, entryA)
+			if !sources[entryA.Filename] {
+				writeFileDeletion(w, entryA)
+			}
 			idxA++
 			continue
 		} else { // entryA.Filename > entryB.Filename
-			if err := writeFileAddition(version, w, b, entryB); err != nil {
+			if err := writeFileAdditionOrMove(version, w, b, entryB, moves); err != nil {
 				return err
 			}
 			idxB++
@@ -171,11 +240,13 @@ func Diff(version int, w io.Writer, a, b string, excludePaths []string) error {
 		idxB++
 	}
 	for idxA < len(listA) {
-		writeFileDeletion(w, listA[idxA])
+		if !sources[listA[idxA].Filename] {
+			writeFileDeletion(w, listA[idxA])
+		}
 		idxA++
 	}
 	for idxB < len(listB) {
-		if err := writeFileAddition(version, w, b, listB[idxB]); err != nil {
+		if err := writeFileAdditionOrMove(version, w, b, listB[idxB], moves); err != nil {
 			return err
 		}
 		idxB++
@@ -52,6 +52,29 @@ The number after "ascii85" denotes the number of lines following containing
 the actual binary encoding. "ascii85" patches are not real patches, but always
 encode the entire binary file.
 
+Since version 4 file diffs of binary files can be encoded as "bindelta"
+patches (if they are smaller than the corresponding "ascii85" encoding):
+
+  bindelta 1
+  5X5>M-ia;J!@\f
+
+The number after "bindelta" denotes the number of lines following containing
+the ascii85 encoding of a binary delta (see util/bindelta package) which
+transforms the previous version of the file into the new one. "bindelta"
+patches must not follow file additions.
+
+Since version 5 symlinks are supported (see tree package). They have mode 'l'
+and their content is encoded as "symlink" section containing exactly one line
+with the link target (example):
+
+  + l 69e077b3eb0028e955189bc80f1ac923f362a9e789bff895e34f11543616f8b7 sub/link
+  symlink 1
+  ../foo/bar.txt
+
+Symlinks are never moved or copied, changes from and to symlinks always encode
+the entire new content. Link targets must not escape the directory tree and
+files are never written through symlinks (see tree.CheckSymlink).
+
 A file diff is encoded as follows (example):
 
   - f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
@@ -63,7 +86,19 @@ is, if just the file mode changed and the file hash stayed the same no patch
 must follow.
 
 File diffs are only used if the file names ("hello.go" in the example above) are
-the same. File moves are implemented as a file deletion and a file addition.
+the same. Before version 3, file moves are implemented as a file deletion and a
+file addition.
+
+Since version 3 file moves and copies can be encoded explicitly (example):
+
+  m f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
+  + f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hellomove.go
+
+The 'm' denotes a move and a 'c' a copy of the source file given in the first
+line, which must exist with the given hash. The '+' line gives the target file,
+which must not exist. If the file hashes differ, a "dmppatch" which transforms
+the source into the target file must follow (as with file diffs). The source of
+a move is not deleted separately.
 
 The last line in a patchfile must be the tree hash of the directory tree after
 the patchfile has been applied (example):
@@ -94,8 +129,10 @@ implement:
   2. Compare the file names NAME_A and NAME_B (lexicographically) of the first
      two entries in  LIST_A and LIST_B:
 
-     - If NAME_A < NAME_B: File delete NAME_A, remove it from LIST_A, goto 2.
-     - If NAME_A > NAME_B: File add NAME_B, remove it from LIST_B, goto 2.
+     - If NAME_A < NAME_B: File delete NAME_A (unless it is a move source),
+       remove it from LIST_A, goto 2.
+     - If NAME_A > NAME_B: File add NAME_B (or move/copy it, see below),
+       remove it from LIST_B, goto 2.
      - If NAME_A == NAME_B:
        - If file mode or file hash of files NAME_A and NAME_B differ: file diff.
        - Remove NAME_A from LIST_A, NAME_B from LIST_B, and goto 2.
@@ -106,6 +143,16 @@ implement:
   4. If LIST_B still contains entries while LIST_A is empty, add file additions
      for all entries in LIST_B.
 
+For version 3 moves and copies are determined before step 2:
+
+  - A file deleted from LIST_A and added to LIST_B with the same hash is a move
+    (unless LIST_B contains files in a directory with the name of the source).
+  - A file added to LIST_B with the same hash as a file contained unchanged in
+    LIST_A and LIST_B is a copy.
+  - A file deleted from LIST_A and added to LIST_B with the same base name is a
+    move, if both are UTF-8 files and a clean "dmppatch" smaller than half
+    the size of the target file exists.
+
 
 Apply function specification
 
@@ -129,11 +176,31 @@ To apply a patchfile PATCH to a directory DIR we use the following algorithm:
            - Otherwise (hashes differ): Apply the following patch, which must be
              either ascii85 or dmppatch (and adjust mode, if necessary).
        - Otherwise: Delete file.
+     - If it starts with 'm' or 'c' (version 3): Make sure the source file
+       exists with the given hash and that the next line starts with '+' and
+       the target file doesn't exist.
+       - If the hashes are the same: Move or copy file (and adjust mode).
+       - Otherwise: Apply the following patch to the source file, write the
+         result to the target file, and delete the source file for moves.
      - If it starts with 'treehash': Goto 4.
      - Goto 3.
 
+  The hash of every file written by a patch must match the hash given in the
+  preceding '+' line.
+
   4. Read the last line of PATCH, make sure it is a treehash, and compare it
      with the treehash of DIR (after all patches have been applied).
 
+If any of the steps fails, all changes made to DIR are rolled back. To make
+this possible, the original state of every file is recorded in a journal
+before it is changed (see Journal).
+
+Patchfiles cannot be applied backwards directly, because file deletions and
+most file changes do not contain the previous content. However, the journal
+contains the previous state of all files changed by a patch. Right after a
+patch has been applied, it can therefore write a reverse patch, which is a
+normal patchfile leading from the final tree hash of the patch back to its
+start tree hash (see Journal.WriteReverse).
+
 */
 package patchfile
@@ -38,11 +38,13 @@ var ErrFileHashMismatchAfter = errors.New("patchfile: file hash does not match a
 // ErrFileFieldsNum is returned if a file diff line does not have 4 space separated fields.
 var ErrFileFieldsNum = errors.New("patchfile: file diff line does not have 4 space separated fields")
 
-// ErrFileField0 is returned if the file diff line does not start with '-' or '+'.
-var ErrFileField0 = errors.New("patchfile: file diff line does not start with '-' or '+'")
+// ErrFileField0 is returned if the file diff line does not start with '-' or '+'
+// (or 'm' or 'c' for version 3).
+var ErrFileField0 = errors.New("patchfile: file diff line does not start with '-', '+', 'm', or 'c'")
 
-// ErrFileField1 is returned if the file diff line does have mode 'f' or 'x'.
-var ErrFileField1 = errors.New("patchfile: file diff line does not have mode 'f' or 'x'")
+// ErrFileField1 is returned if the file diff line does have mode 'f' or 'x'
+// (or 'l' for version 5).
+var ErrFileField1 = errors.New("patchfile: file diff line does not have mode 'f', 'x', or 'l'")
 
 // ErrAddTargetFileExists is returned if an add target file exists already.
 var ErrAddTargetFileExists = errors.New("patchfile: add target file exists already")
@@ -50,6 +52,9 @@ var ErrAddTargetFileExists = errors.New("patchfile: add target file exists alrea
 // ErrMoveTargetFileExists is returned if a move target file exists already.
 var ErrMoveTargetFileExists = errors.New("patchfile: move target file exists already")
 
+// ErrMoveTargetMissing is returned if a move or copy line is not followed by a '+' line.
+var ErrMoveTargetMissing = errors.New("patchfile: move or copy line not followed by '+' line")
+
 // ErrDiffLinesParse is returned if the number of diff lines cannot be parsed.
 var ErrDiffLinesParse = errors.New("patchfile: cannot parse number of diff lines")
 
@@ -62,6 +67,34 @@ var ErrDiffLinesNegative = errors.New("patchfile: number of lines < 0")
 // ErrDiffModeUnknown if returned if the diff mode is unknown.
 var ErrDiffModeUnknown = errors.New("patchfile: unknown diff modes")
 
+// ErrDeltaWithoutSource is returned if a "bindelta" section follows a file addition.
+var ErrDeltaWithoutSource = errors.New("patchfile: bindelta without source file")
+
+// ErrSymlinkMode is returned if the symlink mode 'l' and "symlink" sections
+// are not used together or a symlink is moved or copied.
+var ErrSymlinkMode = errors.New("patchfile: invalid use of symlink mode")
+
+// ErrSymlinkLines is returned if a "symlink" section does not have exactly one line.
+var ErrSymlinkLines = errors.New("patchfile: symlink section does not have exactly one line")
+
+// ErrNotRegularFile is returned if a file with mode 'f' or 'x' in a patchfile
+// is not a regular file in the directory tree (e.g., a symlink).
+var ErrNotRegularFile = errors.New("patchfile: file is not a regular file")
+
+// ErrSymlinkPath is returned if a file in a patchfile has a symlink as parent directory.
+var ErrSymlinkPath = errors.New("patchfile: file path contains symlink")
+
+// ErrSymlinkVersion is returned if Diff is called for directory trees
+// containing symlinks with a patchfile version < 5.
+var ErrSymlinkVersion = errors.New("patchfile: symlinks require patchfile version 5")
+
+// ErrInterrupted is returned if applying a patchfile has been interrupted.
+var ErrInterrupted = errors.New("patchfile: interrupted")
+
+// ErrNoReverse is returned if a reverse patch is requested from a journal
+// without a successfully applied patch.
+var ErrNoReverse = errors.New("patchfile: no applied patch to reverse")
+
 // ErrNotTerminal is returned if more input is read after terminal state.
 var ErrNotTerminal = errors.New("patchfile: more input read after terminal state")
 
@@ -0,0 +1,321 @@
+package patchfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/frankbraun/codechain/util"
+	"github.com/frankbraun/codechain/util/file"
+	"github.com/frankbraun/codechain/util/interrupt"
+	"github.com/frankbraun/codechain/util/log"
+)
+
+// Journal records all changes made to a directory tree while applying
+// patchfiles, so that they can be rolled back. Before a file is changed or
+// removed for the first time, a backup of it is stored in a temporary
+// directory. Created files and directories are recorded and removed during a
+// rollback.
+//
+// The changes of every Apply call are recorded separately, which allows to
+// write a reverse patch for the last one (see WriteReverse).
+type Journal struct {
+	dir       string          // directory tree the journal is for
+	backupDir string          // temporary directory containing backups
+	entries   []journalEntry  // changes in the order they happened
+	recorded  map[string]bool // paths which have been recorded in this step
+	step      int             // index of first entry of the current step
+	stepDir   string          // backup directory of the current step
+	startHash string          // start tree hash of the last applied patch
+	endHash   string          // final tree hash of the last applied patch
+	sigs      chan struct{}   // interrupts (see Run)
+	interrupt bool            // interrupt received
+}
+
+// journalEntry describes the state of a path before it was changed.
+type journalEntry struct {
+	path   string      // path which has been changed
+	backup string      // backup of regular file, if any
+	link   string      // previous target, if path was a symlink
+	mode   os.FileMode // previous file mode, if path existed
+	exists bool        // path existed
+	isDir  bool        // path is a created directory
+	remDir bool        // path is a removed directory
+}
+
+// NewJournal returns a new journal for the directory tree rooted at dir.
+func NewJournal(dir string) (*Journal, error) {
+	backupDir, err := ioutil.TempDir("", "patchfile_journal")
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{
+		dir:       dir,
+		backupDir: backupDir,
+		recorded:  make(map[string]bool),
+	}, nil
+}
+
+// begin starts a new step, which records the changes of a single Apply call.
+func (j *Journal) begin() {
+	j.step = len(j.entries)
+	j.stepDir = filepath.Join(j.backupDir, strconv.Itoa(j.step))
+	j.recorded = make(map[string]bool)
+	j.startHash = ""
+	j.endHash = ""
+}
+
+// record the state of path before it is changed for the first time in the
+// current step. Backups are stored under the same relative path in the backup
+// directory of the step.
+func (j *Journal) record(path string) error {
+	if j.recorded[path] {
+		return nil
+	}
+	entry := journalEntry{path: path}
+	fi, err := os.Lstat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		entry.exists = true
+		entry.mode = fi.Mode() & os.ModePerm
+		rel, err := filepath.Rel(j.dir, path)
+		if err != nil {
+			return err
+		}
+		backup := filepath.Join(j.stepDir, rel)
+		if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			entry.link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(entry.link, backup); err != nil {
+				return err
+			}
+		} else {
+			entry.backup = backup
+			if err := file.Copy(path, entry.backup); err != nil {
+				return err
+			}
+		}
+	}
+	j.recorded[path] = true
+	j.entries = append(j.entries, entry)
+	return nil
+}
+
+// mkdirAll records all directories of path which do not exist yet and
+// creates them.
+func (j *Journal) mkdirAll(path string) error {
+	var dirs []string
+	for p := path; ; p = filepath.Dir(p) {
+		_, err := os.Lstat(p)
+		if err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		dirs = append(dirs, p)
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Mkdir(dirs[i], 0755); err != nil {
+			return err
+		}
+		j.recorded[dirs[i]] = true
+		j.entries = append(j.entries, journalEntry{path: dirs[i], isDir: true})
+	}
+	return nil
+}
+
+// remove records path and removes it.
+func (j *Journal) remove(path string) error {
+	if err := j.record(path); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// RemoveAll records all contents of the directory tree of journal j, except
+// for the paths in excludePaths, and removes them. The root directory itself
+// and directories which contain excluded paths are kept. Like all other
+// changes, the removal is undone by a rollback.
+func (j *Journal) RemoveAll(excludePaths []string) error {
+	j.begin()
+	var paths []string
+	err := filepath.Walk(j.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == j.dir {
+			return nil
+		}
+		rel, err := filepath.Rel(j.dir, path)
+		if err != nil {
+			return err
+		}
+		if util.ContainsString(excludePaths, filepath.ToSlash(rel)) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// remove in reverse order, so directories are empty when they are removed
+	for i := len(paths) - 1; i >= 0; i-- {
+		path := paths[i]
+		fi, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			if err := j.remove(path); err != nil {
+				return err
+			}
+			continue
+		}
+		entries, err := file.List(path)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			continue // contains excluded paths
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		j.entries = append(j.entries, journalEntry{
+			path:   path,
+			mode:   fi.Mode() & os.ModePerm,
+			exists: true,
+			remDir: true,
+		})
+	}
+	return nil
+}
+
+// Rollback restores the directory tree of journal j to the state before the
+// first change and removes the backups. The first error encountered is
+// returned, but Rollback tries to restore as much as possible.
+func (j *Journal) Rollback() error {
+	log.Println("patchfile: rollback")
+	var rerr error
+	setErr := func(err error) {
+		if err != nil && rerr == nil {
+			rerr = err
+		}
+	}
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		e := j.entries[i]
+		if e.isDir {
+			setErr(os.Remove(e.path))
+			continue
+		}
+		if e.remDir {
+			if err := os.Mkdir(e.path, e.mode); err != nil && !os.IsExist(err) {
+				setErr(err)
+				continue
+			}
+			setErr(os.Chmod(e.path, e.mode))
+			continue
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			setErr(err)
+			continue
+		}
+		if !e.exists {
+			continue
+		}
+		if e.backup == "" {
+			setErr(os.Symlink(e.link, e.path))
+			continue
+		}
+		if err := file.Copy(e.backup, e.path); err != nil {
+			setErr(err)
+			continue
+		}
+		setErr(os.Chmod(e.path, e.mode))
+	}
+	j.entries = nil
+	j.recorded = make(map[string]bool)
+	j.step = 0
+	j.startHash = ""
+	j.endHash = ""
+	setErr(os.RemoveAll(j.backupDir))
+	return rerr
+}
+
+// Commit keeps all changes recorded in journal j and removes the backups.
+func (j *Journal) Commit() error {
+	j.entries = nil
+	j.recorded = make(map[string]bool)
+	j.step = 0
+	j.startHash = ""
+	j.endHash = ""
+	return os.RemoveAll(j.backupDir)
+}
+
+// interrupted returns true, if an interrupt has been received during Run.
+func (j *Journal) interrupted() bool {
+	if j.sigs == nil {
+		return false
+	}
+	select {
+	case <-j.sigs:
+		j.interrupt = true
+	default:
+	}
+	return j.interrupt
+}
+
+// Run calls f and commits journal j afterwards, if f succeeds. Otherwise, j
+// is rolled back and the error returned by f is returned.
+//
+// While f runs, interrupts (SIGINT) are caught with an interrupt handler (see
+// util/interrupt). They cause Apply calls with j to abort with
+// ErrInterrupted, which leads to a rollback. The interrupt handler only
+// returns after the rollback has finished, so the shutdown of commands which
+// wait on interrupt.ShutdownChannel cannot interrupt the rollback.
+// Changes are not protected against crashes of the whole system.
+func (j *Journal) Run(f func() error) error {
+	sigs := make(chan struct{}, 1)
+	done := make(chan struct{})
+	interrupt.AddInterruptHandler(func() {
+		select {
+		case sigs <- struct{}{}:
+		default:
+		}
+		<-done // wait for commit or rollback
+	})
+	j.sigs = sigs
+	defer func() {
+		j.sigs = nil
+		j.interrupt = false
+		close(done)
+	}()
+	err := f()
+	if err == nil && j.interrupted() {
+		err = ErrInterrupted
+	}
+	if err != nil {
+		if rerr := j.Rollback(); rerr != nil {
+			return fmt.Errorf("%v (rollback failed: %v)", err, rerr)
+		}
+		return err
+	}
+	return j.Commit()
+}
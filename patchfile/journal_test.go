@@ -0,0 +1,75 @@
+package patchfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frankbraun/codechain/tree"
+	"github.com/frankbraun/codechain/util/file"
+	"github.com/frankbraun/codechain/util/hex"
+)
+
+func TestRollback(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "patchfile_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	dir := filepath.Join(tmpdir, "hello")
+	if err := file.CopyDir(filepath.Join("testdata", "hello"), dir); err != nil {
+		t.Fatalf("file.CopyDir() failed: %v", err)
+	}
+	if err := os.Symlink("hello.go", filepath.Join(dir, "link")); err != nil {
+		t.Fatalf("os.Symlink() failed: %v", err)
+	}
+	before, err := tree.ListBytes(dir, nil)
+	if err != nil {
+		t.Fatalf("tree.ListBytes() failed: %v", err)
+	}
+	h, err := tree.Hash(dir, nil)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+
+	linkHash, err := tree.SymlinkHash(filepath.Join(dir, "link"))
+	if err != nil {
+		t.Fatalf("tree.SymlinkHash() failed: %v", err)
+	}
+
+	// The patch changes the mode of hello.go, removes the symlink, adds a
+	// file in a new directory, and then fails while adding another file.
+	patch := `codechain patchfile version 5
+treehash ` + hex.Encode(h[:]) + `
+- f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
++ x ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
+- l ` + hex.Encode(linkHash[:]) + ` link
++ f 2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881 new/dir/x.txt
+utf8file 1
+x
++ f 2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881 y.txt
+utf8file 1
+y
+treehash e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
+`
+	err = Apply(dir, bytes.NewBufferString(patch), nil)
+	if err != ErrFileHashMismatchAfter {
+		t.Fatalf("Apply() should fail with ErrFileHashMismatchAfter (has %v)", err)
+	}
+	after, err := tree.ListBytes(dir, nil)
+	if err != nil {
+		t.Fatalf("tree.ListBytes() failed: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Errorf("tree differs after rollback:\n%s\n%s", before, after)
+	}
+	exists, err := file.Exists(filepath.Join(dir, "new"))
+	if err != nil {
+		t.Fatalf("file.Exists() failed: %v", err)
+	}
+	if exists {
+		t.Error("created directory not removed during rollback")
+	}
+}
@@ -0,0 +1,177 @@
+package patchfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/frankbraun/codechain/tree"
+	"github.com/frankbraun/codechain/util/file"
+)
+
+// move describes a file move or copy from src to the target entry it is
+// stored for.
+type move struct {
+	op    byte           // 'm' (move) or 'c' (copy)
+	src   tree.ListEntry // source entry in directory tree a
+	patch []byte         // "dmppatch" or "bindelta" section, if src and target differ
+}
+
+// movable returns true, if src can be moved in directory tree b.
+// Because move sources are only removed when the move target is processed,
+// they must not become a directory in b.
+func movable(src string, listB []tree.ListEntry) bool {
+	prefix := src + "/"
+	for _, entry := range listB {
+		if strings.HasPrefix(entry.Filename, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// similarDiff tries to compute a "dmppatch" section between the similar
+// files src (in directory a) and dst (in directory b). If either of the files
+// is binary, a "bindelta" section is computed instead (version 4 and higher).
+// It returns nil, if no patch can be computed or the patch is not smaller than
+// half the size of dst.
+func similarDiff(version int, a, b, src, dst string) ([]byte, error) {
+	filenameA := filepath.Join(a, src)
+	filenameB := filepath.Join(b, dst)
+	isBinaryA, err := file.IsBinary(filenameA)
+	if err != nil {
+		return nil, err
+	}
+	isBinaryB, err := file.IsBinary(filenameB)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(filenameB)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		patch bytes.Buffer
+		clean bool
+	)
+	if isBinaryA || isBinaryB {
+		if version < 4 {
+			return nil, nil
+		}
+		clean, err = bindeltaDiff(&patch, filenameA, filenameB)
+	} else {
+		clean, err = dmpDiff(&patch, filenameA, filenameB)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !clean || int64(patch.Len()) >= fi.Size()/2 {
+		return nil, nil
+	}
+	return patch.Bytes(), nil
+}
+
+// findMoves finds file moves and copies between the tree lists listA (of
+// directory tree a) and listB (of directory tree b).
+//
+// Files which are deleted in a and added in b with the same hash are moves.
+// Files which are added in b with the same hash as an unchanged file are
+// copies. Files which are deleted in a and added in b with the same base name
+// and a small diff (see similarDiff) are also moves.
+//
+// Symlinks are never moved or copied.
+//
+// findMoves returns the moves and copies indexed by target filename and the
+// set of move sources, for which no file deletion must be written.
+func findMoves(
+	version int,
+	a, b string,
+	listA, listB []tree.ListEntry,
+) (map[string]*move, map[string]bool, error) {
+	namesA := make(map[string]tree.ListEntry)
+	for _, entry := range listA {
+		namesA[entry.Filename] = entry
+	}
+	namesB := make(map[string]bool)
+	for _, entry := range listB {
+		namesB[entry.Filename] = true
+	}
+	// collect deleted and unchanged files in a
+	var deleted []tree.ListEntry
+	unchanged := make(map[[32]byte]tree.ListEntry)
+	for _, entry := range listB {
+		entryA, ok := namesA[entry.Filename]
+		if ok && entryA.Hash == entry.Hash && entryA.Mode != 'l' && entry.Mode != 'l' {
+			if _, ok := unchanged[entry.Hash]; !ok {
+				unchanged[entry.Hash] = entryA
+			}
+		}
+	}
+	for _, entry := range listA {
+		if !namesB[entry.Filename] && entry.Mode != 'l' && movable(entry.Filename, listB) {
+			deleted = append(deleted, entry)
+		}
+	}
+	moves := make(map[string]*move)
+	sources := make(map[string]bool)
+	var added []tree.ListEntry
+	// exact matches
+	for _, entry := range listB {
+		if _, ok := namesA[entry.Filename]; ok || entry.Mode == 'l' {
+			continue
+		}
+		found := false
+		for _, src := range deleted {
+			if !sources[src.Filename] && src.Hash == entry.Hash {
+				moves[entry.Filename] = &move{op: 'm', src: src}
+				sources[src.Filename] = true
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		if src, ok := unchanged[entry.Hash]; ok {
+			moves[entry.Filename] = &move{op: 'c', src: src}
+			continue
+		}
+		added = append(added, entry)
+	}
+	// similar files with the same base name
+	for _, entry := range added {
+		for _, src := range deleted {
+			if sources[src.Filename] ||
+				path.Base(src.Filename) != path.Base(entry.Filename) {
+				continue
+			}
+			patch, err := similarDiff(version, a, b, src.Filename, entry.Filename)
+			if err != nil {
+				return nil, nil, err
+			}
+			if patch != nil {
+				moves[entry.Filename] = &move{op: 'm', src: src, patch: patch}
+				sources[src.Filename] = true
+				break
+			}
+		}
+	}
+	return moves, sources, nil
+}
+
+// writeMove writes the move or copy mv to the tree list entry as a file move
+// to w.
+func writeMove(w io.Writer, entry tree.ListEntry, mv *move) error {
+	fmt.Fprintf(w, "%c %c %x %s\n", mv.op, mv.src.Mode, mv.src.Hash, mv.src.Filename)
+	fmt.Fprintf(w, "+ %c %x %s\n", entry.Mode, entry.Hash, entry.Filename)
+	if mv.patch != nil {
+		if _, err := w.Write(mv.patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
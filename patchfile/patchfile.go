@@ -6,4 +6,10 @@ package patchfile
 // Version 1 was the initial Codechain patchfile version.
 //
 // Version 2 introduced "utf8file" sections.
-const Version = 2
+//
+// Version 3 introduced file moves and copies ('m' and 'c' lines).
+//
+// Version 4 introduced "bindelta" sections.
+//
+// Version 5 introduced symlinks (mode 'l' and "symlink" sections).
+const Version = 5
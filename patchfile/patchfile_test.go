@@ -7,8 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/frankbraun/codechain/tree"
+	"github.com/frankbraun/codechain/util/bindelta"
 	"github.com/frankbraun/codechain/util/file"
 )
 
@@ -191,6 +194,59 @@ ascii85 1
 `,
 			ErrPrematureDiffEnd,
 		},
+		{
+
+			`codechain patchfile version 2
+treehash 5998c63aca42e471297c0fa353538a93d4d4cfafe9a672df6989e694188b4a92
+m f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
+`,
+			ErrFileField0,
+		},
+		{
+
+			`codechain patchfile version 3
+treehash 5998c63aca42e471297c0fa353538a93d4d4cfafe9a672df6989e694188b4a92
+m f 15bb620236c7bba4ff1edbda701444c99ea5111e9d0b133329f8199a30fd26ac hello.go
+`,
+			ErrFileHashMismatchBefore,
+		},
+		{
+
+			`codechain patchfile version 3
+treehash 5998c63aca42e471297c0fa353538a93d4d4cfafe9a672df6989e694188b4a92
+m f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
+- f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
+`,
+			ErrMoveTargetMissing,
+		},
+		{
+
+			`codechain patchfile version 3
+treehash 5998c63aca42e471297c0fa353538a93d4d4cfafe9a672df6989e694188b4a92
+c f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
++ f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
+`,
+			ErrMoveTargetFileExists,
+		},
+		{
+
+			`codechain patchfile version 3
+treehash 5998c63aca42e471297c0fa353538a93d4d4cfafe9a672df6989e694188b4a92
+- f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
++ f 15bb620236c7bba4ff1edbda701444c99ea5111e9d0b133329f8199a30fd26ac hello.go
+bindelta 1
+`,
+			ErrDiffModeUnknown,
+		},
+		{
+
+			`codechain patchfile version 4
+treehash 5998c63aca42e471297c0fa353538a93d4d4cfafe9a672df6989e694188b4a92
++ f 15bb620236c7bba4ff1edbda701444c99ea5111e9d0b133329f8199a30fd26ac hello2.go
+bindelta 1
+`,
+			ErrDeltaWithoutSource,
+		},
 	}
 
 	helloDir := filepath.Join("testdata", "hello")
@@ -670,3 +726,457 @@ treehash 51400bd59c9ba8e05474c39e2c7cda018b524a99910521e358d791817a51af55
 		}
 	}
 }
+
+func TestMoveCopy(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "patchfile_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	hello, err := ioutil.ReadFile(filepath.Join("testdata", "hello", "hello.go"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() failed: %v", err)
+	}
+	// similar files have to be large enough for the diff to be small
+	comment := []byte(strings.Repeat("// comment\n", 30))
+	similar := append(append([]byte{}, hello...), comment...)
+	similar2 := append(append([]byte{}, hello...), comment...)
+	similar2 = bytes.Replace(similar2, []byte("world"), []byte("moon"), 1)
+	type treeFile struct {
+		name string
+		perm os.FileMode
+		data []byte
+	}
+	writeTree := func(dir string, files []treeFile) {
+		for _, f := range files {
+			fn := filepath.Join(dir, f.name)
+			if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+				t.Fatalf("os.MkdirAll() failed: %v", err)
+			}
+			if err := ioutil.WriteFile(fn, f.data, f.perm); err != nil {
+				t.Fatalf("ioutil.WriteFile() failed: %v", err)
+			}
+		}
+	}
+
+	testCases := []struct {
+		version int
+		a       []treeFile
+		b       []treeFile
+		ops     []string // expected file diff line prefixes
+	}{
+		{
+			// move
+			3,
+			[]treeFile{{"hello.go", 0644, hello}},
+			[]treeFile{{"hellomove.go", 0644, hello}},
+			[]string{"m f", "+ f"},
+		},
+		{
+			// move is add and delete for version 2
+			2,
+			[]treeFile{{"hello.go", 0644, hello}},
+			[]treeFile{{"hellomove.go", 0644, hello}},
+			[]string{"- f", "+ f", "utf8file"},
+		},
+		{
+			// move with mode change
+			3,
+			[]treeFile{{"hello.go", 0644, hello}},
+			[]treeFile{{"dir/hellomove.go", 0755, hello}},
+			[]string{"m f", "+ x"},
+		},
+		{
+			// copy
+			3,
+			[]treeFile{{"hello.go", 0644, hello}},
+			[]treeFile{{"a/hello.go", 0644, hello}, {"hello.go", 0644, hello}},
+			[]string{"c f", "+ f"},
+		},
+		{
+			// similar move
+			3,
+			[]treeFile{{"hello.go", 0644, similar}, {"z.txt", 0644, []byte("z")}},
+			[]treeFile{{"dir/hello.go", 0644, similar2}, {"z.txt", 0644, []byte("z")}},
+			[]string{"m f", "+ f", "dmppatch"},
+		},
+		{
+			// move source becomes directory
+			3,
+			[]treeFile{{"hello", 0644, hello}, {"hello.txt", 0644, []byte("x")}},
+			[]treeFile{{"hello/hello", 0644, hello}},
+			[]string{"- f", "- f", "+ f", "utf8file"},
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Logf("test case %d\n", i+1)
+		a := filepath.Join(tmpdir, strconv.Itoa(i), "a")
+		b := filepath.Join(tmpdir, strconv.Itoa(i), "b")
+		writeTree(a, testCase.a)
+		writeTree(b, testCase.b)
+		var out bytes.Buffer
+		if err := Diff(testCase.version, &out, a, b, nil); err != nil {
+			t.Fatalf("Diff() failed: %v", err)
+		}
+		lines := strings.Split(out.String(), "\n")
+		for j, op := range testCase.ops {
+			if !strings.HasPrefix(lines[j+2], op) {
+				t.Errorf("line %d should start with '%s':\n%s", j+3, op, out.String())
+			}
+		}
+		if err := Apply(a, &out, nil); err != nil {
+			t.Fatalf("Apply() failed: %v", err)
+		}
+		hashA, err := tree.Hash(a, nil)
+		if err != nil {
+			t.Fatalf("tree.Hash() failed: %v", err)
+		}
+		hashB, err := tree.Hash(b, nil)
+		if err != nil {
+			t.Fatalf("tree.Hash() failed: %v", err)
+		}
+		if *hashA != *hashB {
+			t.Error("tree hashes differ after Apply()")
+		}
+	}
+}
+
+func TestBinDelta(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "patchfile_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	gopher, err := ioutil.ReadFile(filepath.Join("testdata", "binary", "gopher.png"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() failed: %v", err)
+	}
+	gopher2 := append([]byte{}, gopher...)
+	gopher2[len(gopher2)/2] ^= 0xff
+	gopher2 = append(gopher2, []byte("trailer")...)
+
+	testCases := []struct {
+		version int
+		a       string // filename in a
+		b       string // filename in b
+		ops     []string
+	}{
+		{3, "gopher.png", "gopher.png", []string{"- f", "+ f", "ascii85"}},
+		{4, "gopher.png", "gopher.png", []string{"- f", "+ f", "bindelta"}},
+		{4, "gopher.png", "img/gopher.png", []string{"m f", "+ f", "bindelta"}},
+	}
+
+	for i, testCase := range testCases {
+		t.Logf("test case %d\n", i+1)
+		a := filepath.Join(tmpdir, strconv.Itoa(i), "a")
+		b := filepath.Join(tmpdir, strconv.Itoa(i), "b")
+		fnA := filepath.Join(a, testCase.a)
+		fnB := filepath.Join(b, testCase.b)
+		if err := os.MkdirAll(filepath.Dir(fnA), 0755); err != nil {
+			t.Fatalf("os.MkdirAll() failed: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(fnB), 0755); err != nil {
+			t.Fatalf("os.MkdirAll() failed: %v", err)
+		}
+		if err := ioutil.WriteFile(fnA, gopher, 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile() failed: %v", err)
+		}
+		if err := ioutil.WriteFile(fnB, gopher2, 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile() failed: %v", err)
+		}
+		var out bytes.Buffer
+		if err := Diff(testCase.version, &out, a, b, nil); err != nil {
+			t.Fatalf("Diff() failed: %v", err)
+		}
+		lines := strings.Split(out.String(), "\n")
+		for j, op := range testCase.ops {
+			if !strings.HasPrefix(lines[j+2], op) {
+				t.Errorf("line %d should start with '%s':\n%s", j+3, op, out.String())
+			}
+		}
+		if err := Apply(a, &out, nil); err != nil {
+			t.Fatalf("Apply() failed: %v", err)
+		}
+		hashA, err := tree.Hash(a, nil)
+		if err != nil {
+			t.Fatalf("tree.Hash() failed: %v", err)
+		}
+		hashB, err := tree.Hash(b, nil)
+		if err != nil {
+			t.Fatalf("tree.Hash() failed: %v", err)
+		}
+		if *hashA != *hashB {
+			t.Error("tree hashes differ after Apply()")
+		}
+	}
+
+	// invalid deltas
+	testCases2 := []struct {
+		patch     string
+		errorCode error
+	}{
+		{
+			`codechain patchfile version 4
+treehash 5998c63aca42e471297c0fa353538a93d4d4cfafe9a672df6989e694188b4a92
+- f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
++ f 15bb620236c7bba4ff1edbda701444c99ea5111e9d0b133329f8199a30fd26ac hello.go
+bindelta 1
+:'CYf'E
+`,
+			bindelta.ErrInvalid,
+		},
+		{
+			// valid delta, but the result doesn't match the hash
+			`codechain patchfile version 4
+treehash 5998c63aca42e471297c0fa353538a93d4d4cfafe9a672df6989e694188b4a92
+- f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d hello.go
++ f 15bb620236c7bba4ff1edbda701444c99ea5111e9d0b133329f8199a30fd26ac hello.go
+bindelta 1
+:'CV$GB.V>B)
+`,
+			ErrFileHashMismatchAfter,
+		},
+	}
+	for i, testCase := range testCases2 {
+		helloDir := filepath.Join(tmpdir, "hello"+strconv.Itoa(i))
+		if err := file.CopyDir(filepath.Join("testdata", "hello"), helloDir); err != nil {
+			t.Fatalf("file.CopyDir() failed: %v", err)
+		}
+		err := Apply(helloDir, bytes.NewBufferString(testCase.patch), nil)
+		if err != testCase.errorCode {
+			t.Errorf("Apply(%s) should have error code: %v (has %v)", testCase.patch,
+				testCase.errorCode, err)
+		}
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "patchfile_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	type treeFile struct {
+		name string
+		link string // symlink target, if not empty
+		data string
+	}
+	writeTree := func(dir string, files []treeFile) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("os.MkdirAll() failed: %v", err)
+		}
+		for _, f := range files {
+			fn := filepath.Join(dir, f.name)
+			if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+				t.Fatalf("os.MkdirAll() failed: %v", err)
+			}
+			if f.link != "" {
+				if err := os.Symlink(f.link, fn); err != nil {
+					t.Fatalf("os.Symlink() failed: %v", err)
+				}
+			} else {
+				if err := ioutil.WriteFile(fn, []byte(f.data), 0644); err != nil {
+					t.Fatalf("ioutil.WriteFile() failed: %v", err)
+				}
+			}
+		}
+	}
+
+	foo := treeFile{"foo.txt", "", "foo\n"}
+	testCases := []struct {
+		a   []treeFile
+		b   []treeFile
+		ops []string
+	}{
+		{
+			// add symlink
+			[]treeFile{foo},
+			[]treeFile{{"bar/link", "../foo.txt", ""}, foo},
+			[]string{"+ l", "symlink 1", "../foo.txt"},
+		},
+		{
+			// change symlink
+			[]treeFile{{"link", "foo.txt", ""}, foo},
+			[]treeFile{{"link", "bar.txt", ""}, foo},
+			[]string{"- l", "+ l", "symlink 1", "bar.txt"},
+		},
+		{
+			// symlink to file
+			[]treeFile{{"link", "foo.txt", ""}, foo},
+			[]treeFile{{"link", "", "foo\n"}, foo},
+			[]string{"- l", "+ f", "utf8file"},
+		},
+		{
+			// file to symlink
+			[]treeFile{{"link", "", "foo\n"}, foo},
+			[]treeFile{{"link", "foo.txt", ""}, foo},
+			[]string{"- f", "+ l", "symlink 1", "foo.txt"},
+		},
+		{
+			// delete symlink
+			[]treeFile{{"link", "foo.txt", ""}, foo},
+			[]treeFile{foo},
+			[]string{"- l", "treehash"},
+		},
+		{
+			// moved symlink is deleted and added
+			[]treeFile{{"link", "foo.txt", ""}, foo},
+			[]treeFile{foo, {"link2", "foo.txt", ""}},
+			[]string{"- l", "+ l", "symlink 1", "foo.txt"},
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Logf("test case %d\n", i+1)
+		a := filepath.Join(tmpdir, strconv.Itoa(i), "a")
+		b := filepath.Join(tmpdir, strconv.Itoa(i), "b")
+		writeTree(a, testCase.a)
+		writeTree(b, testCase.b)
+		if err := Diff(4, ioutil.Discard, a, b, nil); err != ErrSymlinkVersion {
+			t.Error("Diff() should fail with ErrSymlinkVersion")
+		}
+		var out bytes.Buffer
+		if err := Diff(5, &out, a, b, nil); err != nil {
+			t.Fatalf("Diff() failed: %v", err)
+		}
+		lines := strings.Split(out.String(), "\n")
+		for j, op := range testCase.ops {
+			if !strings.HasPrefix(lines[j+2], op) {
+				t.Errorf("line %d should start with '%s':\n%s", j+3, op, out.String())
+			}
+		}
+		if err := Apply(a, &out, nil); err != nil {
+			t.Fatalf("Apply() failed: %v", err)
+		}
+		hashA, err := tree.Hash(a, nil)
+		if err != nil {
+			t.Fatalf("tree.Hash() failed: %v", err)
+		}
+		hashB, err := tree.Hash(b, nil)
+		if err != nil {
+			t.Fatalf("tree.Hash() failed: %v", err)
+		}
+		if *hashA != *hashB {
+			t.Error("tree hashes differ after Apply()")
+		}
+	}
+
+	// unsafe patches
+	emptyHash := tree.EmptyHash
+	dir := filepath.Join(tmpdir, "unsafe")
+	writeTree(dir, []treeFile{{"link", ".", ""}})
+	h, err := tree.Hash(dir, nil)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+	linkHash := hex.EncodeToString(h[:])
+	errorCases := []struct {
+		treeHash  string
+		patch     string
+		errorCode error
+	}{
+		{
+			emptyHash,
+			`+ l 4ba3e9ab27a3d2d6d58fb3f7e7bc6d5ec8ee4f5c56a69f08ae3a8a35e0d4f0b1 link
+symlink 1
+../outside
+`,
+			tree.ErrSymlinkEscapes,
+		},
+		{
+			linkHash,
+			`+ f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d link/hello.go
+`,
+			ErrSymlinkPath,
+		},
+		{
+			emptyHash,
+			`+ f ad125cc5c1fb680be130908a0838ca2235db04285bcdd29e8e25087927e7dd0d link
+symlink 1
+foo.txt
+`,
+			ErrSymlinkMode,
+		},
+	}
+	for i, errorCase := range errorCases {
+		dir := filepath.Join(tmpdir, "unsafe"+strconv.Itoa(i))
+		if errorCase.treeHash == emptyHash {
+			writeTree(dir, nil)
+		} else {
+			writeTree(dir, []treeFile{{"link", ".", ""}})
+		}
+		patch := "codechain patchfile version 5\ntreehash " + errorCase.treeHash + "\n" +
+			errorCase.patch
+		err := Apply(dir, bytes.NewBufferString(patch), nil)
+		if err != errorCase.errorCode {
+			t.Errorf("Apply(%s) should have error code: %v (has %v)", patch,
+				errorCase.errorCode, err)
+		}
+	}
+
+	// file diffs must not be written through symlinks
+	a := filepath.Join(tmpdir, "through", "a")
+	b := filepath.Join(tmpdir, "through", "b")
+	dir = filepath.Join(tmpdir, "through", "dir")
+	writeTree(a, []treeFile{{"link", "", "foo\n"}, foo})
+	writeTree(b, []treeFile{{"link", "", "bar\n"}, foo})
+	writeTree(dir, []treeFile{{"link", "foo.txt", ""}, foo})
+	var out bytes.Buffer
+	if err := Diff(5, &out, a, b, nil); err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+	hashA, err := tree.Hash(a, nil)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+	h, err = tree.Hash(dir, nil)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+	patch := strings.Replace(out.String(), hex.EncodeToString(hashA[:]),
+		hex.EncodeToString(h[:]), 1)
+	err = Apply(dir, bytes.NewBufferString(patch), nil)
+	if err != ErrNotRegularFile {
+		t.Errorf("Apply() should fail with ErrNotRegularFile (has %v)", err)
+	}
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "foo.txt"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() failed: %v", err)
+	}
+	if string(buf) != "foo\n" {
+		t.Errorf("Apply() wrote through symlink: %q", buf)
+	}
+
+	// files must not be moved or copied through symlinks
+	a = filepath.Join(tmpdir, "move", "a")
+	b = filepath.Join(tmpdir, "move", "b")
+	dir = filepath.Join(tmpdir, "move", "dir")
+	writeTree(a, []treeFile{{"a.txt", "", "foo\n"}, foo})
+	writeTree(b, []treeFile{{"b.txt", "", "foo\n"}, foo})
+	writeTree(dir, []treeFile{{"a.txt", "foo.txt", ""}, foo})
+	out.Reset()
+	if err := Diff(5, &out, a, b, nil); err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "\nm f ") {
+		t.Fatalf("Diff() did not create move:\n%s", out.String())
+	}
+	hashA, err = tree.Hash(a, nil)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+	h, err = tree.Hash(dir, nil)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+	patch = strings.Replace(out.String(), hex.EncodeToString(hashA[:]),
+		hex.EncodeToString(h[:]), 1)
+	err = Apply(dir, bytes.NewBufferString(patch), nil)
+	if err != ErrNotRegularFile {
+		t.Errorf("Apply() should fail with ErrNotRegularFile (has %v)", err)
+	}
+}
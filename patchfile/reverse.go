@@ -0,0 +1,104 @@
+package patchfile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/frankbraun/codechain/tree"
+)
+
+// listEntry returns the tree list entry for the canonical filename name in
+// directory dir or nil, if the file does not exist.
+func listEntry(dir, name string) (*tree.ListEntry, error) {
+	filename := filepath.Join(dir, name)
+	fi, err := os.Lstat(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	entry := tree.ListEntry{Filename: name}
+	var hash *[32]byte
+	if fi.Mode()&os.ModeSymlink != 0 {
+		entry.Mode = 'l'
+		hash, err = tree.SymlinkHash(filename)
+	} else {
+		if fi.Mode()&0100 == 0100 {
+			entry.Mode = 'x'
+		} else {
+			entry.Mode = 'f'
+		}
+		hash, err = tree.SHA256(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry.Hash = *hash
+	return &entry, nil
+}
+
+// WriteReverse writes a reverse patch for the last patch applied with journal
+// j to w. The reverse patch leads from the final tree hash of the applied
+// patch back to its start tree hash. It is a normal patchfile (of the current
+// Version) and can be applied with Apply.
+//
+// WriteReverse must be called after Apply succeeded and before j is committed
+// or rolled back. Otherwise, ErrNoReverse is returned.
+func (j *Journal) WriteReverse(w io.Writer) error {
+	if j.endHash == "" {
+		return ErrNoReverse
+	}
+	// collect files changed by last patch (directories are kept)
+	var names []string
+	exists := make(map[string]bool)
+	for _, entry := range j.entries[j.step:] {
+		if entry.isDir || entry.remDir {
+			continue
+		}
+		rel, err := filepath.Rel(j.dir, entry.path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		names = append(names, name)
+		exists[name] = entry.exists
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "codechain patchfile version %d\n", Version)
+	fmt.Fprintf(w, "treehash %s\n", j.endHash)
+	for _, name := range names {
+		// the previous state is contained in the backup directory of the step
+		var prev *tree.ListEntry
+		if exists[name] {
+			var err error
+			prev, err = listEntry(j.stepDir, name)
+			if err != nil {
+				return err
+			}
+		}
+		cur, err := listEntry(j.dir, name)
+		if err != nil {
+			return err
+		}
+		switch {
+		case prev == nil && cur == nil:
+			// file was added and removed again
+		case prev == nil:
+			writeFileDeletion(w, *cur)
+		case cur == nil:
+			if err := writeFileAddition(Version, w, j.stepDir, *prev); err != nil {
+				return err
+			}
+		default:
+			if err := writeFileDiff(Version, w, j.dir, j.stepDir, *cur, *prev); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Fprintf(w, "treehash %s\n", j.startHash)
+	return nil
+}
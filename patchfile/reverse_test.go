@@ -0,0 +1,103 @@
+package patchfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frankbraun/codechain/tree"
+	"github.com/frankbraun/codechain/util/file"
+)
+
+func testReverse(t *testing.T, tmpdir, a, b string) {
+	var patch bytes.Buffer
+	if err := Diff(Version, &patch, a, b, nil); err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+	dir := filepath.Join(tmpdir, "dir")
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("os.RemoveAll() failed: %v", err)
+	}
+	if err := file.CopyDir(a, dir); err != nil {
+		t.Fatalf("file.CopyDir() failed: %v", err)
+	}
+	before, err := tree.ListBytes(a, nil)
+	if err != nil {
+		t.Fatalf("tree.ListBytes() failed: %v", err)
+	}
+
+	// apply patch and write reverse patch
+	j, err := NewJournal(dir)
+	if err != nil {
+		t.Fatalf("NewJournal() failed: %v", err)
+	}
+	if err := j.Apply(&patch, nil); err != nil {
+		t.Fatalf("j.Apply() failed: %v", err)
+	}
+	var reverse bytes.Buffer
+	if err := j.WriteReverse(&reverse); err != nil {
+		t.Fatalf("j.WriteReverse() failed: %v", err)
+	}
+	if err := j.Commit(); err != nil {
+		t.Fatalf("j.Commit() failed: %v", err)
+	}
+	if err := j.WriteReverse(ioutil.Discard); err != ErrNoReverse {
+		t.Errorf("j.WriteReverse() should fail with ErrNoReverse after commit (has %v)", err)
+	}
+
+	// apply reverse patch
+	if err := Apply(dir, &reverse, nil); err != nil {
+		t.Fatalf("Apply() of reverse patch %s -> %s failed: %v", b, a, err)
+	}
+	after, err := tree.ListBytes(dir, nil)
+	if err != nil {
+		t.Fatalf("tree.ListBytes() failed: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Errorf("reverse patch %s -> %s failed:\n%s\n%s", b, a, before, after)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "patchfile_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// directory trees with symlinks
+	link := filepath.Join(tmpdir, "link")
+	link2 := filepath.Join(tmpdir, "link2")
+	if err := file.CopyDir(filepath.Join("testdata", "xy"), link); err != nil {
+		t.Fatalf("file.CopyDir() failed: %v", err)
+	}
+	if err := os.Symlink("x.txt", filepath.Join(link, "link")); err != nil {
+		t.Fatalf("os.Symlink() failed: %v", err)
+	}
+	if err := file.CopyDir(filepath.Join("testdata", "y"), link2); err != nil {
+		t.Fatalf("file.CopyDir() failed: %v", err)
+	}
+	if err := os.Symlink("y.txt", filepath.Join(link2, "link")); err != nil {
+		t.Fatalf("os.Symlink() failed: %v", err)
+	}
+	if err := os.Symlink("y.txt", filepath.Join(link2, "x.txt")); err != nil {
+		t.Fatalf("os.Symlink() failed: %v", err)
+	}
+
+	pairs := [][2]string{
+		{filepath.Join("testdata", "hello"), filepath.Join("testdata", "hello2")},
+		{filepath.Join("testdata", "binary"), filepath.Join("testdata", "binary2")},
+		{filepath.Join("testdata", "script"), filepath.Join("testdata", "script2")},
+		{filepath.Join("testdata", "script"), filepath.Join("testdata", "scriptfile")},
+		{filepath.Join("testdata", "xy"), filepath.Join("testdata", "y")},
+		{filepath.Join("testdata", "hello"), filepath.Join("testdata", "tree")},
+		{filepath.Join("testdata", "hellomove"), filepath.Join("testdata", "hello")},
+		{link, link2},
+	}
+	for _, pair := range pairs {
+		testReverse(t, tmpdir, pair[0], pair[1])
+		testReverse(t, tmpdir, pair[1], pair[0])
+	}
+}
@@ -0,0 +1,107 @@
+package patchfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frankbraun/codechain/tree"
+)
+
+// symlinkDiff writes the target of the symbolic link with filename to w as a
+// "symlink" section.
+func symlinkDiff(w io.Writer, filename string) error {
+	target, err := os.Readlink(filename)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "symlink 1\n%s\n", filepath.ToSlash(target))
+	return nil
+}
+
+// checkPath makes sure that no parent directory of the canonical filename
+// name in directory dir is a symlink. Otherwise, patches could be applied to
+// files outside of the directory tree list (or even outside of dir).
+func checkPath(dir, name string) error {
+	elems := strings.Split(name, "/")
+	p := dir
+	for _, elem := range elems[:len(elems)-1] {
+		p = filepath.Join(p, elem)
+		fi, err := os.Lstat(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return ErrSymlinkPath
+		}
+	}
+	return nil
+}
+
+// fileHash returns the hash of the file with filename according to mode m.
+// For other modes than symlink the file must be a regular file, otherwise
+// patches could write through symlinks.
+func fileHash(filename string, m mode) (*[32]byte, error) {
+	if m == symlink {
+		return tree.SymlinkHash(filename)
+	}
+	fi, err := os.Lstat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, ErrNotRegularFile
+	}
+	return tree.SHA256(filename)
+}
+
+// applySymlink creates the symbolic link described by cur with the given
+// target in the directory tree of journal j. If state is not addFile, the previous file
+// described by prev is removed first.
+func applySymlink(j *Journal, target string, state state, prev, cur *diffInfo) error {
+	dir := j.dir
+	if cur.mode != symlink {
+		return ErrSymlinkMode
+	}
+	if err := tree.CheckSymlink(cur.name, target); err != nil {
+		return err
+	}
+	fileB := filepath.Join(dir, cur.name)
+	if state == addFile {
+		if err := j.mkdirAll(filepath.Dir(fileB)); err != nil {
+			return err
+		}
+	} else {
+		fileA := filepath.Join(dir, prev.name)
+		hash, err := fileHash(fileA, prev.mode)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(hash[:], prev.hash) {
+			return ErrFileHashMismatchBefore
+		}
+		if err := j.remove(fileA); err != nil {
+			return err
+		}
+	}
+	if err := j.record(fileB); err != nil {
+		return err
+	}
+	if err := os.Symlink(filepath.FromSlash(target), fileB); err != nil {
+		return err
+	}
+	hash, err := tree.SymlinkHash(fileB)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash[:], cur.hash) {
+		return ErrFileHashMismatchAfter
+	}
+	return nil
+}
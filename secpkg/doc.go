@@ -75,7 +75,7 @@ Installing software described by a .secpkg file works as follows:
       If it fails: Goto 11.
 
   13. Apply ~/.config/secpkg/pkgs/NAME/dists/HEAD_SSOT.DNS.tar.gz
-      to ~/.config/secpkg/pkgs/NAME/src with `codechain apply
+      to ~/.config/secpkg/pkgs/NAME/src with `codechain apply -block-nacks
       -f ~/.config/secpkg/pkgs/NAME/dists/HEAD_SSOT.DNS.tar.gz
       -head HEAD_SSOT.DNS`
       If it fails: Goto 11.
@@ -174,7 +174,7 @@ Updating a software package with NAME works as follows:
       If it fails: Goto 14.
 
   16. If not SKIP_BUILD, apply ~/.config/secpkg/pkgs/NAME/dists/HEAD.tar.gz
-      to ~/.config/secpkg/pkgs/NAME/src with `codechain apply
+      to ~/.config/secpkg/pkgs/NAME/src with `codechain apply -block-nacks
       -f ~/.config/secpkg/pkgs/NAME/dists/HEAD.tar.gz -head HEAD`.
       If it fails: Goto 14.
 
@@ -144,7 +144,7 @@ _11:
 	}
 
 	// 13. Apply ~/.config/secpkg/pkgs/NAME/dists/HEAD_SSOT.tar.gz
-	//     to ~/.config/secpkg/pkgs/NAME/src with `codechain apply
+	//     to ~/.config/secpkg/pkgs/NAME/src with `codechain apply -block-nacks
 	//     -f ~/.config/secpkg/pkgs/NAME/dists/HEAD_SSOT.tar.gz -head HEAD_SSOT`
 	//     If it fails: Goto 11.
 	srcDir := filepath.Join(pkgDir, "src")
@@ -185,6 +185,7 @@ _11:
 		os.RemoveAll(pkgDir)
 		return err
 	}
+	c.SetObjectionPolicy(true)
 	if err := c.Apply(&head, def.PatchDir); err != nil {
 		fmt.Printf("error: %s\n", err)
 		goto _11
@@ -12,6 +12,7 @@ import (
 
 	"github.com/frankbraun/codechain/ssot"
 	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/signer"
 )
 
 func signHead(head string) (ssot.SignedHead, error) {
@@ -27,7 +28,7 @@ func signHead(head string) (ssot.SignedHead, error) {
 	}
 	var sk [64]byte
 	copy(sk[:], sec)
-	return ssot.SignHeadV2(hb, 2, 0, sk, nil, ssot.MaximumValidity)
+	return ssot.SignHeadV2(hb, 2, 0, signer.New(sk), nil, ssot.MaximumValidity)
 }
 
 func TestInstallBinpkg(t *testing.T) {
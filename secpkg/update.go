@@ -154,7 +154,7 @@ _9:
 	}
 
 	// 11. If not SKIP_BUILD, apply ~/.config/secpkg/pkgs/NAME/dists/HEAD.tar.gz
-	//     to ~/.config/secpkg/pkgs/NAME/src with `codechain apply
+	//     to ~/.config/secpkg/pkgs/NAME/src with `codechain apply -block-nacks
 	//     -f ~/.config/secpkg/pkgs/NAME/dists/HEAD.tar.gz -head HEAD`.
 	//     If it fails: Goto 9.
 	if !skipBuild {
@@ -188,6 +188,7 @@ _9:
 		if err := c.Close(); err != nil {
 			return false, err
 		}
+		c.SetObjectionPolicy(true)
 		if err := c.Apply(&head, def.PatchDir); err != nil {
 			fmt.Printf("error: %s\n", err)
 			goto _9
@@ -0,0 +1,198 @@
+// Package signreq implements signing requests for offline (air-gapped)
+// signing of hash chain reviews and SSOT signed heads.
+//
+// The online machine exports a signing request as a compact text blob, the
+// offline machine signs it (see Sign), and the response is imported back on
+// the online machine. For reviews the response has the form
+//
+//	linkhash pubkey signature
+//
+// as expected by 'codechain review -a'. For signed heads the response is the
+// base64 encoded signed head as expected by 'ssotpub signhead -import'.
+package signreq
+
+import (
+	"bufio"
+	"bytes"
+	b64 "encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/frankbraun/codechain/ssot"
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/signer"
+)
+
+// Types of signing requests.
+const (
+	TypeReview   = "review"   // review signature over link hash
+	TypeSignHead = "signhead" // SSOT signed head
+)
+
+const header = "codechain-signreq"
+
+// ErrInvalid is returned if a signing request cannot be parsed.
+var ErrInvalid = errors.New("signreq: invalid signing request")
+
+// ErrWrongKey is returned if a signing request is signed with the wrong key.
+var ErrWrongKey = errors.New("signreq: signing request is for a different key")
+
+// Request is a signing request.
+type Request struct {
+	Type    string   // TypeReview or TypeSignHead
+	PubKey  [32]byte // public key of the requested signer
+	Message []byte   // the message to sign
+	Info    []string // informational summary lines (not signed)
+}
+
+// NewReview returns a new signing request for a review signature of the
+// signer with pubKey over linkHash.
+func NewReview(linkHash, pubKey [32]byte, info []string) *Request {
+	return &Request{
+		Type:    TypeReview,
+		PubKey:  pubKey,
+		Message: linkHash[:],
+		Info:    info,
+	}
+}
+
+// NewSignHead returns a new signing request for the unsigned head sh (see
+// ssot.NewUnsignedHeadV2).
+func NewSignHead(sh *ssot.SignedHeadV2, info []string) (*Request, error) {
+	pubKey, err := base64.Decode(sh.PubKey(), 32)
+	if err != nil {
+		return nil, err
+	}
+	r := &Request{
+		Type:    TypeSignHead,
+		Message: sh.Message(),
+		Info:    info,
+	}
+	copy(r.PubKey[:], pubKey)
+	return r, nil
+}
+
+// Marshal signing request r as compact text blob.
+func (r *Request) Marshal() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s %s %s\n", header, r.Type,
+		base64.Encode(r.PubKey[:]), base64.Encode(r.Message))
+	for _, info := range r.Info {
+		fmt.Fprintln(&b, info)
+	}
+	return base64.Encode(b.Bytes())
+}
+
+// Unmarshal a signing request encoded as compact text blob and check that
+// the message is well-formed for the request type.
+func Unmarshal(blob string) (*Request, error) {
+	text, err := b64.RawURLEncoding.DecodeString(strings.TrimSpace(blob))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	s := bufio.NewScanner(bytes.NewReader(text))
+	if !s.Scan() {
+		return nil, ErrInvalid
+	}
+	fields := strings.Split(s.Text(), " ")
+	if len(fields) != 4 || fields[0] != header {
+		return nil, ErrInvalid
+	}
+	var r Request
+	r.Type = fields[1]
+	pubKey, err := base64.Decode(fields[2], 32)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	copy(r.PubKey[:], pubKey)
+	r.Message, err = b64.RawURLEncoding.DecodeString(fields[3])
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	switch r.Type {
+	case TypeReview:
+		if len(r.Message) != 32 {
+			return nil, ErrInvalid
+		}
+	case TypeSignHead:
+		sh, err := ssot.UnmarshalUnsignedV2(base64.Encode(r.Message))
+		if err != nil {
+			return nil, err
+		}
+		if sh.PubKey() != base64.Encode(r.PubKey[:]) {
+			return nil, ErrInvalid
+		}
+	default:
+		return nil, fmt.Errorf("signreq: unknown request type: %s", r.Type)
+	}
+	for s.Scan() {
+		r.Info = append(r.Info, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Summary returns a human readable summary of signing request r. The fields
+// which are actually signed are decoded from the message, the unsigned info
+// lines are shown separately as unverified.
+func (r *Request) Summary() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "TYPE:          %s\n", r.Type)
+	fmt.Fprintf(&b, "PUBKEY:        %s\n", base64.Encode(r.PubKey[:]))
+	switch r.Type {
+	case TypeReview:
+		fmt.Fprintf(&b, "LINKHASH:      %x\n", r.Message)
+	case TypeSignHead:
+		sh, err := ssot.UnmarshalUnsignedV2(base64.Encode(r.Message))
+		if err != nil {
+			panic(err) // checked by Unmarshal
+		}
+		validFrom := time.Unix(sh.ValidFrom(), 0)
+		validTo := time.Unix(sh.ValidTo(), 0)
+		fmt.Fprintf(&b, "PUBKEY_ROTATE: %s\n", sh.PubKeyRotate())
+		fmt.Fprintf(&b, "VALID_FROM:    %s\n", validFrom.Format(time.RFC3339))
+		fmt.Fprintf(&b, "VALID_TO:      %s\n", validTo.Format(time.RFC3339))
+		fmt.Fprintf(&b, "COUNTER:       %d\n", sh.Counter())
+		fmt.Fprintf(&b, "HEAD:          %s\n", sh.Head())
+		fmt.Fprintf(&b, "LINE:          %d\n", sh.Line())
+	}
+	if len(r.Info) > 0 {
+		// info lines are created on the online machine and could be forged
+		fmt.Fprintln(&b, "UNVERIFIED (not covered by signature):")
+		for _, info := range r.Info {
+			fmt.Fprintf(&b, "  %s\n", info)
+		}
+	}
+	return b.String()
+}
+
+// Sign signing request r with signer s and return the response, which can be
+// imported on the online machine.
+func (r *Request) Sign(s signer.Signer) (string, error) {
+	if s.PublicKey() != r.PubKey {
+		return "", ErrWrongKey
+	}
+	sig, err := s.Sign(r.Message)
+	if err != nil {
+		return "", err
+	}
+	switch r.Type {
+	case TypeReview:
+		return fmt.Sprintf("%x %s %s", r.Message, base64.Encode(r.PubKey[:]),
+			base64.Encode(sig[:])), nil
+	case TypeSignHead:
+		sh, err := ssot.UnmarshalUnsignedV2(base64.Encode(r.Message))
+		if err != nil {
+			return "", err
+		}
+		if err := sh.SetSignature(sig); err != nil {
+			return "", err
+		}
+		return sh.Marshal(), nil
+	}
+	return "", fmt.Errorf("signreq: unknown request type: %s", r.Type)
+}
@@ -0,0 +1,107 @@
+package signreq
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/frankbraun/codechain/ssot"
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/signer"
+)
+
+func newSigner(t *testing.T) signer.Signer {
+	_, sec, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() failed: %v", err)
+	}
+	var secKey [64]byte
+	copy(secKey[:], sec)
+	return signer.New(secKey)
+}
+
+func TestReview(t *testing.T) {
+	s := newSigner(t)
+	var linkHash [32]byte
+	linkHash[0] = 1
+	r := NewReview(linkHash, s.PublicKey(), []string{"source a b", "addkey c"})
+	blob := r.Marshal()
+	if strings.ContainsAny(blob, " \n") {
+		t.Error("blob is not compact")
+	}
+	r2, err := Unmarshal(blob + "\n")
+	if err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if r2.Type != TypeReview || r2.PubKey != r.PubKey || len(r2.Info) != 2 {
+		t.Error("unmarshaled request differs")
+	}
+	if !strings.Contains(r2.Summary(), hex.Encode(linkHash[:])) {
+		t.Error("summary doesn't contain link hash")
+	}
+	summary := r2.Summary()
+	i := strings.Index(summary, "UNVERIFIED")
+	if i < 0 || i > strings.Index(summary, "source a b") {
+		t.Error("summary doesn't mark info lines as unverified")
+	}
+	if _, err := r2.Sign(newSigner(t)); err != ErrWrongKey {
+		t.Error("r.Sign() should fail with ErrWrongKey")
+	}
+	resp, err := r2.Sign(s)
+	if err != nil {
+		t.Fatalf("r.Sign() failed: %v", err)
+	}
+	fields := strings.Split(resp, " ")
+	if len(fields) != 3 {
+		t.Fatalf("wrong response: %s", resp)
+	}
+	pub := s.PublicKey()
+	sig, err := base64.Decode(fields[2], 64)
+	if err != nil {
+		t.Fatalf("base64.Decode() failed: %v", err)
+	}
+	if fields[0] != hex.Encode(linkHash[:]) || fields[1] != base64.Encode(pub[:]) ||
+		!ed25519.Verify(pub[:], linkHash[:], sig) {
+		t.Errorf("wrong response: %s", resp)
+	}
+
+	if _, err := Unmarshal("garbage"); err != ErrInvalid {
+		t.Error("Unmarshal() should fail with ErrInvalid")
+	}
+}
+
+func TestSignHead(t *testing.T) {
+	s := newSigner(t)
+	var head [32]byte
+	head[0] = 1
+	sh, err := ssot.NewUnsignedHeadV2(head, 5, 3, s.PublicKey(), nil,
+		ssot.MinimumValidity)
+	if err != nil {
+		t.Fatalf("ssot.NewUnsignedHeadV2() failed: %v", err)
+	}
+	r, err := NewSignHead(sh, []string{"package test"})
+	if err != nil {
+		t.Fatalf("NewSignHead() failed: %v", err)
+	}
+	r2, err := Unmarshal(r.Marshal())
+	if err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if !strings.Contains(r2.Summary(), "COUNTER:       3") {
+		t.Errorf("wrong summary:\n%s", r2.Summary())
+	}
+	resp, err := r2.Sign(s)
+	if err != nil {
+		t.Fatalf("r.Sign() failed: %v", err)
+	}
+	signedHead, err := ssot.Unmarshal(resp)
+	if err != nil {
+		t.Fatalf("ssot.Unmarshal() failed: %v", err)
+	}
+	if signedHead.HeadBuf() != head || signedHead.Counter() != 3 ||
+		signedHead.Line() != 5 || signedHead.PubKey() != sh.PubKey() {
+		t.Error("signed head differs")
+	}
+}
@@ -50,17 +50,20 @@ func createPkg(
 	c *hashchain.HashChain,
 	name, dns string,
 	dns2 []string,
-	URL, secKeyFile, secpkgFile string,
+	URL, secKeyFile string,
+	agent bool,
+	secpkgFile string,
 	encrypted, useCloudflare bool,
 	apiKey, email string,
 	validity time.Duration,
 ) error {
 	head, line := c.LastSignedHead()
 	fmt.Printf("create package for head %x\n", head)
-	secKey, _, _, err := seckey.Read(secKeyFile)
+	s, _, err := seckey.ReadSigner(secKeyFile, agent)
 	if err != nil {
 		return err
 	}
+	defer s.Zero()
 	// 4. Create package (before 1., because this checks the arguments)
 	if _, err := url.Parse(URL); err != nil {
 		return err
@@ -119,7 +122,7 @@ func createPkg(
 	fmt.Printf("%s: written\n", secpkgFile)
 
 	// 5. Create the first signed head with counter set to 0.
-	sh, err := ssot.SignHeadV2(head, line, 0, *secKey, nil, validity)
+	sh, err := ssot.SignHeadV2(head, line, 0, s, nil, validity)
 	if err != nil {
 		return err
 	}
@@ -196,7 +199,7 @@ func createPkg(
 func CreatePkg(argv0 string, args ...string) error {
 	fs := flag.NewFlagSet(argv0, flag.ContinueOnError)
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s -name name -dns FQDN -url URL -s seckey.bin\n", argv0)
+		fmt.Fprintf(os.Stderr, "Usage: %s -name name -dns FQDN -url URL -s seckey.bin|-agent\n", argv0)
 		fmt.Fprintf(os.Stderr, "Create secure package and first signed head.\n")
 		fs.PrintDefaults()
 	}
@@ -206,6 +209,7 @@ func CreatePkg(argv0 string, args ...string) error {
 	dns2opt := fs.String("dns2", "", "secondary fully qualified domain name for Codechain's TXT records")
 	url := fs.String("url", "", "URL to download project files from (URL/head.tar.gz)")
 	secKey := fs.String("s", "", "Secret key file")
+	agent := fs.Bool("agent", false, "Use Ed25519 key from ssh-agent")
 	verbose := fs.Bool("v", false, "Be verbose")
 	encrypted := fs.Bool("encrypted", false, "Encrypt source code archives")
 	useCloudflare := fs.Bool("cloudflare", false, "Use Cloudflare API to publish TXT records automatically")
@@ -233,7 +237,11 @@ func CreatePkg(argv0 string, args ...string) error {
 	if *verbose {
 		log.Std = log.NewStd(os.Stdout)
 	}
-	if err := seckey.Check(homedir.SSOTPub(), *secKey); err != nil {
+	if *agent {
+		if *secKey != "" {
+			return fmt.Errorf("%s: options -s and -agent exclude each other", argv0)
+		}
+	} else if err := seckey.Check(homedir.SSOTPub(), *secKey); err != nil {
 		return err
 	}
 	if fs.NArg() != 0 {
@@ -258,7 +266,7 @@ func CreatePkg(argv0 string, args ...string) error {
 		if *dns2opt != "" {
 			dns2 = append(dns2, *dns2opt)
 		}
-		err := createPkg(c, *name, *dns, dns2, *url, *secKey, *secpkgFile,
+		err := createPkg(c, *name, *dns, dns2, *url, *secKey, *agent, *secpkgFile,
 			*encrypted, *useCloudflare, *apiKey, *email, *validity)
 		if err != nil {
 			interrupt.ShutdownChannel <- err
@@ -12,7 +12,6 @@ import (
 	"github.com/frankbraun/codechain/ssot"
 	"github.com/frankbraun/codechain/util/base64"
 	"github.com/frankbraun/codechain/util/cloudflare"
-	"github.com/frankbraun/codechain/util/def"
 	"github.com/frankbraun/codechain/util/file"
 	"github.com/frankbraun/codechain/util/homedir"
 	"github.com/frankbraun/codechain/util/log"
@@ -22,6 +21,7 @@ import (
 func refresh(
 	secpkgFilename string,
 	validity time.Duration,
+	agent bool,
 	secKeyRotate *[64]byte,
 	sigRotate *[64]byte,
 	commentRotate []byte,
@@ -144,14 +144,14 @@ func refresh(
 		}
 	}
 
-	secKeyFile := filepath.Join(homedir.SSOTPub(), def.SecretsSubDir, pubKey)
-	secKey, _, _, err := seckey.Read(secKeyFile)
+	s, err := loadSigner(pubKey, agent)
 	if err != nil {
 		return err
 	}
+	defer s.Zero()
 
 	newSignedHead, err := ssot.SignHeadV2(prevSignedHead.HeadBuf(),
-		prevSignedHead.Line(), prevSignedHead.Counter()+1, *secKey,
+		prevSignedHead.Line(), prevSignedHead.Counter()+1, s,
 		pubKeyRotate, validity)
 	if err != nil {
 		return err
@@ -195,6 +195,7 @@ func Refresh(argv0 string, args ...string) error {
 		fmt.Fprintf(os.Stderr, "Refresh head from .secpkg file(s).\n")
 		fs.PrintDefaults()
 	}
+	agent := fs.Bool("agent", false, "Use Ed25519 key from ssh-agent")
 	rotate := fs.String("rotate", "", "Secret key file")
 	verbose := fs.Bool("v", false, "Be verbose")
 	validity := fs.Duration("validity", ssot.MaximumValidity, "Validity of signed head")
@@ -225,7 +226,7 @@ func Refresh(argv0 string, args ...string) error {
 	}
 	for _, secpkgFilename := range fs.Args() {
 		fmt.Printf("refreshing %s...\n", secpkgFilename)
-		err := refresh(secpkgFilename, *validity, secKeyRotate, sigRotate,
+		err := refresh(secpkgFilename, *validity, *agent, secKeyRotate, sigRotate,
 			commentRotate)
 		if err != nil {
 			return err
@@ -13,6 +13,7 @@ import (
 	"github.com/frankbraun/codechain/archive"
 	"github.com/frankbraun/codechain/hashchain"
 	"github.com/frankbraun/codechain/secpkg"
+	"github.com/frankbraun/codechain/signreq"
 	"github.com/frankbraun/codechain/ssot"
 	"github.com/frankbraun/codechain/util/base64"
 	"github.com/frankbraun/codechain/util/cloudflare"
@@ -23,6 +24,7 @@ import (
 	"github.com/frankbraun/codechain/util/interrupt"
 	"github.com/frankbraun/codechain/util/log"
 	"github.com/frankbraun/codechain/util/seckey"
+	"github.com/frankbraun/codechain/util/signer"
 )
 
 func writeTXTRecord(
@@ -36,10 +38,97 @@ func writeTXTRecord(
 	return s.TXTUpdate(zone, def.CodechainHeadName+DNS, sh.Marshal(), ssot.TTL)
 }
 
+// loadSigner returns the signer for pubKey, either from the ssh-agent (if
+// agent is true) or from the corresponding keyfile in the secrets
+// subdirectory of homedir.SSOTPub().
+func loadSigner(pubKey string, agent bool) (signer.Signer, error) {
+	if agent {
+		s, _, err := seckey.Agent(map[string]bool{pubKey: true})
+		return s, err
+	}
+	secKeyFile := filepath.Join(homedir.SSOTPub(), def.SecretsSubDir, pubKey)
+	s, _, err := seckey.ReadSigner(secKeyFile, false)
+	return s, err
+}
+
+func printSignHeadRequest(
+	name, dns string,
+	head [32]byte,
+	line int,
+	counter uint64,
+	pubKey string,
+	pubKeyRotate *[32]byte,
+	validity time.Duration,
+) error {
+	pk, err := base64.Decode(pubKey, 32)
+	if err != nil {
+		return err
+	}
+	var pub [32]byte
+	copy(pub[:], pk)
+	sh, err := ssot.NewUnsignedHeadV2(head, line, counter, pub, pubKeyRotate,
+		validity)
+	if err != nil {
+		return err
+	}
+	r, err := signreq.NewSignHead(sh, []string{
+		"package: " + name,
+		"DNS:     " + dns,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("sign the following request offline with 'codechain sign-request':")
+	fmt.Println(r.Marshal())
+	return nil
+}
+
+// checkImportHead parses the signed head importHead (signed offline) and
+// makes sure it matches the signed head we would have created.
+func checkImportHead(
+	importHead string,
+	head [32]byte,
+	line int,
+	counter uint64,
+	pubKey string,
+	pubKeyRotate *[32]byte,
+) (ssot.SignedHead, error) {
+	sh, err := ssot.Unmarshal(importHead)
+	if err != nil {
+		return nil, err
+	}
+	if err := ssot.Valid(sh); err != nil {
+		return nil, err
+	}
+	if sh.HeadBuf() != head {
+		return nil, fmt.Errorf("imported signed head has wrong head: %s", sh.Head())
+	}
+	if sh.Line() != line {
+		return nil, fmt.Errorf("imported signed head has wrong line: %d", sh.Line())
+	}
+	if sh.Counter() != counter {
+		return nil, fmt.Errorf("imported signed head has wrong counter: %d", sh.Counter())
+	}
+	if sh.PubKey() != pubKey {
+		return nil, fmt.Errorf("imported signed head has wrong pubkey: %s", sh.PubKey())
+	}
+	var rotate [32]byte
+	if pubKeyRotate != nil {
+		rotate = *pubKeyRotate
+	}
+	if sh.PubKeyRotate() != base64.Encode(rotate[:]) {
+		return nil, fmt.Errorf("imported signed head has wrong pubkey rotate: %s",
+			sh.PubKeyRotate())
+	}
+	return sh, nil
+}
+
 func signHead(
 	ctx context.Context,
 	c *hashchain.HashChain,
 	validity time.Duration,
+	agent, request bool,
+	importHead string,
 	secKeyRotate *[64]byte,
 	sigRotate *[64]byte,
 	commentRotate []byte,
@@ -163,16 +252,28 @@ func signHead(
 		}
 	}
 
-	secKeyFile := filepath.Join(homedir.SSOTPub(), def.SecretsSubDir, pubKey)
-	secKey, _, _, err := seckey.Read(secKeyFile)
-	if err != nil {
-		return err
-	}
-
-	newSignedHead, err := ssot.SignHeadV2(head, line,
-		prevSignedHead.Counter()+1, *secKey, pubKeyRotate, validity)
-	if err != nil {
-		return err
+	var newSignedHead ssot.SignedHead
+	if request {
+		// create signing request for offline signing and stop
+		return printSignHeadRequest(pkg.Name, pkg.DNS, head, line,
+			prevSignedHead.Counter()+1, pubKey, pubKeyRotate, validity)
+	} else if importHead != "" {
+		newSignedHead, err = checkImportHead(importHead, head, line,
+			prevSignedHead.Counter()+1, pubKey, pubKeyRotate)
+		if err != nil {
+			return err
+		}
+	} else {
+		s, err := loadSigner(pubKey, agent)
+		if err != nil {
+			return err
+		}
+		defer s.Zero()
+		newSignedHead, err = ssot.SignHeadV2(head, line,
+			prevSignedHead.Counter()+1, s, pubKeyRotate, validity)
+		if err != nil {
+			return err
+		}
 	}
 	if err := ssot.RotateFile(newSignedHead, pkgDir); err != nil {
 		return err
@@ -275,6 +376,9 @@ func SignHead(argv0 string, args ...string) error {
 		fmt.Fprintf(os.Stderr, "Sign Codechain head and print it on stdout.\n")
 		fs.PrintDefaults()
 	}
+	agent := fs.Bool("agent", false, "Use Ed25519 key from ssh-agent")
+	importHead := fs.String("import", "", "Import signed head created by 'codechain sign-request'")
+	request := fs.Bool("request", false, "Create signing request for offline signing")
 	secpkgFile := fs.String("f", secpkg.File, "The secpkg filename")
 	rotate := fs.String("rotate", "", "Secret key file")
 	verbose := fs.Bool("v", false, "Be verbose")
@@ -289,6 +393,12 @@ func SignHead(argv0 string, args ...string) error {
 		fs.Usage()
 		return flag.ErrHelp
 	}
+	if *request && *importHead != "" {
+		return fmt.Errorf("%s: options -request and -import exclude each other", argv0)
+	}
+	if *agent && (*request || *importHead != "") {
+		return fmt.Errorf("%s: option -agent excludes options -request and -import", argv0)
+	}
 	if err := secpkg.UpToDate("codechain"); err != nil {
 		return err
 	}
@@ -314,8 +424,8 @@ func SignHead(argv0 string, args ...string) error {
 	})
 	// run signHead
 	go func() {
-		err := signHead(context.Background(), c, *validity, secKeyRotate,
-			sigRotate, commentRotate, *secpkgFile)
+		err := signHead(context.Background(), c, *validity, *agent, *request,
+			*importHead, secKeyRotate, sigRotate, commentRotate, *secpkgFile)
 		if err != nil {
 			interrupt.ShutdownChannel <- err
 			return
@@ -88,13 +88,14 @@ func testBuild() error {
 	}
 	defer os.Chdir(cwd)
 
-	// 6. `codechain apply`
-	log.Println("6. `codechain apply`")
+	// 6. `codechain apply -block-nacks`
+	log.Println("6. `codechain apply -block-nacks`")
 	c, err := hashchain.ReadFile(def.UnoverwriteableHashchainFile)
 	if err != nil {
 		return err
 	}
 	defer c.Close()
+	c.SetObjectionPolicy(true)
 	if err := c.Apply(nil, def.UnoverwriteablePatchDir); err != nil {
 		return err
 	}
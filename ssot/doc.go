@@ -106,6 +106,12 @@ To publish an update of a secure package with SSOT do the following:
         remove ~/.config/ssotput/pkgs/NAME/rotate_to.
       - Otherwise use old PUBKEY and set pubkey from file as PUBKEY_ROTATE.
 
+      For offline signing (-request) print a signing request for the new
+      signed head and stop. It is signed on the offline machine with
+      `codechain sign-request` and the resulting signed head is imported with
+      -import, which makes sure it matches the signed head created in this
+      step and continues with the specification.
+
    9. If the HEAD changed, save the current distribution to:
       ~/.config/secpkg/pkgs/NAME/dists/HEAD.tar.gz (`codechain createdist`).
 
@@ -176,7 +182,7 @@ To test the build of a secure package do the following:
 
    5. `cd TMPDIR/build`
 
-   6. `codechain apply`
+   6. `codechain apply -block-nacks`
 
    7. `make prefix=TMPDIR/local`
 
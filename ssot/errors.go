@@ -24,3 +24,6 @@ var ErrTXTNoValidHead = errors.New("ssot: no valid TXT record for head found")
 
 // ErrTXTNoValidURL is returned if no valid TXT record for URL could be found.
 var ErrTXTNoValidURL = errors.New("ssot: no valid TXT record for URL found")
+
+// ErrSignedHeadVersion is returned if the version of a signed head is not supported.
+var ErrSignedHeadVersion = errors.New("ssot: signed head version not supported")
@@ -2,21 +2,28 @@ package ssot
 
 import (
 	"crypto/ed25519"
+	"encoding/binary"
 	"time"
+
+	"github.com/frankbraun/codechain/util/base64"
+	"github.com/frankbraun/codechain/util/signer"
 )
 
-// SignHeadV2 signs the given Codechain head.
-func SignHeadV2(
+// NewUnsignedHeadV2 returns a new signed head for the given Codechain head
+// without signature. The signature has to be set with SetSignature before it
+// can be published. It is used for offline signing, otherwise use
+// SignHeadV2.
+func NewUnsignedHeadV2(
 	head [32]byte,
 	line int,
 	counter uint64,
-	secKey [64]byte,
+	pubKey [32]byte,
 	pubKeyRotate *[32]byte,
 	validity time.Duration,
-) (SignedHead, error) {
+) (*SignedHeadV2, error) {
 	var sh SignedHeadV2
 	sh.version = 2
-	copy(sh.pubKey[:], secKey[32:])
+	sh.pubKey = pubKey
 	if pubKeyRotate != nil {
 		copy(sh.pubKeyRotate[:], pubKeyRotate[:])
 	}
@@ -32,8 +39,73 @@ func SignHeadV2(
 	sh.counter = counter
 	copy(sh.head[:], head[:])
 	sh.line = uint32(line)
+	return &sh, nil
+}
+
+// Message returns the message which has to be signed for signed head sh.
+func (sh *SignedHeadV2) Message() []byte {
 	msg := sh.marshal()
-	sig := ed25519.Sign(secKey[:], msg[:])
-	copy(sh.signature[:], sig)
+	return msg[:]
+}
+
+// SetSignature sets the signature of signed head sh after verifying it.
+func (sh *SignedHeadV2) SetSignature(sig [64]byte) error {
+	msg := sh.marshal()
+	if !ed25519.Verify(sh.pubKey[:], msg[:], sig[:]) {
+		return ErrSignedHeadSignature
+	}
+	sh.signature = sig
+	return nil
+}
+
+// MarshalUnsigned encodes signed head sh without signature as base64.
+func (sh *SignedHeadV2) MarshalUnsigned() string {
+	msg := sh.marshal()
+	return base64.Encode(msg[:])
+}
+
+// UnmarshalUnsignedV2 decodes a base64 encoded signed head without
+// signature (see MarshalUnsigned).
+func UnmarshalUnsignedV2(unsignedHead string) (*SignedHeadV2, error) {
+	m, err := base64.Decode(unsignedHead, 125)
+	if err != nil {
+		return nil, err
+	}
+	var sh SignedHeadV2
+	sh.version = m[0]
+	if sh.version != 2 {
+		return nil, ErrSignedHeadVersion
+	}
+	copy(sh.pubKey[:], m[1:33])
+	copy(sh.pubKeyRotate[:], m[33:65])
+	sh.validFrom = int64(binary.BigEndian.Uint64(m[65:73]))
+	sh.validTo = int64(binary.BigEndian.Uint64(m[73:81]))
+	sh.counter = binary.BigEndian.Uint64(m[81:89])
+	copy(sh.head[:], m[89:121])
+	sh.line = binary.BigEndian.Uint32(m[121:125])
 	return &sh, nil
 }
+
+// SignHeadV2 signs the given Codechain head with signer s.
+func SignHeadV2(
+	head [32]byte,
+	line int,
+	counter uint64,
+	s signer.Signer,
+	pubKeyRotate *[32]byte,
+	validity time.Duration,
+) (SignedHead, error) {
+	sh, err := NewUnsignedHeadV2(head, line, counter, s.PublicKey(),
+		pubKeyRotate, validity)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := s.Sign(sh.Message())
+	if err != nil {
+		return nil, err
+	}
+	if err := sh.SetSignature(sig); err != nil {
+		return nil, err
+	}
+	return sh, nil
+}
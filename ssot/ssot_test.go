@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/frankbraun/codechain/util/hex"
+	"github.com/frankbraun/codechain/util/signer"
 )
 
 const headStr = "73fe1313fd924854f149021e969546bce6052eca0c22b2b91245cb448410493c"
@@ -26,21 +27,21 @@ func TestSignedHead(t *testing.T) {
 	copy(sk[:], sec)
 
 	// error cases
-	_, err = SignHeadV2(head, 2, 0, sk, nil, MinimumValidity-time.Second)
+	_, err = SignHeadV2(head, 2, 0, signer.New(sk), nil, MinimumValidity-time.Second)
 	if err != ErrValidityTooShort {
 		t.Error("SignHeadV2() should fail with ErrValidityTooShort")
 	}
-	_, err = SignHeadV2(head, 2, 0, sk, nil, MaximumValidity+time.Second)
+	_, err = SignHeadV2(head, 2, 0, signer.New(sk), nil, MaximumValidity+time.Second)
 	if err != ErrValidityTooLong {
 		t.Error("SignHeadV2() should fail with ErrValidityTooLong")
 	}
 
 	// happy cases
-	_, err = SignHeadV2(head, 2, 0, sk, nil, MinimumValidity)
+	_, err = SignHeadV2(head, 2, 0, signer.New(sk), nil, MinimumValidity)
 	if err != nil {
 		t.Fatalf("SignHeadV2() failed: %v", err)
 	}
-	sh, err := SignHeadV2(head, 2, 0, sk, nil, MaximumValidity)
+	sh, err := SignHeadV2(head, 2, 0, signer.New(sk), nil, MaximumValidity)
 	if err != nil {
 		t.Fatalf("SignHeadV2() failed: %v", err)
 	}
@@ -51,7 +52,7 @@ func TestSignedHead(t *testing.T) {
 	}
 
 	// V2
-	sh2, err := SignHeadV2(head, 2, 0, sk, nil, MinimumValidity)
+	sh2, err := SignHeadV2(head, 2, 0, signer.New(sk), nil, MinimumValidity)
 	if err != nil {
 		t.Fatalf("SignHeadV2() failed: %v", err)
 	}
@@ -7,3 +7,6 @@ import (
 // ErrCannotRemove is returned if Dir could not find a valid start to apply,
 // but cannot remove the directory.
 var ErrCannotRemove = errors.New("sync: could not find a valid start to apply, try with empty dir")
+
+// errNoReverse is returned if Dir cannot sync back with reverse patches.
+var errNoReverse = errors.New("sync: cannot sync back with reverse patches")
@@ -2,14 +2,16 @@
 package sync
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
 	"github.com/frankbraun/codechain/patchfile"
 	"github.com/frankbraun/codechain/tree"
 	"github.com/frankbraun/codechain/util"
-	"github.com/frankbraun/codechain/util/def"
+	"github.com/frankbraun/codechain/util/file"
 	"github.com/frankbraun/codechain/util/hex"
 	"github.com/frankbraun/codechain/util/log"
 )
@@ -21,21 +23,39 @@ import (
 // applying one patch file after another, iterating through the treeHashes
 // array until the targetHash is found.
 //
+// revoked maps revoked tree hashes to the reason of their revocation, it can
+// be nil. If targetHash has been revoked, Dir refuses to sync to it.
+//
 // In order to find a suitable start, the tree hash of treeDir is calculated
 // and treeHashes is searched for the result.
 //
+// If treeDir is at a tree hash after targetHash and reverseDir is not empty,
+// Dir steps back to targetHash with the reverse patches from reverseDir,
+// verifying every intermediate tree hash. Reverse patches are named after the
+// tree hash they start from and are written to reverseDir by Dir itself,
+// whenever it applies a patch (see patchfile.Journal.WriteReverse).
+//
 // If no suitable start can be found and canRemoveDir is true, all contents of
 // treeDir are removed and the patches are applied starting from
-// tree.EmptyHash. Otherwise, ErrCannotRemove is returned.
+// tree.EmptyHash. If treeDir is at a revoked tree hash, all contents except
+// for the excludePaths are removed, even if canRemoveDir is false (so revoked
+// releases can always be left). Otherwise, ErrCannotRemove is returned. The
+// removal is part of the transaction described below.
 //
 // Patch files (see patchfile package) are named after the outgoing (source)
 // tree hash and must lead to the targetDir having the tree hash of the next
-// treeHashes entry after they have been applied.
+// treeHashes entry after they have been applied. All patchfile versions
+// supported by patchfile.Apply are accepted, including file moves and copies.
+//
+// The patches are applied in a single transaction (see patchfile.Journal): If
+// one of them fails (or Dir is interrupted), treeDir is restored to its
+// original state.
 //
 // The paths given in excludePaths are excluded from all tree hash calculations.
 func Dir(
-	treeDir, targetHash, patchDir string,
+	treeDir, targetHash, patchDir, reverseDir string,
 	treeHashes []string,
+	revoked map[string]string,
 	excludePaths []string,
 	canRemoveDir bool,
 ) error {
@@ -46,6 +66,9 @@ func Dir(
 	if !util.ContainsString(treeHashes, targetHash) {
 		return fmt.Errorf("sync: targetHash unknown: %s", targetHash)
 	}
+	if reason, ok := revoked[targetHash]; ok {
+		return fmt.Errorf("sync: targetHash has been revoked: %s (%s)", targetHash, reason)
+	}
 
 	hash, err := tree.Hash(treeDir, excludePaths)
 	if err != nil {
@@ -80,52 +103,194 @@ func Dir(
 			break
 		}
 	}
-	if i == idx {
-		if !canRemoveDir {
-			return ErrCannotRemove
+	if i == idx && reverseDir != "" {
+		err := back(treeDir, hashStr, idx, reverseDir, treeHashes, excludePaths)
+		if err == nil {
+			return nil
 		}
-		log.Println("could not find a valid start to apply, trying with empty dir...")
-		if err := os.RemoveAll(treeDir); err != nil {
-			return err
+		if err != errNoReverse {
+			if !canRemoveDir {
+				return err
+			}
+			log.Printf("could not sync back with reverse patches: %v", err)
 		}
-		if err := os.Mkdir(treeDir, 0755); err != nil {
-			return err
+	}
+	var (
+		removeAll     bool
+		removeExclude []string
+	)
+	if i == idx {
+		if _, ok := revoked[hashStr]; ok && !canRemoveDir {
+			// treeDir is exactly at a revoked tree hash, so it can be
+			// removed safely (except for the excluded paths) to leave it
+			log.Println("leaving revoked tree hash, trying with empty dir...")
+			removeExclude = excludePaths
+		} else if !canRemoveDir {
+			return ErrCannotRemove
+		} else {
+			log.Println("could not find a valid start to apply, trying with empty dir...")
 		}
+		removeAll = true
 		i = 0
 	}
 
-	for ; i <= idx; i++ {
-		h := treeHashes[i]
-
-		// verify previous patch
-		p, err := tree.Hash(treeDir, excludePaths)
-		if err != nil {
+	if reverseDir != "" {
+		if err := os.MkdirAll(reverseDir, 0755); err != nil {
 			return err
 		}
-		if hex.Encode(p[:]) != h {
-			return fmt.Errorf("sync: patch failed to create target: %s", h)
+	}
+
+	// apply all patches in a single transaction
+	j, err := patchfile.NewJournal(treeDir)
+	if err != nil {
+		return err
+	}
+	return j.Run(func() error {
+		// remove contents of treeDir as part of the transaction, so they are
+		// restored if a patch fails
+		if removeAll {
+			if err := j.RemoveAll(removeExclude); err != nil {
+				return err
+			}
+		}
+		for ; i <= idx; i++ {
+			h := treeHashes[i]
+
+			// verify previous patch
+			p, err := tree.Hash(treeDir, excludePaths)
+			if err != nil {
+				return err
+			}
+			if hex.Encode(p[:]) != h {
+				return fmt.Errorf("sync: patch failed to create target: %s", h)
+			}
+			log.Printf("verified patch: %s\n", h)
+
+			// check if we are done
+			if h == targetHash {
+				break
+			}
+
+			// open patch file
+			patch, err := os.Open(filepath.Join(patchDir, h))
+			if err != nil {
+				return err
+			}
+
+			// apply patch
+			log.Printf("applying patch: %s\n", h)
+			err = j.Apply(patch, excludePaths)
+			if err != nil {
+				patch.Close()
+				return err
+			}
+			patch.Close()
+
+			// write reverse patch, if necessary
+			if reverseDir != "" {
+				if err := writeReverse(j, reverseDir, treeHashes[i+1]); err != nil {
+					return err
+				}
+			}
 		}
-		log.Printf("verified patch: %s\n", h)
+		return nil
+	})
+}
 
-		// check if we are done
-		if h == targetHash {
+// writeReverse writes the reverse patch for the patch last applied with
+// journal j to reverseDir, if it does not exist yet. h is the tree hash the
+// reverse patch starts from.
+func writeReverse(j *patchfile.Journal, reverseDir, h string) error {
+	filename := filepath.Join(reverseDir, h)
+	exists, err := file.Exists(filename)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	var reverse bytes.Buffer
+	if err := j.WriteReverse(&reverse); err != nil {
+		return err
+	}
+	log.Printf("writing reverse patch: %s\n", h)
+	return ioutil.WriteFile(filename, reverse.Bytes(), 0644)
+}
+
+// back syncs treeDir from the tree hash hashStr back to treeHashes[idx] by
+// applying the reverse patches from reverseDir in a single transaction.
+// If hashStr cannot be found after idx or not all necessary reverse patches
+// exist, errNoReverse is returned.
+func back(
+	treeDir, hashStr string,
+	idx int,
+	reverseDir string,
+	treeHashes []string,
+	excludePaths []string,
+) error {
+	// find start position after target
+	i := idx + 1
+	for ; i < len(treeHashes); i++ {
+		if hashStr == treeHashes[i] {
+			log.Printf("start position %d found (going back)", i)
 			break
 		}
+	}
+	if i == len(treeHashes) {
+		return errNoReverse
+	}
 
-		// open patch file
-		patch, err := os.Open(filepath.Join(patchDir, h))
+	// make sure all reverse patches exist
+	for k := i; k > idx; k-- {
+		exists, err := file.Exists(filepath.Join(reverseDir, treeHashes[k]))
 		if err != nil {
 			return err
 		}
+		if !exists {
+			log.Printf("reverse patch missing: %s\n", treeHashes[k])
+			return errNoReverse
+		}
+	}
 
-		// apply patch
-		log.Printf("applying patch: %s\n", h)
-		err = patchfile.Apply(treeDir, patch, def.ExcludePaths)
-		if err != nil {
+	// apply all reverse patches in a single transaction
+	j, err := patchfile.NewJournal(treeDir)
+	if err != nil {
+		return err
+	}
+	return j.Run(func() error {
+		for ; i >= idx; i-- {
+			h := treeHashes[i]
+
+			// verify previous reverse patch
+			p, err := tree.Hash(treeDir, excludePaths)
+			if err != nil {
+				return err
+			}
+			if hex.Encode(p[:]) != h {
+				return fmt.Errorf("sync: reverse patch failed to create target: %s", h)
+			}
+			log.Printf("verified reverse patch: %s\n", h)
+
+			// check if we are done
+			if i == idx {
+				break
+			}
+
+			// open reverse patch file
+			patch, err := os.Open(filepath.Join(reverseDir, h))
+			if err != nil {
+				return err
+			}
+
+			// apply reverse patch
+			log.Printf("applying reverse patch: %s\n", h)
+			err = j.Apply(patch, excludePaths)
+			if err != nil {
+				patch.Close()
+				return err
+			}
 			patch.Close()
-			return err
 		}
-		patch.Close()
-	}
-	return nil
+		return nil
+	})
 }
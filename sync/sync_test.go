@@ -4,12 +4,17 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/frankbraun/codechain/hashchain"
+	"github.com/frankbraun/codechain/patchfile"
 	"github.com/frankbraun/codechain/sync"
+	"github.com/frankbraun/codechain/tree"
 	"github.com/frankbraun/codechain/util/def"
 	"github.com/frankbraun/codechain/util/file"
+	"github.com/frankbraun/codechain/util/hex"
 )
 
 func TestDir(t *testing.T) {
@@ -39,7 +44,7 @@ func TestDir(t *testing.T) {
 	treeHashes := c.TreeHashes()
 
 	patchDir := filepath.Join("..", def.PatchDir)
-	err = sync.Dir(tmpdir, treeHashes[len(treeHashes)-1], patchDir, treeHashes,
+	err = sync.Dir(tmpdir, treeHashes[len(treeHashes)-1], patchDir, "", treeHashes, nil,
 		def.ExcludePaths, false)
 	if err != nil {
 		t.Fatalf("sync.Dir() failed: %v", err)
@@ -50,15 +55,285 @@ func TestDir(t *testing.T) {
 		t.Fatalf("os.Remove() failed: %v", err)
 	}
 
-	err = sync.Dir(tmpdir, treeHashes[len(treeHashes)-1], patchDir, treeHashes,
+	err = sync.Dir(tmpdir, treeHashes[len(treeHashes)-1], patchDir, "", treeHashes, nil,
 		def.ExcludePaths, false)
 	if err != sync.ErrCannotRemove {
 		t.Fatalf("sync.Dir() should fail with sync.ErrCannotRemove")
 	}
 
-	err = sync.Dir(tmpdir, treeHashes[len(treeHashes)-1], patchDir, treeHashes,
+	err = sync.Dir(tmpdir, treeHashes[len(treeHashes)-1], patchDir, "", treeHashes, nil,
 		def.ExcludePaths, true)
 	if err != nil {
 		t.Fatalf("sync.Dir() failed: %v", err)
 	}
 }
+
+func TestDirRevoked(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "sync_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	targetHash := "5998c63aca42e471297c0fa353538a93d4d4cfafe9a672df6989e694188b4a92"
+	treeHashes := []string{tree.EmptyHash, targetHash}
+	revoked := map[string]string{targetHash: "broken release"}
+	err = sync.Dir(tmpdir, targetHash, tmpdir, "", treeHashes, revoked,
+		def.ExcludePaths, false)
+	if err == nil {
+		t.Fatal("sync.Dir() should fail for revoked target hash")
+	}
+	if !strings.Contains(err.Error(), "broken release") {
+		t.Errorf("sync.Dir() error should contain reason: %v", err)
+	}
+}
+
+// writePatches writes patches between the directories dirs to patchDir and
+// returns the tree hashes of dirs.
+func writePatches(t *testing.T, patchDir string, dirs []string) []string {
+	if err := os.Mkdir(patchDir, 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	var treeHashes []string
+	for i, dir := range dirs {
+		h, err := tree.Hash(dir, nil)
+		if err != nil {
+			t.Fatalf("tree.Hash() failed: %v", err)
+		}
+		treeHashes = append(treeHashes, hex.Encode(h[:]))
+		if i == 0 {
+			continue
+		}
+		f, err := os.Create(filepath.Join(patchDir, treeHashes[i-1]))
+		if err != nil {
+			t.Fatalf("os.Create() failed: %v", err)
+		}
+		if err := patchfile.Diff(patchfile.Version, f, dirs[i-1], dir, nil); err != nil {
+			t.Fatalf("patchfile.Diff() failed: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("f.Close() failed: %v", err)
+		}
+	}
+	return treeHashes
+}
+
+func TestDirMove(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "sync_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// create releases: empty, hello.go, moved and copied hello.go
+	hello := filepath.Join("..", "patchfile", "testdata", "hello", "hello.go")
+	dirs := []string{
+		filepath.Join(tmpdir, "empty"),
+		filepath.Join(tmpdir, "hello"),
+		filepath.Join(tmpdir, "moved"),
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("os.MkdirAll() failed: %v", err)
+		}
+	}
+	if err := file.Copy(hello, filepath.Join(dirs[1], "hello.go")); err != nil {
+		t.Fatalf("file.Copy() failed: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dirs[2], "cmd"), 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	if err := file.Copy(hello, filepath.Join(dirs[2], "cmd", "hello.go")); err != nil {
+		t.Fatalf("file.Copy() failed: %v", err)
+	}
+	if err := file.Copy(hello, filepath.Join(dirs[2], "main.go")); err != nil {
+		t.Fatalf("file.Copy() failed: %v", err)
+	}
+
+	// write patches
+	patchDir := filepath.Join(tmpdir, "patches")
+	treeHashes := writePatches(t, patchDir, dirs)
+
+	// sync
+	treeDir := filepath.Join(tmpdir, "tree")
+	if err := os.Mkdir(treeDir, 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	err = sync.Dir(treeDir, treeHashes[2], patchDir, "", treeHashes, nil, nil, false)
+	if err != nil {
+		t.Fatalf("sync.Dir() failed: %v", err)
+	}
+}
+
+func TestDirRollback(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "sync_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// create releases: empty, one file, two files, three files
+	var dirs []string
+	for i := 0; i < 4; i++ {
+		dir := filepath.Join(tmpdir, "release"+strconv.Itoa(i))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("os.Mkdir() failed: %v", err)
+		}
+		for k := 0; k < i; k++ {
+			fn := filepath.Join(dir, "file"+strconv.Itoa(k))
+			if err := ioutil.WriteFile(fn, []byte(strconv.Itoa(k)), 0644); err != nil {
+				t.Fatalf("ioutil.WriteFile() failed: %v", err)
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+	patchDir := filepath.Join(tmpdir, "patches")
+	treeHashes := writePatches(t, patchDir, dirs)
+
+	// sync to first release
+	treeDir := filepath.Join(tmpdir, "tree")
+	if err := os.Mkdir(treeDir, 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	err = sync.Dir(treeDir, treeHashes[1], patchDir, "", treeHashes, nil, nil, false)
+	if err != nil {
+		t.Fatalf("sync.Dir() failed: %v", err)
+	}
+
+	// corrupt last patch
+	fn := filepath.Join(patchDir, treeHashes[2])
+	patch, err := ioutil.ReadFile(fn)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() failed: %v", err)
+	}
+	patch = []byte(strings.Replace(string(patch), treeHashes[3], tree.EmptyHash, 1))
+	if err := ioutil.WriteFile(fn, patch, 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile() failed: %v", err)
+	}
+
+	// failed sync must restore first release
+	err = sync.Dir(treeDir, treeHashes[3], patchDir, "", treeHashes, nil, nil, false)
+	if err != patchfile.ErrTreeHashFinishMismatch {
+		t.Fatalf("sync.Dir() should fail with patchfile.ErrTreeHashFinishMismatch (has %v)", err)
+	}
+	h, err := tree.Hash(treeDir, nil)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+	if hex.Encode(h[:]) != treeHashes[1] {
+		t.Error("sync.Dir() did not restore tree after failure")
+	}
+
+	// failed sync must also restore a tree which has to be removed first
+	extra := filepath.Join(treeDir, "sub", "extra")
+	if err := os.MkdirAll(filepath.Dir(extra), 0755); err != nil {
+		t.Fatalf("os.MkdirAll() failed: %v", err)
+	}
+	if err := ioutil.WriteFile(extra, []byte("extra"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile() failed: %v", err)
+	}
+	before, err := tree.Hash(treeDir, nil)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+	err = sync.Dir(treeDir, treeHashes[3], patchDir, "", treeHashes, nil, nil, true)
+	if err != patchfile.ErrTreeHashFinishMismatch {
+		t.Fatalf("sync.Dir() should fail with patchfile.ErrTreeHashFinishMismatch (has %v)", err)
+	}
+	after, err := tree.Hash(treeDir, nil)
+	if err != nil {
+		t.Fatalf("tree.Hash() failed: %v", err)
+	}
+	if *after != *before {
+		t.Error("sync.Dir() did not restore removed tree after failure")
+	}
+}
+
+func TestDirReverse(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "sync_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// create releases: empty, one file, changed file and new file in subdir,
+	// deleted file
+	var dirs []string
+	for i := 0; i < 4; i++ {
+		dirs = append(dirs, filepath.Join(tmpdir, "release"+strconv.Itoa(i)))
+	}
+	files := []map[string]string{
+		{},
+		{"a.txt": "a\n"},
+		{"a.txt": "a\nb\n", "sub/c.txt": "c\n"},
+		{"sub/c.txt": "c\n"},
+	}
+	for i, dir := range dirs {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("os.Mkdir() failed: %v", err)
+		}
+		for name, content := range files[i] {
+			fn := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+				t.Fatalf("os.MkdirAll() failed: %v", err)
+			}
+			if err := ioutil.WriteFile(fn, []byte(content), 0644); err != nil {
+				t.Fatalf("ioutil.WriteFile() failed: %v", err)
+			}
+		}
+	}
+	patchDir := filepath.Join(tmpdir, "patches")
+	treeHashes := writePatches(t, patchDir, dirs)
+
+	// sync to last release, which writes reverse patches
+	treeDir := filepath.Join(tmpdir, "tree")
+	if err := os.Mkdir(treeDir, 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	reverseDir := filepath.Join(tmpdir, "reverse")
+	err = sync.Dir(treeDir, treeHashes[3], patchDir, reverseDir, treeHashes, nil, nil, false)
+	if err != nil {
+		t.Fatalf("sync.Dir() failed: %v", err)
+	}
+	for _, h := range treeHashes[1:] {
+		exists, err := file.Exists(filepath.Join(reverseDir, h))
+		if err != nil {
+			t.Fatalf("file.Exists() failed: %v", err)
+		}
+		if !exists {
+			t.Fatalf("reverse patch %s missing", h)
+		}
+	}
+
+	// sync back without removing the directory
+	for _, i := range []int{1, 2, 0} {
+		err = sync.Dir(treeDir, treeHashes[i], patchDir, reverseDir, treeHashes, nil, nil, false)
+		if err != nil {
+			t.Fatalf("sync.Dir() failed: %v", err)
+		}
+		h, err := tree.Hash(treeDir, nil)
+		if err != nil {
+			t.Fatalf("tree.Hash() failed: %v", err)
+		}
+		if hex.Encode(h[:]) != treeHashes[i] {
+			t.Errorf("sync.Dir() did not sync back to release %d", i)
+		}
+	}
+
+	// syncing back without reverse patches is not possible
+	err = sync.Dir(treeDir, treeHashes[3], patchDir, reverseDir, treeHashes, nil, nil, false)
+	if err != nil {
+		t.Fatalf("sync.Dir() failed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(reverseDir, treeHashes[2])); err != nil {
+		t.Fatalf("os.Remove() failed: %v", err)
+	}
+	err = sync.Dir(treeDir, treeHashes[1], patchDir, reverseDir, treeHashes, nil, nil, false)
+	if err != sync.ErrCannotRemove {
+		t.Fatalf("sync.Dir() should fail with sync.ErrCannotRemove (has %v)", err)
+	}
+	err = sync.Dir(treeDir, treeHashes[1], patchDir, reverseDir, treeHashes, nil, nil, true)
+	if err != nil {
+		t.Fatalf("sync.Dir() failed: %v", err)
+	}
+}
@@ -10,7 +10,7 @@ order (with filepath.Walk) and printed in this format:
   m xxx filename
 
 Where:
-  m        is the mode ('f' or 'x')
+  m        is the mode ('f', 'x', or 'l')
   xxx      is the SHA256 hash for the file in hex notation
   filename is the file name with directory prefix starting at root
 
@@ -26,7 +26,15 @@ contain files). Entries start with 'f' if it is a regular file (read and
 write permission for user) and with 'x' if it is an executable (read,
 write, and executable for user).
 
-The directory tree must only contain directories, regular files, or executables.
+Symbolic links are listed with mode 'l' and the SHA256 hash of the link
+target (with slashes as path separators). Link targets must be relative and
+must not escape the directory tree (see CheckSymlink). Directory trees without
+symbolic links have the same tree list as before symbolic links were
+supported, therefore their tree hashes are unchanged. Patchfiles support
+symbolic links since version 5 (see patchfile package).
+
+The directory tree must only contain directories, regular files, executables,
+or symbolic links.
 
 The deterministic tree list serves as the basis for a hash of a directory tree
 (the tree hash), which is the SHA256 hash of the tree list in hex notation.
@@ -36,13 +44,19 @@ package tree
 import (
 	"bytes"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
+// ErrSymlinkEscapes is returned if the target of a symbolic link escapes the
+// directory tree.
+var ErrSymlinkEscapes = errors.New("tree: symlink target escapes directory tree")
+
 // SHA256 returns the SHA256 hash of the file with given path.
 func SHA256(path string) (*[32]byte, error) {
 	f, err := os.Open(path)
@@ -60,9 +74,50 @@ func SHA256(path string) (*[32]byte, error) {
 	return &hash, nil
 }
 
+// SymlinkHash returns the SHA256 hash of the target of the symbolic link with
+// given path.
+func SymlinkHash(path string) (*[32]byte, error) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256([]byte(filepath.ToSlash(target)))
+	return &hash, nil
+}
+
+// CheckSymlink checks that the target of the symbolic link with the canonical
+// filename name (with directory prefix starting at root) stays in the
+// directory tree. That is, target must be relative and must only contain ".."
+// elements at the start, which must not lead outside of the root. Otherwise,
+// ErrSymlinkEscapes is returned.
+//
+// Restricting ".." elements to the start of the target makes sure that
+// symbolic links contained in the target path cannot be used to escape the
+// directory tree, as long as they follow the same rules.
+func CheckSymlink(name, target string) error {
+	target = filepath.ToSlash(target)
+	if target == "" || path.IsAbs(target) || filepath.IsAbs(target) {
+		return ErrSymlinkEscapes
+	}
+	elems := strings.Split(target, "/")
+	up := 0
+	for up < len(elems) && elems[up] == ".." {
+		up++
+	}
+	for _, elem := range elems[up:] {
+		if elem == ".." {
+			return ErrSymlinkEscapes
+		}
+	}
+	if up > strings.Count(name, "/") {
+		return ErrSymlinkEscapes
+	}
+	return nil
+}
+
 // ListEntry describes a directory tree entry.
 type ListEntry struct {
-	Mode     rune     // 'f' (regular) or 'x' (binary)
+	Mode     rune     // 'f' (regular), 'x' (binary), or 'l' (symlink)
 	Filename string   // Including directory path starting from root
 	Hash     [32]byte // SHA256 hash
 }
@@ -81,8 +136,9 @@ func List(root string, excludePaths []string) ([]ListEntry, error) {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && !info.Mode().IsRegular() {
-			return fmt.Errorf("%s: neither directory nor normal file", path)
+		isSymlink := info.Mode()&os.ModeSymlink != 0
+		if !info.IsDir() && !info.Mode().IsRegular() && !isSymlink {
+			return fmt.Errorf("%s: neither directory nor normal file nor symlink", path)
 		}
 		if path == root {
 			return nil
@@ -103,6 +159,18 @@ func List(root string, excludePaths []string) ([]ListEntry, error) {
 				}
 			}
 		}
+		if isSymlink {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := CheckSymlink(canonical, target); err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+			h := sha256.Sum256([]byte(filepath.ToSlash(target)))
+			entries = append(entries, ListEntry{'l', canonical, h})
+			return nil
+		}
 		perm := info.Mode().Perm() & os.ModePerm
 		if info.IsDir() {
 			if perm&0700 != 0700 {
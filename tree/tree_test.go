@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -94,3 +95,72 @@ func TestTestdataChdir(t *testing.T) {
 		t.Errorf("Hash() should return the testdataHash")
 	}
 }
+
+func TestCheckSymlink(t *testing.T) {
+	testCases := []struct {
+		name   string
+		target string
+		valid  bool
+	}{
+		{"link", "foo.txt", true},
+		{"link", "bar/baz.txt", true},
+		{"bar/link", "../foo.txt", true},
+		{"bar/link", "..", true},
+		{"bar/baz/link", "../../foo.txt", true},
+		{"link", "", false},
+		{"link", "/etc/passwd", false},
+		{"link", "../foo.txt", false},
+		{"bar/link", "../../foo.txt", false},
+		{"link", "bar/../foo.txt", false},
+		{"link", "bar/../../foo.txt", false},
+	}
+	for _, testCase := range testCases {
+		err := CheckSymlink(testCase.name, testCase.target)
+		if testCase.valid && err != nil {
+			t.Errorf("CheckSymlink(%s, %s) failed: %v", testCase.name, testCase.target, err)
+		}
+		if !testCase.valid && err != ErrSymlinkEscapes {
+			t.Errorf("CheckSymlink(%s, %s) should fail with ErrSymlinkEscapes",
+				testCase.name, testCase.target)
+		}
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "tree_test")
+	if err != nil {
+		t.Fatalf("TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	if err := os.Mkdir(filepath.Join(tmpdir, "bar"), 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(tmpdir, "foo.txt"), []byte("foo\n"), 0644)
+	if err != nil {
+		t.Fatalf("ioutil.WriteFile() failed: %v", err)
+	}
+	link := filepath.Join(tmpdir, "bar", "link")
+	if err := os.Symlink(filepath.Join("..", "foo.txt"), link); err != nil {
+		t.Fatalf("os.Symlink() failed: %v", err)
+	}
+	l, err := ListBytes(tmpdir, nil)
+	if err != nil {
+		t.Fatalf("ListBytes() failed: %v", err)
+	}
+	list := `l 308ac2f9c0fb8a962826d66acbb4ecfaa9feacaf5e5e06ca5df33a6a7805ae2a bar/link
+f b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c foo.txt
+`
+	if string(l) != list {
+		t.Errorf("ListBytes() returned wrong list:\n%s", l)
+	}
+	// escaping symlink
+	if err := os.Remove(link); err != nil {
+		t.Fatalf("os.Remove() failed: %v", err)
+	}
+	if err := os.Symlink(filepath.Join("..", "..", "foo.txt"), link); err != nil {
+		t.Fatalf("os.Symlink() failed: %v", err)
+	}
+	if _, err := ListBytes(tmpdir, nil); err == nil {
+		t.Error("ListBytes() should fail for escaping symlink")
+	}
+}
@@ -0,0 +1,151 @@
+// Package bindelta implements a simple binary delta encoding.
+//
+// A delta transforms a source into a target and consists of the source length
+// and the target length (as unsigned varints) followed by a sequence of
+// instructions:
+//
+//	0x00 length data            # insert length bytes of data
+//	0x01 offset length          # copy length bytes from source at offset
+//
+// length and offset are encoded as unsigned varints. Copies are found by
+// indexing the source in blocks of BlockSize bytes, similar to rsync.
+package bindelta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// BlockSize is the size of the source blocks which are indexed for copies.
+const BlockSize = 16
+
+// Delta instructions.
+const (
+	opInsert = 0x00
+	opCopy   = 0x01
+)
+
+// ErrInvalid is returned if a delta cannot be decoded.
+var ErrInvalid = errors.New("bindelta: invalid delta")
+
+// ErrSourceLength is returned if the source length does not match the delta.
+var ErrSourceLength = errors.New("bindelta: source length does not match delta")
+
+type encoder struct {
+	buf bytes.Buffer
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) uvarint(x int) {
+	n := binary.PutUvarint(e.tmp[:], uint64(x))
+	e.buf.Write(e.tmp[:n])
+}
+
+func (e *encoder) insert(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	e.buf.WriteByte(opInsert)
+	e.uvarint(len(data))
+	e.buf.Write(data)
+}
+
+func (e *encoder) copy(offset, length int) {
+	e.buf.WriteByte(opCopy)
+	e.uvarint(offset)
+	e.uvarint(length)
+}
+
+// Encode returns a delta which transforms src into dst.
+func Encode(src, dst []byte) []byte {
+	// index source blocks
+	index := make(map[[BlockSize]byte]int)
+	var block [BlockSize]byte
+	for i := 0; i+BlockSize <= len(src); i += BlockSize {
+		copy(block[:], src[i:i+BlockSize])
+		if _, ok := index[block]; !ok {
+			index[block] = i
+		}
+	}
+	var e encoder
+	e.uvarint(len(src))
+	e.uvarint(len(dst))
+	start := 0 // start of pending insert
+	i := 0
+	for i+BlockSize <= len(dst) {
+		copy(block[:], dst[i:i+BlockSize])
+		offset, ok := index[block]
+		if !ok {
+			i++
+			continue
+		}
+		// extend match backwards into pending insert
+		length := BlockSize
+		for offset > 0 && i > start && src[offset-1] == dst[i-1] {
+			offset--
+			i--
+			length++
+		}
+		// extend match forwards
+		for offset+length < len(src) && i+length < len(dst) &&
+			src[offset+length] == dst[i+length] {
+			length++
+		}
+		e.insert(dst[start:i])
+		e.copy(offset, length)
+		i += length
+		start = i
+	}
+	e.insert(dst[start:])
+	return e.buf.Bytes()
+}
+
+// Decode applies delta to src and returns the result.
+func Decode(src, delta []byte) ([]byte, error) {
+	r := bytes.NewReader(delta)
+	srcLen, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	if srcLen != uint64(len(src)) {
+		return nil, ErrSourceLength
+	}
+	dstLen, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	var dst []byte
+	for r.Len() > 0 {
+		op, _ := r.ReadByte()
+		switch op {
+		case opInsert:
+			length, err := binary.ReadUvarint(r)
+			if err != nil || length == 0 || length > uint64(r.Len()) {
+				return nil, ErrInvalid
+			}
+			data := make([]byte, length)
+			r.Read(data)
+			dst = append(dst, data...)
+		case opCopy:
+			offset, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, ErrInvalid
+			}
+			length, err := binary.ReadUvarint(r)
+			if err != nil || length == 0 || offset > srcLen || length > srcLen-offset {
+				return nil, ErrInvalid
+			}
+			dst = append(dst, src[offset:offset+length]...)
+		default:
+			return nil, ErrInvalid
+		}
+		if uint64(len(dst)) > dstLen {
+			return nil, ErrInvalid
+		}
+	}
+	if uint64(len(dst)) != dstLen {
+		return nil, ErrInvalid
+	}
+	return dst, nil
+}
@@ -0,0 +1,64 @@
+package bindelta
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	src := make([]byte, 4096)
+	if _, err := rand.Read(src); err != nil {
+		t.Fatalf("rand.Read() failed: %v", err)
+	}
+	// change a few bytes, insert and remove some data
+	dst := append([]byte{}, src[:1000]...)
+	dst = append(dst, []byte("inserted data")...)
+	dst = append(dst, src[1000:3000]...)
+	dst = append(dst, src[3100:]...)
+	dst[10] ^= 0xff
+	dst[2000] ^= 0xff
+
+	testCases := []struct {
+		src []byte
+		dst []byte
+	}{
+		{src, dst},
+		{src, src},
+		{src, nil},
+		{nil, dst},
+		{[]byte("short"), []byte("shorter")},
+	}
+	for _, testCase := range testCases {
+		delta := Encode(testCase.src, testCase.dst)
+		res, err := Decode(testCase.src, delta)
+		if err != nil {
+			t.Fatalf("Decode() failed: %v", err)
+		}
+		if !bytes.Equal(res, testCase.dst) {
+			t.Error("Encode() + Decode() failed")
+		}
+	}
+	if delta := Encode(src, dst); len(delta) > 100 {
+		t.Errorf("delta too large: %d bytes", len(delta))
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	src := []byte("0123456789abcdef0123456789abcdef")
+	delta := Encode(src, append(src, 'x'))
+	if _, err := Decode(src[1:], delta); err != ErrSourceLength {
+		t.Error("Decode() should fail with ErrSourceLength")
+	}
+	for i := 1; i < len(delta); i++ {
+		if _, err := Decode(src, delta[:i]); err != ErrInvalid {
+			t.Errorf("Decode() of truncated delta (%d) should fail with ErrInvalid", i)
+		}
+	}
+	if _, err := Decode(src, []byte{32, 1, 0x01, 20, 20}); err != ErrInvalid {
+		t.Error("Decode() should fail with ErrInvalid for copy out of range")
+	}
+	if _, err := Decode(src, []byte{32, 1, 0x02}); err != ErrInvalid {
+		t.Error("Decode() should fail with ErrInvalid for unknown instruction")
+	}
+}
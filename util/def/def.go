@@ -36,6 +36,10 @@ func init() {
 // to store secret key files
 const SecretsSubDir = "secrets"
 
+// CheckpointsSubDir is the default subdirectory of a tool's home directory
+// used to store the checkpoint files of hash chains.
+const CheckpointsSubDir = "checkpoints"
+
 // CodechainHeadName is the TXT entry used for Codechain's secpkg heads.
 const CodechainHeadName = "_codechain-head."
 
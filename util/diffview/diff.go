@@ -0,0 +1,259 @@
+// Package diffview implements a simple paged diff viewer for the terminal,
+// which shows the differences between two directory trees file by file.
+// It is an alternative to git.DiffPager for systems without Git.
+package diffview
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/frankbraun/codechain/tree"
+	"github.com/frankbraun/go-diff/diffmatchpatch"
+)
+
+// Status of a changed file.
+const (
+	Added    = "added"
+	Deleted  = "deleted"
+	Modified = "modified"
+	Mode     = "mode" // only the mode changed
+)
+
+// contextLines is the number of unchanged lines shown around changes.
+const contextLines = 3
+
+// File describes the changes of a single file between two directory trees.
+type File struct {
+	Name   string   // filename with directory path starting from root
+	Status string   // Added, Deleted, Modified, or Mode
+	ModeA  rune     // mode in tree a ('f', 'x', or 'l'), 0 if not present
+	ModeB  rune     // mode in tree b ('f', 'x', or 'l'), 0 if not present
+	Binary bool     // file is binary in tree a or b (the diff is not shown)
+	Lines  []string // diff lines in unified format, without newlines
+}
+
+// Files returns the changes between the directory trees rooted at a and b in
+// lexical order of filenames.
+func Files(a, b string, excludePaths []string) ([]File, error) {
+	listA, err := tree.List(a, excludePaths)
+	if err != nil {
+		return nil, err
+	}
+	listB, err := tree.List(b, excludePaths)
+	if err != nil {
+		return nil, err
+	}
+	entriesA := make(map[string]tree.ListEntry)
+	for _, e := range listA {
+		entriesA[e.Filename] = e
+	}
+	entriesB := make(map[string]tree.ListEntry)
+	for _, e := range listB {
+		entriesB[e.Filename] = e
+	}
+	var names []string
+	for _, e := range listA {
+		names = append(names, e.Filename)
+	}
+	for _, e := range listB {
+		if _, ok := entriesA[e.Filename]; !ok {
+			names = append(names, e.Filename)
+		}
+	}
+	sort.Strings(names)
+	var files []File
+	for _, name := range names {
+		ea, inA := entriesA[name]
+		eb, inB := entriesB[name]
+		f := File{Name: name}
+		switch {
+		case !inA:
+			f.Status = Added
+			f.ModeB = eb.Mode
+		case !inB:
+			f.Status = Deleted
+			f.ModeA = ea.Mode
+		case ea.Hash == eb.Hash && ea.Mode == eb.Mode:
+			continue // unchanged
+		case ea.Hash == eb.Hash && ea.Mode != 'l' && eb.Mode != 'l':
+			f.Status = Mode
+			f.ModeA = ea.Mode
+			f.ModeB = eb.Mode
+		default:
+			f.Status = Modified
+			f.ModeA = ea.Mode
+			f.ModeB = eb.Mode
+		}
+		if err := f.diff(a, b); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// readContent reads the content of the file name with mode in directory dir.
+// For symlinks the link target (followed by a newline) is returned.
+func readContent(dir, name string, mode rune) ([]byte, error) {
+	filename := filepath.Join(dir, filepath.FromSlash(name))
+	if mode == 'l' {
+		target, err := os.Readlink(filename)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(filepath.ToSlash(target) + "\n"), nil
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// diff sets f.Binary and f.Lines for the file f in the trees a and b.
+func (f *File) diff(a, b string) error {
+	if f.Status == Mode {
+		f.Lines = []string{fmt.Sprintf("mode changed: %c -> %c", f.ModeA, f.ModeB)}
+		return nil
+	}
+	var textA, textB []byte
+	if f.ModeA != 0 {
+		var err error
+		textA, err = readContent(a, f.Name, f.ModeA)
+		if err != nil {
+			return err
+		}
+	}
+	if f.ModeB != 0 {
+		var err error
+		textB, err = readContent(b, f.Name, f.ModeB)
+		if err != nil {
+			return err
+		}
+	}
+	if f.ModeA != 0 && f.ModeB != 0 && f.ModeA != f.ModeB {
+		f.Lines = append(f.Lines, fmt.Sprintf("mode changed: %c -> %c", f.ModeA, f.ModeB))
+	}
+	if !utf8.Valid(textA) || !utf8.Valid(textB) {
+		f.Binary = true
+		f.Lines = append(f.Lines, fmt.Sprintf("binary file differs (%d -> %d bytes)",
+			len(textA), len(textB)))
+		return nil
+	}
+	f.Lines = append(f.Lines, Unified(string(textA), string(textB))...)
+	if len(f.Lines) == 0 {
+		f.Lines = []string{"empty file"}
+	}
+	return nil
+}
+
+// diffLine is a single line of a line diff.
+type diffLine struct {
+	op    byte // ' ', '-', or '+'
+	text  string
+	noEOL bool // line is not terminated by a newline
+}
+
+// Unified returns the line diff between the texts a and b in unified format
+// (with hunk headers and three lines of context), without newlines.
+func Unified(a, b string) []string {
+	dmp := diffmatchpatch.New()
+	runesA, runesB, lineArray := dmp.DiffLinesToRunes(a, b)
+	var lines []diffLine
+	for _, d := range dmp.DiffMainRunes(runesA, runesB, false) {
+		var op byte
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			op = ' '
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		}
+		for _, r := range d.Text {
+			text := lineArray[r]
+			l := diffLine{op: op, text: strings.TrimSuffix(text, "\n")}
+			l.noEOL = len(l.text) == len(text)
+			lines = append(lines, l)
+		}
+	}
+	// group changed lines into hunks with context
+	var (
+		hunks []string
+		i     int
+		lineA = 1 // line number in a of lines[i]
+		lineB = 1 // line number in b of lines[i]
+	)
+	for i < len(lines) {
+		if lines[i].op == ' ' {
+			i++
+			lineA++
+			lineB++
+			continue
+		}
+		// hunk starts with context before the change
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+		// extend hunk until more than 2*contextLines equal lines follow
+		end := i
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+			n := 0
+			for end+n < len(lines) && lines[end+n].op == ' ' {
+				n++
+			}
+			if end+n == len(lines) || n > 2*contextLines {
+				if n > contextLines {
+					n = contextLines
+				}
+				end += n
+				break
+			}
+			end += n
+		}
+		var countA, countB int
+		for _, l := range lines[start:end] {
+			if l.op != '+' {
+				countA++
+			}
+			if l.op != '-' {
+				countB++
+			}
+		}
+		hunks = append(hunks, fmt.Sprintf("@@ -%s +%s @@",
+			hunkRange(lineA-(i-start), countA), hunkRange(lineB-(i-start), countB)))
+		for _, l := range lines[start:end] {
+			hunks = append(hunks, string(l.op)+l.text)
+			if l.noEOL {
+				hunks = append(hunks, "\\ No newline at end of file")
+			}
+		}
+		for _, l := range lines[i:end] {
+			if l.op != '+' {
+				lineA++
+			}
+			if l.op != '-' {
+				lineB++
+			}
+		}
+		i = end
+	}
+	return hunks
+}
+
+// hunkRange formats the range of a hunk header like diff(1).
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start-- // empty ranges refer to the line before
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,154 @@
+package diffview
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13"
+	expected := []string{
+		"@@ -1,6 +1,6 @@",
+		" 1",
+		" 2",
+		"-3",
+		"+three",
+		" 4",
+		" 5",
+		" 6",
+		"@@ -10,3 +10,4 @@",
+		" 10",
+		" 11",
+		" 12",
+		"+13",
+		"\\ No newline at end of file",
+	}
+	if lines := Unified(a, b); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Unified() returned wrong diff:\n%s", strings.Join(lines, "\n"))
+	}
+	expected = []string{
+		"@@ -0,0 +1,2 @@",
+		"+a",
+		"+b",
+	}
+	if lines := Unified("", "a\nb\n"); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Unified() returned wrong diff:\n%s", strings.Join(lines, "\n"))
+	}
+	if lines := Unified(a, a); len(lines) != 0 {
+		t.Errorf("Unified() should return no diff for equal texts: %v", lines)
+	}
+}
+
+func writeFile(t *testing.T, filename, content string, perm os.FileMode) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFilesViewer(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "diffview_test")
+	if err != nil {
+		t.Fatalf("TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	a := filepath.Join(tmpdir, "a")
+	b := filepath.Join(tmpdir, "b")
+	writeFile(t, filepath.Join(a, "bin"), "\xff\x00", 0644)
+	writeFile(t, filepath.Join(b, "bin"), "\xff\x01", 0644)
+	writeFile(t, filepath.Join(a, "deleted.txt"), "gone\n", 0644)
+	writeFile(t, filepath.Join(a, "dir", "same.txt"), "same\n", 0644)
+	writeFile(t, filepath.Join(b, "dir", "same.txt"), "same\n", 0644)
+	writeFile(t, filepath.Join(a, "run.sh"), "#!/bin/sh\n", 0644)
+	writeFile(t, filepath.Join(b, "run.sh"), "#!/bin/sh\n", 0755)
+	writeFile(t, filepath.Join(b, "new.txt"), "1\n2\n3\n4\n5\n", 0644)
+	files, err := Files(a, b, nil)
+	if err != nil {
+		t.Fatalf("Files() failed: %v", err)
+	}
+	var names, status []string
+	for _, f := range files {
+		names = append(names, f.Name)
+		status = append(status, f.Status)
+	}
+	if !reflect.DeepEqual(names, []string{"bin", "deleted.txt", "new.txt", "run.sh"}) {
+		t.Fatalf("Files() returned wrong files: %v", names)
+	}
+	if !reflect.DeepEqual(status, []string{Modified, Deleted, Added, Mode}) {
+		t.Errorf("Files() returned wrong status: %v", status)
+	}
+	if !files[0].Binary || files[1].Binary {
+		t.Errorf("Files() should only mark bin as binary")
+	}
+	if files[3].ModeA != 'f' || files[3].ModeB != 'x' {
+		t.Errorf("Files() returned wrong modes: %c -> %c", files[3].ModeA, files[3].ModeB)
+	}
+
+	// view first and second file, show first page of third file (hunk header
+	// plus 5 lines in pages of 3 lines), and quit
+	var out bytes.Buffer
+	v := NewViewer(files, strings.NewReader("\n\nq\n"), &out, 6)
+	if err := v.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	unviewed := v.Unviewed()
+	if !reflect.DeepEqual(unviewed, []string{"new.txt", "run.sh"}) {
+		t.Errorf("Unviewed() returned wrong files: %v", unviewed)
+	}
+	if !strings.Contains(out.String(), "binary file differs (2 -> 2 bytes)") {
+		t.Errorf("Run() should show binary file")
+	}
+
+	// first file is shown at start, go to the last file, then view the third
+	// file completely, list files, and stop at end of input
+	v = NewViewer(files, strings.NewReader("4\n3\n\nl\n"), &out, 6)
+	if err := v.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	unviewed = v.Unviewed()
+	if !reflect.DeepEqual(unviewed, []string{"deleted.txt"}) {
+		t.Errorf("Unviewed() returned wrong files: %v", unviewed)
+	}
+
+	// resume with preset marks at first unviewed file (deleted.txt), mark
+	// next file explicitly, and quit
+	var onMark []string
+	v = NewViewer(files, strings.NewReader("n\nm\nq\n"), &out, 6)
+	v.Mark([]string{"bin", "run.sh"})
+	v.OnMark = func(name string) error {
+		onMark = append(onMark, name)
+		return nil
+	}
+	if err := v.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if !reflect.DeepEqual(onMark, []string{"deleted.txt", "new.txt"}) {
+		t.Errorf("OnMark called for wrong files: %v", onMark)
+	}
+	if unviewed := v.Unviewed(); len(unviewed) != 0 {
+		t.Errorf("Unviewed() should be empty: %v", unviewed)
+	}
+
+	// failing OnMark is reported without claiming the file has been marked
+	errMark := errors.New("cannot mark")
+	out.Reset()
+	v = NewViewer(files, strings.NewReader("m\nq\n"), &out, 6)
+	v.OnMark = func(name string) error {
+		return errMark
+	}
+	if err := v.Run(); err != errMark {
+		t.Errorf("Run() should fail with errMark (has %v)", err)
+	}
+	if strings.Contains(out.String(), "marked") {
+		t.Error("Run() reported failed mark as successful")
+	}
+}
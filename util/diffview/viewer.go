@@ -0,0 +1,267 @@
+package diffview
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// defaultHeight is the number of terminal lines used, if the terminal size
+// cannot be determined.
+const defaultHeight = 24
+
+// Viewer is a paged viewer for a list of changed files. It is controlled with
+// line based commands and records which files have been viewed completely or
+// have been marked as viewed explicitly.
+type Viewer struct {
+	// OnMark is called (if not nil) when a file is marked as viewed.
+	OnMark func(name string) error
+
+	files  []File
+	viewed []bool
+	r      *bufio.Reader
+	w      io.Writer
+	page   int // number of diff lines per page
+	cur    int // index of current file
+	line   int // index of next line to show in current file
+}
+
+// NewViewer returns a new viewer for files, which reads commands from r and
+// writes to w. The diff is shown in pages which fit into a terminal with the
+// given height (in lines).
+func NewViewer(files []File, r io.Reader, w io.Writer, height int) *Viewer {
+	page := height - 3 // header and prompt
+	if page < 1 {
+		page = 1
+	}
+	return &Viewer{
+		files:  files,
+		viewed: make([]bool, len(files)),
+		r:      bufio.NewReader(r),
+		w:      w,
+		page:   page,
+	}
+}
+
+func (v *Viewer) status(i int) string {
+	f := &v.files[i]
+	s := f.Status
+	if f.Binary {
+		s += ", binary"
+	}
+	if f.ModeA != 0 && f.ModeB != 0 && f.ModeA != f.ModeB {
+		s += fmt.Sprintf(", %c -> %c", f.ModeA, f.ModeB)
+	}
+	return s
+}
+
+func (v *Viewer) list() {
+	for i := range v.files {
+		mark := " "
+		if v.viewed[i] {
+			mark = "*"
+		}
+		fmt.Fprintf(v.w, "%s %3d %s (%s)\n", mark, i+1, v.files[i].Name, v.status(i))
+	}
+}
+
+func colorLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "@@"):
+		return color.CyanString("%s", line)
+	case strings.HasPrefix(line, "+"):
+		return color.GreenString("%s", line)
+	case strings.HasPrefix(line, "-"):
+		return color.RedString("%s", line)
+	case strings.HasPrefix(line, " "), strings.HasPrefix(line, "\\"):
+		return line
+	}
+	return color.YellowString("%s", line) // mode changes and binary files
+}
+
+// Mark marks the files with the given names as viewed.
+func (v *Viewer) Mark(names []string) {
+	marked := make(map[string]bool)
+	for _, name := range names {
+		marked[name] = true
+	}
+	for i := range v.files {
+		if marked[v.files[i].Name] {
+			v.viewed[i] = true
+		}
+	}
+}
+
+// mark marks the current file as viewed.
+func (v *Viewer) mark() error {
+	if v.viewed[v.cur] {
+		return nil
+	}
+	v.viewed[v.cur] = true
+	if v.OnMark != nil {
+		return v.OnMark(v.files[v.cur].Name)
+	}
+	return nil
+}
+
+// show shows the next page of the current file.
+func (v *Viewer) show() error {
+	f := &v.files[v.cur]
+	if v.line == 0 {
+		header := fmt.Sprintf("file %d/%d: %s (%s)", v.cur+1, len(v.files), f.Name, v.status(v.cur))
+		fmt.Fprintln(v.w, color.New(color.Bold).Sprint(header))
+	}
+	end := v.line + v.page
+	if end > len(f.Lines) {
+		end = len(f.Lines)
+	}
+	for _, line := range f.Lines[v.line:end] {
+		fmt.Fprintln(v.w, colorLine(line))
+	}
+	v.line = end
+	if v.line == len(f.Lines) {
+		return v.mark()
+	}
+	return nil
+}
+
+// gotoFile makes file i the current file and shows its first page.
+func (v *Viewer) gotoFile(i int) error {
+	v.cur = i
+	v.line = 0
+	return v.show()
+}
+
+func (v *Viewer) prompt() {
+	f := &v.files[v.cur]
+	var pos string
+	if v.line < len(f.Lines) {
+		pos = fmt.Sprintf("line %d/%d", v.line, len(f.Lines))
+	} else {
+		pos = "end"
+	}
+	fmt.Fprintf(v.w, "-- file %d/%d, %s -- [enter] more, n next, p prev, m mark, l list, <num> goto, q quit: ",
+		v.cur+1, len(v.files), pos)
+}
+
+// Run shows the files until the user quits or all files have been shown. It
+// starts with the list of files and the first page of the first file which
+// has not been viewed yet.
+func (v *Viewer) Run() error {
+	if len(v.files) == 0 {
+		fmt.Fprintln(v.w, "no changes")
+		return nil
+	}
+	v.list()
+	start := 0
+	for start < len(v.files) && v.viewed[start] {
+		start++
+	}
+	if start == len(v.files) {
+		start = 0
+	}
+	if err := v.gotoFile(start); err != nil {
+		return err
+	}
+	for {
+		v.prompt()
+		line, err := v.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				fmt.Fprintln(v.w)
+				return nil
+			}
+			return err
+		}
+		cmd := strings.TrimSpace(line)
+		switch cmd {
+		case "":
+			if v.line < len(v.files[v.cur].Lines) {
+				err = v.show()
+			} else if v.cur+1 < len(v.files) {
+				err = v.gotoFile(v.cur + 1)
+			} else {
+				return nil
+			}
+		case "n":
+			if v.cur+1 < len(v.files) {
+				err = v.gotoFile(v.cur + 1)
+			} else {
+				fmt.Fprintln(v.w, "last file")
+			}
+		case "p":
+			if v.cur > 0 {
+				err = v.gotoFile(v.cur - 1)
+			} else {
+				fmt.Fprintln(v.w, "first file")
+			}
+		case "m":
+			if err = v.mark(); err == nil {
+				fmt.Fprintf(v.w, "marked %s as viewed\n", v.files[v.cur].Name)
+			}
+		case "l":
+			v.list()
+		case "q":
+			return nil
+		default:
+			i, convErr := strconv.Atoi(cmd)
+			if convErr != nil || i < 1 || i > len(v.files) {
+				fmt.Fprintf(v.w, "unknown command: %s\n", cmd)
+				continue
+			}
+			err = v.gotoFile(i - 1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Unviewed returns the names of the files which have not been viewed
+// completely (or marked as viewed).
+func (v *Viewer) Unviewed() []string {
+	var names []string
+	for i, viewed := range v.viewed {
+		if !viewed {
+			names = append(names, v.files[i].Name)
+		}
+	}
+	return names
+}
+
+// Pager shows the changes between the directory trees rooted at a and b with
+// a Viewer on the terminal and prints which files have been viewed. The files
+// in marked are already marked as viewed, onMark is set as Viewer.OnMark.
+// It returns the names of the files which have not been viewed.
+func Pager(
+	a, b string,
+	excludePaths, marked []string,
+	onMark func(name string) error,
+) ([]string, error) {
+	files, err := Files(a, b, excludePaths)
+	if err != nil {
+		return nil, err
+	}
+	height := defaultHeight
+	if _, h, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil && h > 0 {
+		height = h
+	}
+	v := NewViewer(files, os.Stdin, os.Stdout, height)
+	v.Mark(marked)
+	v.OnMark = onMark
+	if err := v.Run(); err != nil {
+		return nil, err
+	}
+	unviewed := v.Unviewed()
+	fmt.Printf("viewed %d/%d files\n", len(files)-len(unviewed), len(files))
+	for _, name := range unviewed {
+		fmt.Println(color.YellowString("not viewed: %s", name))
+	}
+	return unviewed, nil
+}
@@ -135,6 +135,15 @@ outer:
 			if err := copyDir(s, d, nil); err != nil {
 				return err
 			}
+		} else if fi.Mode()&os.ModeSymlink != 0 {
+			// recreate symbolic link
+			target, err := os.Readlink(s)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(target, d); err != nil {
+				return err
+			}
 		} else {
 			if err := Copy(s, d); err != nil {
 				return err
@@ -145,16 +154,17 @@ outer:
 }
 
 // CopyDir recursively copies the source directory src to destination directory
-// dst. The source directory must exist already and only contain regular files
-// and directories. The destination directory must not exist already.
+// dst. The source directory must exist already and only contain regular files,
+// directories, and symbolic links (which are copied as symbolic links). The
+// destination directory must not exist already.
 func CopyDir(src, dst string) error {
 	return copyDir(src, dst, nil)
 }
 
 // CopyDirExclude recursively copies the source directory src to destination
 // directory dst, except for paths contained in excludePath. The source
-// directory must exist already and only contain regular files and
-// directories. The destination directory must not exist already.
+// directory must exist already and only contain regular files, directories,
+// and symbolic links. The destination directory must not exist already.
 func CopyDirExclude(src, dst string, excludePaths []string) error {
 	return copyDir(src, dst, excludePaths)
 }
@@ -2,14 +2,24 @@
 package git
 
 import (
+	"archive/tar"
 	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"syscall"
 )
 
+// Installed returns true, if the git command can be found in the PATH.
+func Installed() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
 // DiffPager calls `git diff no-index` on the two directory trees rooted at a
 // and b and shows the result on stdout, possibly using a pager.
 func DiffPager(a, b string) error {
@@ -34,3 +44,133 @@ func DiffPager(a, b string) error {
 	}
 	return nil
 }
+
+// Run calls git with the given args in directory dir and returns its standard
+// output. The environment variables in env (of the form "key=value") are
+// added to the environment of the git process.
+func Run(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if exiterr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s: %s: %s", strings.Join(args, " "), exiterr,
+				strings.TrimSpace(stderr.String()))
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// Trailer returns the value of the first trailer with the given key in the
+// commit message msg or an empty string, if msg contains no such trailer.
+func Trailer(msg, key string) string {
+	prefix := key + ": "
+	for _, line := range strings.Split(msg, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, prefix))
+		}
+	}
+	return ""
+}
+
+// Export writes the tree of Git revision rev in the repository containing
+// directory repo to directory dir, which must exist. Only the part of the
+// tree below repo is exported. Regular files, executables, and symlinks are
+// supported, submodules are skipped.
+func Export(repo, rev, dir string) error {
+	cmd := exec.Command("git", "archive", "--format=tar", rev)
+	cmd.Dir = repo
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if err := extract(tar.NewReader(stdout), dir); err != nil {
+		io.Copy(ioutil.Discard, stdout)
+		cmd.Wait()
+		return err
+	}
+	if err := cmd.Wait(); err != nil {
+		if exiterr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("git archive %s: %s: %s", rev, exiterr,
+				strings.TrimSpace(stderr.String()))
+		}
+		return err
+	}
+	return nil
+}
+
+// checkParents makes sure that no parent of the relative path name in dir is
+// a symlink, otherwise extracting name could write outside of dir.
+func checkParents(dir, name string) error {
+	for p := filepath.Dir(name); p != "."; p = filepath.Dir(p) {
+		fi, err := os.Lstat(filepath.Join(dir, p))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("git archive: path contains symlink: %s", name)
+		}
+	}
+	return nil
+}
+
+// extract extracts the tar archive r created by git archive to directory dir.
+func extract(r *tar.Reader, dir string) error {
+	for {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." ||
+			strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("git archive: illegal path: %s", hdr.Name)
+		}
+		if err := checkParents(dir, name); err != nil {
+			return err
+		}
+		path := filepath.Join(dir, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			perm := os.FileMode(0644)
+			if hdr.Mode&0100 != 0 {
+				perm = 0755
+			}
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, r); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, path); err != nil {
+				return err
+			}
+		}
+		// other types (like the pax global header with the commit ID) are skipped
+	}
+}
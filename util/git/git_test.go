@@ -0,0 +1,109 @@
+package git
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	content  string
+}
+
+func tarArchive(t *testing.T, entries []tarEntry) *tar.Reader {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Mode:     0644,
+			Size:     int64(len(e.content)),
+		}
+		if err := w.WriteHeader(hdr); err != nil {
+			t.Fatalf("w.WriteHeader() failed: %v", err)
+		}
+		if _, err := w.Write([]byte(e.content)); err != nil {
+			t.Fatalf("w.Write() failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("w.Close() failed: %v", err)
+	}
+	return tar.NewReader(&buf)
+}
+
+func TestExtract(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "git_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	outside := filepath.Join(tmpdir, "outside")
+	if err := os.Mkdir(outside, 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+
+	// valid archive
+	dir := filepath.Join(tmpdir, "valid")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+	err = extract(tarArchive(t, []tarEntry{
+		{name: "d/", typeflag: tar.TypeDir},
+		{name: "d/a.txt", typeflag: tar.TypeReg, content: "a\n"},
+		{name: "link", typeflag: tar.TypeSymlink, linkname: "d/a.txt"},
+	}), dir)
+	if err != nil {
+		t.Fatalf("extract() failed: %v", err)
+	}
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "link"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() failed: %v", err)
+	}
+	if string(buf) != "a\n" {
+		t.Errorf("extract() wrote wrong content: %q", buf)
+	}
+
+	// invalid archives
+	tests := [][]tarEntry{
+		{{name: "../outside/a.txt", typeflag: tar.TypeReg, content: "a\n"}},
+		{{name: "d/../../outside/a.txt", typeflag: tar.TypeReg, content: "a\n"}},
+		{{name: outside + "/a.txt", typeflag: tar.TypeReg, content: "a\n"}},
+		{
+			{name: "link", typeflag: tar.TypeSymlink, linkname: outside},
+			{name: "link/a.txt", typeflag: tar.TypeReg, content: "a\n"},
+		},
+		{
+			{name: "link", typeflag: tar.TypeSymlink, linkname: outside},
+			{name: "link/d/", typeflag: tar.TypeDir},
+		},
+		{
+			{name: "a.txt", typeflag: tar.TypeSymlink, linkname: filepath.Join(outside, "a.txt")},
+			{name: "a.txt", typeflag: tar.TypeReg, content: "a\n"},
+		},
+	}
+	for i, test := range tests {
+		dir := filepath.Join(tmpdir, "invalid", string(rune('a'+i)))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("os.MkdirAll() failed: %v", err)
+		}
+		if err := extract(tarArchive(t, test), dir); err == nil {
+			t.Errorf("extract() should fail for test %d", i)
+		}
+		fis, err := ioutil.ReadDir(outside)
+		if err != nil {
+			t.Fatalf("ioutil.ReadDir() failed: %v", err)
+		}
+		if len(fis) != 0 {
+			t.Fatalf("extract() wrote outside of dir for test %d", i)
+		}
+	}
+}
@@ -10,9 +10,11 @@ import (
 
 	"github.com/frankbraun/codechain/hashchain"
 	"github.com/frankbraun/codechain/keyfile"
+	"github.com/frankbraun/codechain/util/base64"
 	"github.com/frankbraun/codechain/util/bzero"
 	"github.com/frankbraun/codechain/util/def"
 	"github.com/frankbraun/codechain/util/file"
+	"github.com/frankbraun/codechain/util/signer"
 	"github.com/frankbraun/codechain/util/terminal"
 )
 
@@ -120,3 +122,72 @@ func Load(c *hashchain.HashChain, homeDir, filename string) (*[64]byte, *[64]byt
 	}
 	return Read(filepath.Join(secretDir, pubKey))
 }
+
+// Agent returns a signer for the Ed25519 key held by the ssh-agent (see
+// SSH_AUTH_SOCK) and its comment. If pubKeys is not nil, only agent keys
+// contained in pubKeys (base64 encoded) are considered. In both cases exactly
+// one such key must exist.
+func Agent(pubKeys map[string]bool) (signer.Signer, []byte, error) {
+	socket, err := signer.AgentSocket()
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := signer.AgentKeys(socket)
+	if err != nil {
+		return nil, nil, err
+	}
+	var match *signer.AgentKey
+	for i, key := range keys {
+		if pubKeys != nil && !pubKeys[base64.Encode(key.PubKey[:])] {
+			continue
+		}
+		if match != nil {
+			return nil, nil,
+				fmt.Errorf("more than one matching Ed25519 key found in ssh-agent")
+		}
+		match = &keys[i]
+	}
+	if match == nil {
+		return nil, nil, fmt.Errorf("ssh-agent doesn't hold any matching Ed25519 key")
+	}
+	fmt.Printf("using ssh-agent key: %s\n", base64.Encode(match.PubKey[:]))
+	s, err := signer.NewAgent(socket, match.PubKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, []byte(match.Comment), nil
+}
+
+// ReadSigner returns a signer for the ssh-agent, if agent is true.
+// Otherwise it reads the secret key from given filename (see Read).
+// The comment of the key is also returned. The returned signer holds a copy
+// of the secret key, call its Zero method after use.
+func ReadSigner(filename string, agent bool) (signer.Signer, []byte, error) {
+	if agent {
+		return Agent(nil)
+	}
+	sec, _, comment, err := Read(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer bzero.Bytes(sec[:])
+	return signer.New(*sec), comment, nil
+}
+
+// LoadSigner returns a signer for the ssh-agent key corresponding to the
+// signer in given hash chain, if agent is true.
+// Otherwise it loads the secret key from filename or the secrets
+// subdirectory of homeDir (see Load). The returned signer holds a copy of the
+// secret key, call its Zero method after use.
+func LoadSigner(c *hashchain.HashChain, homeDir, filename string, agent bool) (signer.Signer, error) {
+	if agent {
+		s, _, err := Agent(c.Signer())
+		return s, err
+	}
+	sec, _, _, err := Load(c, homeDir, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer bzero.Bytes(sec[:])
+	return signer.New(*sec), nil
+}
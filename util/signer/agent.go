@@ -0,0 +1,238 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+)
+
+// ssh-agent protocol message numbers (see draft-miller-ssh-agent).
+const (
+	agentFailure           = 5
+	agentRequestIdentities = 11
+	agentIdentitiesAnswer  = 12
+	agentSignRequest       = 13
+	agentSignResponse      = 14
+)
+
+// maximum size of a message we accept from the ssh-agent
+const agentMaxMessageSize = 256 * 1024
+
+const keyTypeEd25519 = "ssh-ed25519"
+
+// ErrNoAgent is returned if SSH_AUTH_SOCK is not set.
+var ErrNoAgent = errors.New("signer: SSH_AUTH_SOCK not set, ssh-agent not running?")
+
+// ErrAgentFailure is returned if the ssh-agent refused a request.
+var ErrAgentFailure = errors.New("signer: ssh-agent failure")
+
+// AgentKey is an Ed25519 key held by an ssh-agent.
+type AgentKey struct {
+	PubKey  [32]byte
+	Comment string
+}
+
+// AgentSocket returns the path of the ssh-agent socket from the
+// environment variable SSH_AUTH_SOCK.
+func AgentSocket() (string, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return "", ErrNoAgent
+	}
+	return socket, nil
+}
+
+// AgentKeys returns all Ed25519 keys held by the ssh-agent listening on
+// socket. Keys of other types are ignored.
+func AgentKeys(socket string) ([]AgentKey, error) {
+	resp, err := agentCall(socket, []byte{agentRequestIdentities})
+	if err != nil {
+		return nil, err
+	}
+	if resp[0] != agentIdentitiesAnswer {
+		return nil, fmt.Errorf("signer: unexpected ssh-agent response: %d", resp[0])
+	}
+	r := bytes.NewReader(resp[1:])
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	var keys []AgentKey
+	for i := uint32(0); i < n; i++ {
+		blob, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		comment, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		pubKey, ok := parseKeyBlob(blob)
+		if !ok {
+			continue // not an Ed25519 key
+		}
+		keys = append(keys, AgentKey{PubKey: pubKey, Comment: string(comment)})
+	}
+	return keys, nil
+}
+
+type agentSigner struct {
+	socket string
+	pubKey [32]byte
+}
+
+// NewAgent returns a signer for the Ed25519 key pubKey held by the ssh-agent
+// listening on socket.
+func NewAgent(socket string, pubKey [32]byte) (Signer, error) {
+	keys, err := AgentKeys(socket)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.PubKey == pubKey {
+			return &agentSigner{socket: socket, pubKey: pubKey}, nil
+		}
+	}
+	return nil, fmt.Errorf("signer: ssh-agent doesn't hold key %x", pubKey)
+}
+
+// PublicKey returns the public key of the agent-held key.
+func (s *agentSigner) PublicKey() [32]byte {
+	return s.pubKey
+}
+
+// Zero does nothing, the secret key is held by the ssh-agent.
+func (s *agentSigner) Zero() {}
+
+// Sign lets the ssh-agent sign msg. Ed25519 signatures created by ssh-agent
+// are plain Ed25519 signatures over msg, the returned signature is verified
+// before it is returned.
+func (s *agentSigner) Sign(msg []byte) ([64]byte, error) {
+	var sig [64]byte
+	var req bytes.Buffer
+	req.WriteByte(agentSignRequest)
+	writeString(&req, marshalKeyBlob(s.pubKey))
+	writeString(&req, msg)
+	binary.Write(&req, binary.BigEndian, uint32(0)) // flags
+	resp, err := agentCall(s.socket, req.Bytes())
+	if err != nil {
+		return sig, err
+	}
+	if resp[0] != agentSignResponse {
+		return sig, fmt.Errorf("signer: unexpected ssh-agent response: %d", resp[0])
+	}
+	r := bytes.NewReader(resp[1:])
+	blob, err := readString(r)
+	if err != nil {
+		return sig, err
+	}
+	r = bytes.NewReader(blob)
+	format, err := readString(r)
+	if err != nil {
+		return sig, err
+	}
+	if string(format) != keyTypeEd25519 {
+		return sig, fmt.Errorf("signer: unexpected ssh-agent signature format: %s", format)
+	}
+	s64, err := readString(r)
+	if err != nil {
+		return sig, err
+	}
+	if len(s64) != 64 || !ed25519.Verify(s.pubKey[:], msg, s64) {
+		return sig, fmt.Errorf("signer: ssh-agent signature does not verify")
+	}
+	copy(sig[:], s64)
+	return sig, nil
+}
+
+// agentCall sends the request msg to the ssh-agent listening on socket and
+// returns the (non-empty) response.
+func agentCall(socket string, msg []byte) ([]byte, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("signer: cannot connect to ssh-agent: %s", err)
+	}
+	defer conn.Close()
+	if err := writeMessage(conn, msg); err != nil {
+		return nil, err
+	}
+	resp, err := readMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	if resp[0] == agentFailure {
+		return nil, ErrAgentFailure
+	}
+	return resp, nil
+}
+
+func writeMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 4+len(msg))
+	binary.BigEndian.PutUint32(buf, uint32(len(msg)))
+	copy(buf[4:], msg)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readMessage(r io.Reader) ([]byte, error) {
+	var l [4]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(l[:])
+	if n == 0 || n > agentMaxMessageSize {
+		return nil, fmt.Errorf("signer: invalid ssh-agent message size: %d", n)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeString(b *bytes.Buffer, s []byte) {
+	binary.Write(b, binary.BigEndian, uint32(len(s)))
+	b.Write(s)
+}
+
+func readString(r *bytes.Reader) ([]byte, error) {
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	if int64(n) > int64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	s := make([]byte, n)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func marshalKeyBlob(pubKey [32]byte) []byte {
+	var b bytes.Buffer
+	writeString(&b, []byte(keyTypeEd25519))
+	writeString(&b, pubKey[:])
+	return b.Bytes()
+}
+
+func parseKeyBlob(blob []byte) ([32]byte, bool) {
+	var pubKey [32]byte
+	r := bytes.NewReader(blob)
+	keyType, err := readString(r)
+	if err != nil || string(keyType) != keyTypeEd25519 {
+		return pubKey, false
+	}
+	key, err := readString(r)
+	if err != nil || len(key) != 32 || r.Len() != 0 {
+		return pubKey, false
+	}
+	copy(pubKey[:], key)
+	return pubKey, true
+}
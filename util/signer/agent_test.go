@@ -0,0 +1,126 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeAgent implements the relevant subset of the ssh-agent protocol for
+// a single Ed25519 key.
+func fakeAgent(t *testing.T, l net.Listener, sec ed25519.PrivateKey) {
+	var pubKey [32]byte
+	copy(pubKey[:], sec[32:])
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		req, err := readMessage(conn)
+		if err != nil {
+			t.Errorf("readMessage() failed: %v", err)
+			conn.Close()
+			return
+		}
+		var resp bytes.Buffer
+		switch req[0] {
+		case agentRequestIdentities:
+			resp.WriteByte(agentIdentitiesAnswer)
+			binary.Write(&resp, binary.BigEndian, uint32(2))
+			// a non-Ed25519 key which must be ignored
+			var other bytes.Buffer
+			writeString(&other, []byte("ssh-rsa"))
+			writeString(&other, []byte("dummy"))
+			writeString(&resp, other.Bytes())
+			writeString(&resp, []byte("rsa key"))
+			writeString(&resp, marshalKeyBlob(pubKey))
+			writeString(&resp, []byte("ed25519 key"))
+		case agentSignRequest:
+			r := bytes.NewReader(req[1:])
+			blob, _ := readString(r)
+			msg, _ := readString(r)
+			if !bytes.Equal(blob, marshalKeyBlob(pubKey)) {
+				resp.WriteByte(agentFailure)
+				break
+			}
+			var sig bytes.Buffer
+			writeString(&sig, []byte(keyTypeEd25519))
+			writeString(&sig, ed25519.Sign(sec, msg))
+			resp.WriteByte(agentSignResponse)
+			writeString(&resp, sig.Bytes())
+		default:
+			resp.WriteByte(agentFailure)
+		}
+		if err := writeMessage(conn, resp.Bytes()); err != nil {
+			t.Errorf("writeMessage() failed: %v", err)
+		}
+		conn.Close()
+	}
+}
+
+func TestAgent(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "signer_test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	pub, sec, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() failed: %v", err)
+	}
+	socket := filepath.Join(tmpdir, "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("net.Listen() failed: %v", err)
+	}
+	defer l.Close()
+	go fakeAgent(t, l, sec)
+
+	keys, err := AgentKeys(socket)
+	if err != nil {
+		t.Fatalf("AgentKeys() failed: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("AgentKeys() should return 1 key, not %d", len(keys))
+	}
+	if !bytes.Equal(keys[0].PubKey[:], pub) || keys[0].Comment != "ed25519 key" {
+		t.Error("AgentKeys() returned wrong key")
+	}
+
+	s, err := NewAgent(socket, keys[0].PubKey)
+	if err != nil {
+		t.Fatalf("NewAgent() failed: %v", err)
+	}
+	msg := []byte("message")
+	sig, err := s.Sign(msg)
+	if err != nil {
+		t.Fatalf("s.Sign() failed: %v", err)
+	}
+	if !ed25519.Verify(pub, msg, sig[:]) {
+		t.Error("agent signature does not verify")
+	}
+
+	// signatures are compatible with keyfile signer
+	var secKey [64]byte
+	copy(secKey[:], sec)
+	sig2, err := New(secKey).Sign(msg)
+	if err != nil {
+		t.Fatalf("s.Sign() failed: %v", err)
+	}
+	if sig != sig2 {
+		t.Error("agent and keyfile signatures differ")
+	}
+
+	// unknown key
+	var unknown [32]byte
+	if _, err := NewAgent(socket, unknown); err == nil {
+		t.Error("NewAgent() should fail for unknown key")
+	}
+}
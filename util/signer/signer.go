@@ -0,0 +1,51 @@
+// Package signer defines an interface for Ed25519 signers and implements it
+// for secret keys held in memory and for keys held by an ssh-agent.
+package signer
+
+import (
+	"crypto/ed25519"
+
+	"github.com/frankbraun/codechain/util/bzero"
+)
+
+// Signer is an Ed25519 signer.
+type Signer interface {
+	// PublicKey returns the Ed25519 public key of the signer.
+	PublicKey() [32]byte
+
+	// Sign signs msg with the secret key of the signer.
+	Sign(msg []byte) ([64]byte, error)
+
+	// Zero wipes the secret key held by the signer (if any) from memory.
+	// The signer cannot be used afterwards.
+	Zero()
+}
+
+type secKeySigner struct {
+	secKey [64]byte
+}
+
+// New returns a signer for the given Ed25519 secret key (which is usually
+// decrypted from a keyfile).
+func New(secKey [64]byte) Signer {
+	return &secKeySigner{secKey: secKey}
+}
+
+// PublicKey returns the public key corresponding to the secret key.
+func (s *secKeySigner) PublicKey() [32]byte {
+	var pubKey [32]byte
+	copy(pubKey[:], s.secKey[32:])
+	return pubKey
+}
+
+// Sign signs msg with the secret key.
+func (s *secKeySigner) Sign(msg []byte) ([64]byte, error) {
+	var sig [64]byte
+	copy(sig[:], ed25519.Sign(s.secKey[:], msg))
+	return sig, nil
+}
+
+// Zero wipes the secret key from memory.
+func (s *secKeySigner) Zero() {
+	bzero.Bytes(s.secKey[:])
+}
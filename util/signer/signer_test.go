@@ -0,0 +1,21 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+)
+
+func TestZero(t *testing.T) {
+	_, sec, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() failed: %v", err)
+	}
+	var secKey [64]byte
+	copy(secKey[:], sec)
+	s := New(secKey)
+	s.Zero()
+	if s.(*secKeySigner).secKey != [64]byte{} {
+		t.Error("s.Zero() did not wipe secret key")
+	}
+}